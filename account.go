@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/w0/aggregator/internal/database"
+)
+
+const userUsage = `usage: aggregator user show [username]
+       aggregator user rename [username] <new name>
+       aggregator user delete [--yes] [--reassign <username>||--delete-feeds] [username]
+       aggregator user passwd <username>
+       aggregator user reset-code <username>`
+
+func handlerUser(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf(userUsage)
+	}
+
+	ctx := context.Background()
+
+	switch cmd.arguments[0] {
+	case "show":
+		target, err := targetUser(s, user, cmd.arguments[1:], 1)
+
+		if err != nil {
+			return err
+		}
+
+		follows, err := s.db.CountFeedFollowsForUser(ctx, target.ID)
+
+		if err != nil {
+			return fmt.Errorf("failed counting follows: %w", err)
+		}
+
+		feeds, err := s.db.CountFeedsForUser(ctx, target.ID)
+
+		if err != nil {
+			return fmt.Errorf("failed counting feeds: %w", err)
+		}
+
+		fmt.Printf("Name:      %s\n", target.Name)
+		fmt.Printf("ID:        %s\n", target.ID)
+		fmt.Printf("Role:      %s\n", target.Role)
+		fmt.Printf("Created:   %s\n", target.CreatedAt.Format(time.DateTime))
+		fmt.Printf("Updated:   %s\n", target.UpdatedAt.Format(time.DateTime))
+		fmt.Printf("Following: %d feeds\n", follows)
+		fmt.Printf("Added:     %d feeds\n", feeds)
+	case "rename":
+		if len(cmd.arguments) < 2 {
+			return fmt.Errorf(userUsage)
+		}
+
+		args := cmd.arguments[1:]
+		name := args[len(args)-1]
+
+		target, err := targetUser(s, user, args[:len(args)-1], 1)
+
+		if err != nil {
+			return err
+		}
+
+		renamed, err := s.db.RenameUser(ctx,
+			database.RenameUserParams{
+				Name:      name,
+				UpdatedAt: time.Now(),
+				ID:        target.ID,
+			})
+
+		if err != nil {
+			return fmt.Errorf("failed renaming %s, is %s taken?: %w", target.Name, name, err)
+		}
+
+		if renamed.ID == user.ID {
+			err = s.cfg.SetSession(renamed.Name, s.cfg.SessionToken)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("Renamed %s to %s\n", target.Name, renamed.Name)
+		fmt.Println("Fever passwords include the user name, create new ones with aggregator token create --fever.")
+	case "delete":
+		return deleteUser(s, user, cmd.arguments[1:])
+	case "passwd", "reset-code":
+		if len(cmd.arguments) != 2 {
+			return fmt.Errorf(userUsage)
+		}
+
+		if cmd.arguments[1] == user.Name {
+			return fmt.Errorf("change your own password with aggregator passwd")
+		}
+
+		target, err := targetUser(s, user, cmd.arguments[1:], 1)
+
+		if err != nil {
+			return err
+		}
+
+		if cmd.arguments[0] == "reset-code" {
+			code, expires, err := issueResetCode(ctx, s, target)
+
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Reset code for %s, valid until %s:\n\n\t%s\n\n", target.Name, expires.Format(time.DateTime), code)
+			fmt.Printf("%s can choose a new password with aggregator login %s --reset-code\n", target.Name, target.Name)
+
+			return nil
+		}
+
+		password, err := newPassword()
+
+		if err != nil {
+			return err
+		}
+
+		err = s.db.InTx(ctx, func(tx database.Store) error {
+			err := setPassword(tx, target, password)
+
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.DeletePasswordReset(ctx, target.ID)
+
+			if err != nil {
+				return fmt.Errorf("failed revoking reset code: %w", err)
+			}
+
+			err = tx.DeleteSessionsForUser(ctx, target.ID)
+
+			if err != nil {
+				return fmt.Errorf("failed revoking sessions: %w", err)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Password set for %s. Their sessions have been logged out.\n", target.Name)
+	default:
+		return fmt.Errorf(userUsage)
+	}
+
+	return nil
+}
+
+func deleteUser(s *state, user database.User, args []string) error {
+	fs := flag.NewFlagSet("user delete", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	reassign := fs.String("reassign", "", "give the user's feeds to another user")
+	deleteFeeds := fs.Bool("delete-feeds", false, "delete the user's feeds and their posts")
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf(userUsage)
+	}
+
+	if *reassign != "" && *deleteFeeds {
+		return fmt.Errorf("choose either --reassign or --delete-feeds")
+	}
+
+	ctx := context.Background()
+
+	target, err := targetUser(s, user, fs.Args(), 1)
+
+	if err != nil {
+		return err
+	}
+
+	if target.Role == roleAdmin {
+		admins, err := s.db.CountAdmins(ctx)
+
+		if err != nil {
+			return fmt.Errorf("failed counting admins: %w", err)
+		}
+
+		if admins <= 1 {
+			return fmt.Errorf("%s is the last admin, promote someone else first", target.Name)
+		}
+	}
+
+	feeds, err := s.db.CountFeedsForUser(ctx, target.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed counting feeds: %w", err)
+	}
+
+	if feeds > 0 && *reassign == "" && !*deleteFeeds {
+		if *yes {
+			return fmt.Errorf("%s added %d feeds, pass --reassign <username> or --delete-feeds", target.Name, feeds)
+		}
+
+		answer, err := prompt(fmt.Sprintf("%s added %d feeds. Reassign them to (username, empty to delete them): ", target.Name, feeds))
+
+		if err != nil {
+			return err
+		}
+
+		*reassign = answer
+	}
+
+	var heir database.User
+
+	if feeds > 0 && *reassign != "" {
+		heir, err = s.db.GetUser(ctx, *reassign)
+
+		if err != nil {
+			return fmt.Errorf("user %s not found: %w", *reassign, err)
+		}
+
+		if heir.ID == target.ID {
+			return fmt.Errorf("cannot reassign feeds to the user being deleted")
+		}
+	}
+
+	if !*yes {
+		description := fmt.Sprintf("delete user %s with their follows, filters and tokens", target.Name)
+		switch {
+		case feeds > 0 && heir.Name != "":
+			description = fmt.Sprintf("%s, and give their %d feeds to %s", description, feeds, heir.Name)
+		case feeds > 0:
+			description = fmt.Sprintf("%s, and their %d feeds and posts", description, feeds)
+		}
+
+		answer, err := prompt(fmt.Sprintf("This will %s.\nType %q to confirm: ", description, target.Name))
+
+		if err != nil {
+			return err
+		}
+
+		if answer != target.Name {
+			return fmt.Errorf("confirmation did not match, nothing was deleted")
+		}
+	}
+
+	// the feeds go with the user unless both happen
+	var reassigned int64
+
+	err = s.db.InTx(ctx, func(tx database.Store) error {
+		if heir.Name != "" {
+			n, err := tx.ReassignFeeds(ctx,
+				database.ReassignFeedsParams{
+					UserID:    heir.ID,
+					UpdatedAt: time.Now(),
+					UserID_2:  target.ID,
+				})
+
+			if err != nil {
+				return fmt.Errorf("failed reassigning feeds: %w", err)
+			}
+
+			reassigned = n
+		}
+
+		_, err := tx.DeleteUser(ctx, target.ID)
+
+		if err != nil {
+			return fmt.Errorf("failed deleting user: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if heir.Name != "" {
+		fmt.Printf("Gave %d feeds to %s\n", reassigned, heir.Name)
+	}
+
+	if target.ID == user.ID {
+		err = s.cfg.SetSession("", "")
+
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("User %s has been deleted.\n", target.Name)
+
+	return nil
+}
+
+// targetUser returns the user named in args, or user itself when args has
+// fewer than n entries. Only admins may act on other users.
+func targetUser(s *state, user database.User, args []string, n int) (database.User, error) {
+	if len(args) < n || args[n-1] == user.Name {
+		return user, nil
+	}
+
+	if user.Role != roleAdmin {
+		return database.User{}, fmt.Errorf("only admins can manage other users")
+	}
+
+	target, err := s.db.GetUser(context.Background(), args[n-1])
+
+	if err != nil {
+		return database.User{}, fmt.Errorf("user %s not found: %w", args[n-1], err)
+	}
+
+	return target, nil
+}
+
+func handlerLogout(s *state, cmd command) error {
+	if s.cfg.SessionToken == "" {
+		fmt.Println("Not logged in.")
+		return nil
+	}
+
+	_, err := s.db.DeleteSession(context.Background(), hashToken(s.cfg.SessionToken))
+
+	if err != nil {
+		return fmt.Errorf("failed revoking session: %w", err)
+	}
+
+	name := s.cfg.CurrentUserName
+
+	err = s.cfg.SetSession("", "")
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Logged out %s\n", name)
+
+	return nil
+}
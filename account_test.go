@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestUserShow(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", "https://example.com/feed")
+		e.register("bob")
+		e.must("", "follow", "https://example.com/feed")
+
+		out := e.must("", "user", "show")
+
+		for _, want := range []string{"Name:      bob\n", "Role:      member\n", "Following: 1 feeds\n", "Added:     0 feeds\n"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("user show doesn't print %q:\n%s", want, out)
+			}
+		}
+
+		if err := e.fails("", "user", "show", "alice"); !strings.Contains(err.Error(), "only admins") {
+			t.Errorf("a member showing another user: %v", err)
+		}
+
+		e.login("alice")
+
+		if out := e.must("", "user", "show", "bob"); !strings.Contains(out, "Name:      bob\n") {
+			t.Errorf("user show bob printed\n%s", out)
+		}
+
+		if out := e.must("", "user", "show"); !strings.Contains(out, "Role:      admin\n") || !strings.Contains(out, "Added:     1 feeds\n") {
+			t.Errorf("user show printed\n%s", out)
+		}
+
+		e.fails("", "user", "show", "nobody")
+	})
+}
+
+func TestUserRename(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		e.register("alice")
+		e.register("bob")
+
+		e.fails("", "user", "rename", "alice", "carol")
+		e.fails("", "user", "rename", "alice")
+
+		if out := e.must("", "user", "rename", "robert"); !strings.HasPrefix(out, "Renamed bob to robert\n") {
+			t.Errorf("user rename printed\n%s", out)
+		}
+
+		// the session follows the new name
+		if e.s.cfg.CurrentUserName != "robert" {
+			t.Errorf("session is for %q after renaming", e.s.cfg.CurrentUserName)
+		}
+
+		e.must("", "following")
+
+		e.login("alice")
+		e.fails("", "user", "rename", "robert", "alice")
+		e.must("", "user", "rename", "robert", "bob")
+
+		if e.s.cfg.CurrentUserName != "alice" {
+			t.Errorf("renaming bob changed the session to %q", e.s.cfg.CurrentUserName)
+		}
+
+		e.user("bob")
+	})
+}
+
+func TestUserDeleteReassign(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed := newTestFeed(t, testItem(1))
+
+		e.register("alice")
+		e.register("bob")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.fetchAll()
+
+		e.login("alice")
+
+		if err := e.fails("", "user", "delete", "--yes", "bob"); !strings.Contains(err.Error(), "--reassign") {
+			t.Errorf("deleting a user with feeds without saying what happens to them: %v", err)
+		}
+
+		e.fails("", "user", "delete", "--yes", "--reassign", "bob", "bob")
+		e.fails("", "user", "delete", "--yes", "--reassign", "alice", "--delete-feeds", "bob")
+
+		// a wrong confirmation deletes nothing
+		if err := e.fails("alice\nrobert\n", "user", "delete", "bob"); !strings.Contains(err.Error(), "nothing was deleted") {
+			t.Errorf("user delete with the wrong confirmation: %v", err)
+		}
+
+		e.user("bob")
+
+		out := e.must("alice\nbob\n", "user", "delete", "bob")
+
+		if !strings.Contains(out, "Gave 1 feeds to alice\n") || !strings.Contains(out, "User bob has been deleted.\n") {
+			t.Errorf("user delete printed\n%s", out)
+		}
+
+		if _, err := e.s.db.GetUser(context.Background(), "bob"); err == nil {
+			t.Error("bob is still there")
+		}
+
+		if got := e.feed(feed.URL); got.UserID != e.user("alice").ID {
+			t.Error("the feed wasn't given to alice")
+		}
+
+		if n := len(e.postUrls(feed.URL)); n != 1 {
+			t.Errorf("the feed has %d posts after reassigning it, want 1", n)
+		}
+	})
+}
+
+func TestUserDeleteCascade(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed := newTestFeed(t, testItem(1))
+
+		e.register("alice")
+		e.register("bob")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.fetchAll()
+
+		if err := e.fails("", "user", "delete", "--yes", "alice"); !strings.Contains(err.Error(), "only admins") {
+			t.Errorf("a member deleting another user: %v", err)
+		}
+
+		// bob deletes himself, and with him his feed and its posts
+		out := e.must("", "user", "delete", "--yes", "--delete-feeds")
+
+		if out != "User bob has been deleted.\n" {
+			t.Errorf("user delete printed %q", out)
+		}
+
+		if e.s.cfg.CurrentUserName != "" || e.s.cfg.SessionToken != "" {
+			t.Errorf("deleting yourself left the session %+v", e.s.cfg)
+		}
+
+		if _, err := e.s.db.GetFeedByUrl(context.Background(), feed.URL); err == nil {
+			t.Error("bob's feed is still there")
+		}
+
+		e.login("alice")
+
+		if err := e.fails("", "user", "delete", "--yes", "alice"); !strings.Contains(err.Error(), "last admin") {
+			t.Errorf("deleting the last admin: %v", err)
+		}
+	})
+}
+
+func TestLogout(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		if out := e.must("", "logout"); out != "Not logged in.\n" {
+			t.Errorf("logout without a session printed %q", out)
+		}
+
+		e.register("alice")
+
+		if out := e.must("", "logout"); out != "Logged out alice\n" {
+			t.Errorf("logout printed %q", out)
+		}
+
+		if e.s.cfg.CurrentUserName != "" || e.s.cfg.SessionToken != "" {
+			t.Errorf("logout left the session %+v", e.s.cfg)
+		}
+
+		// logging out one session leaves the others alone
+		e.login("alice")
+		first := e.s.cfg.SessionToken
+		e.login("alice")
+		e.must("", "logout")
+		e.s.cfg.SessionToken = first
+		e.s.cfg.CurrentUserName = "alice"
+
+		e.must("", "following")
+	})
+}
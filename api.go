@@ -0,0 +1,505 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type apiUser struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type apiFeed struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`
+	UserID        uuid.UUID  `json:"user_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+}
+
+type apiFollow struct {
+	FeedID    uuid.UUID `json:"feed_id"`
+	FeedName  string    `json:"feed_name"`
+	FeedURL   string    `json:"feed_url"`
+	Groups    []string  `json:"groups"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type apiPost struct {
+	ID          uuid.UUID  `json:"id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Description string     `json:"description"`
+	Author      string     `json:"author,omitempty"`
+	Categories  []string   `json:"categories,omitempty"`
+	PublishedAt *time.Time `json:"published_at"`
+	FeedID      uuid.UUID  `json:"feed_id"`
+	FeedName    string     `json:"feed_name"`
+	Read        bool       `json:"read"`
+	Starred     bool       `json:"starred"`
+}
+
+type apiPostPage struct {
+	Posts  []apiPost `json:"posts"`
+	Limit  int       `json:"limit"`
+	Offset int       `json:"offset"`
+}
+
+func (srv *server) registerAPI(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/me", srv.requireToken(srv.handleMe))
+	mux.HandleFunc("GET /api/v1/users", srv.requireToken(srv.handleUsers))
+	mux.HandleFunc("GET /api/v1/feeds", srv.requireToken(srv.handleFeeds))
+	mux.HandleFunc("POST /api/v1/feeds", srv.requireToken(srv.handleCreateFeed))
+	mux.HandleFunc("GET /api/v1/follows", srv.requireToken(srv.handleFollows))
+	mux.HandleFunc("POST /api/v1/follows", srv.requireToken(srv.handleCreateFollow))
+	mux.HandleFunc("DELETE /api/v1/follows/{feed_id}", srv.requireToken(srv.handleDeleteFollow))
+	mux.HandleFunc("GET /api/v1/posts", srv.requireToken(srv.handlePosts))
+	mux.HandleFunc("GET /api/v1/posts/{id}", srv.requireToken(srv.handlePost))
+	mux.HandleFunc("PUT /api/v1/posts/{id}/read", srv.requireToken(srv.handleSetRead(true)))
+	mux.HandleFunc("DELETE /api/v1/posts/{id}/read", srv.requireToken(srv.handleSetRead(false)))
+	mux.HandleFunc("PUT /api/v1/posts/{id}/star", srv.requireToken(srv.handleSetStarred(true)))
+	mux.HandleFunc("DELETE /api/v1/posts/{id}/star", srv.requireToken(srv.handleSetStarred(false)))
+}
+
+func (srv *server) handleMe(w http.ResponseWriter, r *http.Request, user database.User) {
+	writeJSON(w, http.StatusOK, apiUserFrom(user))
+}
+
+func (srv *server) handleUsers(w http.ResponseWriter, r *http.Request, user database.User) {
+	if user.Role != roleAdmin {
+		writeError(w, http.StatusForbidden, "listing users requires an admin")
+		return
+	}
+
+	users, err := srv.s.db.GetUsers(r.Context())
+
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	res := make([]apiUser, 0, len(users))
+	for _, v := range users {
+		res = append(res, apiUserFrom(v))
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (srv *server) handleFeeds(w http.ResponseWriter, r *http.Request, user database.User) {
+	feeds, err := srv.s.db.GetAllFeeds(r.Context())
+
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	visible, err := feedVisibility(r.Context(), srv.s, &user)
+
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	res := make([]apiFeed, 0, len(feeds))
+	for _, v := range feeds {
+		if visible(v) {
+			res = append(res, apiFeedFrom(v))
+		}
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (srv *server) handleCreateFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name == "" || body.URL == "" {
+		writeError(w, http.StatusBadRequest, "name and url are required")
+		return
+	}
+
+	_, err := srv.s.db.GetFeedByUrl(r.Context(), body.URL)
+
+	if err == nil {
+		writeError(w, http.StatusConflict, "a feed with this url already exists")
+		return
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	now := time.Now()
+
+	feed, err := srv.s.db.CreateFeed(r.Context(),
+		database.CreateFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			Name:      body.Name,
+			Url:       body.URL,
+			UserID:    user.ID,
+		})
+
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	_, err = srv.s.db.CreateFeedFollow(r.Context(),
+		database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			FeedID:    feed.ID,
+			UserID:    user.ID,
+		})
+
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, apiFeedFrom(feed))
+}
+
+func (srv *server) handleFollows(w http.ResponseWriter, r *http.Request, user database.User) {
+	follows, err := srv.s.db.GetFeedFollowsForUser(r.Context(), user.ID)
+
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	groups, err := groupsByFeedUrl(srv.s, user)
+
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	res := make([]apiFollow, 0, len(follows))
+	for _, v := range follows {
+		res = append(res, apiFollow{
+			FeedID:    v.FeedID,
+			FeedName:  v.FeedName,
+			FeedURL:   v.Url,
+			Groups:    groups[v.Url],
+			CreatedAt: v.CreatedAt,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (srv *server) handleCreateFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		URL   string `json:"url"`
+		Group string `json:"group"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.URL == "" {
+		writeError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+
+	feed, err := srv.s.db.GetFeedByUrl(r.Context(), body.URL)
+
+	if err != nil {
+		writeDBError(w, r, err)
+		return
+	}
+
+	err = canFollowFeed(r.Context(), srv.s, user, feed)
+
+	if errors.Is(err, errPrivateFeed) {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	_, err = srv.s.db.GetFeedFollow(r.Context(),
+		database.GetFeedFollowParams{
+			UserID: user.ID,
+			FeedID: feed.ID,
+		})
+
+	if err == nil {
+		writeError(w, http.StatusConflict, "already following")
+		return
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	now := time.Now()
+
+	_, err = srv.s.db.CreateFeedFollow(r.Context(),
+		database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			FeedID:    feed.ID,
+			UserID:    user.ID,
+		})
+
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	if body.Group != "" {
+		err = addFeedToGroup(srv.s, user, feed, body.Group)
+
+		if err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusCreated, apiFeedFrom(feed))
+}
+
+func (srv *server) handleDeleteFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedID, err := uuid.Parse(r.PathValue("feed_id"))
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid feed id")
+		return
+	}
+
+	n, err := srv.s.db.DeleteFeedFollow(r.Context(),
+		database.DeleteFeedFollowParams{
+			UserID: user.ID,
+			FeedID: feedID,
+		})
+
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	if n == 0 {
+		writeError(w, http.StatusNotFound, "not following")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePosts lists the user's posts. It accepts the query parameters limit,
+// offset, feed (a feed id), group, q (a keyword), unread=true and starred=true.
+func (srv *server) handlePosts(w http.ResponseWriter, r *http.Request, user database.User) {
+	query := r.URL.Query()
+
+	limit, err := queryInt(query.Get("limit"), defaultPageSize)
+
+	if err != nil || limit < 1 || limit > maxPageSize {
+		writeError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+		return
+	}
+
+	offset, err := queryInt(query.Get("offset"), 0)
+
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, "offset must be a positive number")
+		return
+	}
+
+	params := database.ListPostsForUserParams{
+		UserID:      user.ID,
+		UnreadOnly:  query.Get("unread") == "true",
+		StarredOnly: query.Get("starred") == "true",
+		Keyword:     keywordPattern(query.Get("q")),
+		Limit:       int32(limit),
+		Offset:      int32(offset),
+	}
+
+	if feed := query.Get("feed"); feed != "" {
+		feedID, err := uuid.Parse(feed)
+
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid feed id")
+			return
+		}
+
+		params.FeedID = uuid.NullUUID{UUID: feedID, Valid: true}
+	}
+
+	if group := query.Get("group"); group != "" {
+		params.GroupName = sql.NullString{String: group, Valid: true}
+	}
+
+	posts, err := srv.s.db.ListPostsForUser(r.Context(), params)
+
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	page := apiPostPage{
+		Posts:  make([]apiPost, 0, len(posts)),
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	for _, v := range posts {
+		page.Posts = append(page.Posts, apiPostFrom(v))
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (srv *server) handlePost(w http.ResponseWriter, r *http.Request, user database.User) {
+	post, ok := srv.postFromPath(w, r, user)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiPostFrom(post))
+}
+
+func (srv *server) handleSetRead(read bool) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		post, ok := srv.postFromPath(w, r, user)
+		if !ok {
+			return
+		}
+
+		err := setPostRead(srv.s, user, post.ID, read)
+
+		if err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (srv *server) handleSetStarred(starred bool) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		post, ok := srv.postFromPath(w, r, user)
+		if !ok {
+			return
+		}
+
+		err := setPostStarred(srv.s, user, post.ID, starred)
+
+		if err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// postFromPath loads the post named by the {id} path value. It writes an
+// error response and returns false when the user can not see the post.
+func (srv *server) postFromPath(w http.ResponseWriter, r *http.Request, user database.User) (database.ListPostsForUserRow, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid post id")
+		return database.ListPostsForUserRow{}, false
+	}
+
+	post, err := srv.s.db.GetPostForUser(r.Context(),
+		database.GetPostForUserParams{
+			UserID: user.ID,
+			ID:     id,
+		})
+
+	if err != nil {
+		writeDBError(w, r, err)
+		return database.ListPostsForUserRow{}, false
+	}
+
+	return database.ListPostsForUserRow(post), true
+}
+
+func writeDBError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeInternalError(w, r, err)
+}
+
+func queryInt(v string, def int) (int, error) {
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
+func apiUserFrom(u database.User) apiUser {
+	return apiUser{
+		ID:        u.ID,
+		Name:      u.Name,
+		Role:      u.Role,
+		CreatedAt: u.CreatedAt,
+	}
+}
+
+func apiFeedFrom(f database.Feed) apiFeed {
+	return apiFeed{
+		ID:            f.ID,
+		Name:          f.Name,
+		URL:           f.Url,
+		UserID:        f.UserID,
+		CreatedAt:     f.CreatedAt,
+		LastFetchedAt: nullTime(f.LastFetchedAt),
+	}
+}
+
+func apiPostFrom(p database.ListPostsForUserRow) apiPost {
+	return apiPost{
+		ID:          p.ID,
+		Title:       p.Title,
+		URL:         p.Url,
+		Description: p.Description.String,
+		Author:      p.Author.String,
+		Categories:  splitCategories(p.Categories),
+		PublishedAt: nullTime(p.PublishedAt),
+		FeedID:      p.FeedID,
+		FeedName:    p.FeedName,
+		Read:        p.Read,
+		Starred:     p.Starred,
+	}
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+// api serves an api request made with token and returns the response.
+func (e *testEnv) api(method, target, token, body string) *httptest.ResponseRecorder {
+	e.t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	newServer(e.s).ServeHTTP(w, req)
+
+	return w
+}
+
+// decode reads a JSON response into v, checking its status first.
+func decode(t *testing.T, w *httptest.ResponseRecorder, status int, v any) {
+	t.Helper()
+
+	if w.Code != status {
+		t.Fatalf("answered %d, want %d\n%s", w.Code, status, w.Body)
+	}
+
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type is %q", got)
+	}
+
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("%v\n%s", err, w.Body)
+	}
+}
+
+// apiError checks that w is an error response with status and message.
+func apiError(t *testing.T, w *httptest.ResponseRecorder, status int, message string) {
+	t.Helper()
+
+	var body map[string]string
+	decode(t, w, status, &body)
+
+	if len(body) != 1 || body["error"] != message {
+		t.Errorf("error is %v, want %q", body, message)
+	}
+}
+
+func TestAPIAuth(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		e.register("alice")
+		e.register("bob")
+		token := e.apiToken()
+
+		w := httptest.NewRecorder()
+		newServer(e.s).ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/me", nil))
+		apiError(t, w, http.StatusUnauthorized, "missing bearer token")
+
+		apiError(t, e.api("GET", "/api/v1/me", "wrong", ""), http.StatusUnauthorized, "invalid token")
+
+		var me apiUser
+		decode(t, e.api("GET", "/api/v1/me", token, ""), http.StatusOK, &me)
+
+		if me.Name != "bob" || me.Role != roleMember || me.ID != e.user("bob").ID {
+			t.Errorf("me is %+v", me)
+		}
+
+		apiError(t, e.api("GET", "/api/v1/users", token, ""), http.StatusForbidden, "listing users requires an admin")
+
+		e.login("alice")
+
+		var users []apiUser
+		decode(t, e.api("GET", "/api/v1/users", e.apiToken(), ""), http.StatusOK, &users)
+
+		if len(users) != 2 {
+			t.Errorf("users are %+v", users)
+		}
+
+		// revoked tokens stop working
+		e.login("bob")
+		out := e.must("", "token", "list")
+		id := strings.Fields(out[strings.Index(out, "* "):])[1]
+		e.must("", "token", "revoke", id)
+
+		apiError(t, e.api("GET", "/api/v1/me", token, ""), http.StatusUnauthorized, "invalid token")
+	})
+}
+
+func TestAPIPosts(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed := newTestFeed(t, testItem(1), testItem(2), testItem(3), testItem(4), testItem(5))
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.fetchAll()
+		token := e.apiToken()
+
+		titles := func(target string) []string {
+			t.Helper()
+
+			var page apiPostPage
+			decode(t, e.api("GET", target, token, ""), http.StatusOK, &page)
+
+			var titles []string
+			for _, v := range page.Posts {
+				titles = append(titles, v.Title)
+			}
+
+			return titles
+		}
+
+		for _, tc := range []struct {
+			target string
+			want   []string
+		}{
+			{"/api/v1/posts", []string{"Post 5", "Post 4", "Post 3", "Post 2", "Post 1"}},
+			{"/api/v1/posts?limit=2", []string{"Post 5", "Post 4"}},
+			{"/api/v1/posts?limit=2&offset=2", []string{"Post 3", "Post 2"}},
+			{"/api/v1/posts?limit=2&offset=4", []string{"Post 1"}},
+			{"/api/v1/posts?offset=5", nil},
+			{"/api/v1/posts?q=post+3", []string{"Post 3"}},
+			{"/api/v1/posts?feed=" + e.feed(feed.URL).ID.String() + "&limit=1", []string{"Post 5"}},
+			{"/api/v1/posts?feed=" + uuid.NewString(), nil},
+		} {
+			if got := titles(tc.target); !slices.Equal(got, tc.want) {
+				t.Errorf("%s lists %v, want %v", tc.target, got, tc.want)
+			}
+		}
+
+		var page apiPostPage
+		decode(t, e.api("GET", "/api/v1/posts?limit=2&offset=2", token, ""), http.StatusOK, &page)
+
+		if page.Limit != 2 || page.Offset != 2 {
+			t.Errorf("page is limit %d offset %d", page.Limit, page.Offset)
+		}
+
+		// an empty page is a list, not null
+		if w := e.api("GET", "/api/v1/posts?offset=5", token, ""); !strings.Contains(w.Body.String(), `"posts":[]`) {
+			t.Errorf("empty page is %s", w.Body)
+		}
+
+		apiError(t, e.api("GET", "/api/v1/posts?limit=0", token, ""), http.StatusBadRequest, "limit must be between 1 and 100")
+		apiError(t, e.api("GET", "/api/v1/posts?limit=101", token, ""), http.StatusBadRequest, "limit must be between 1 and 100")
+		apiError(t, e.api("GET", "/api/v1/posts?limit=ten", token, ""), http.StatusBadRequest, "limit must be between 1 and 100")
+		apiError(t, e.api("GET", "/api/v1/posts?offset=-1", token, ""), http.StatusBadRequest, "offset must be a positive number")
+		apiError(t, e.api("GET", "/api/v1/posts?feed=42", token, ""), http.StatusBadRequest, "invalid feed id")
+
+		id := e.postID(feed.URL, postUrl(3)).String()
+
+		if w := e.api("PUT", "/api/v1/posts/"+id+"/read", token, ""); w.Code != http.StatusNoContent {
+			t.Errorf("marking a post read answered %d", w.Code)
+		}
+
+		if w := e.api("PUT", "/api/v1/posts/"+id+"/star", token, ""); w.Code != http.StatusNoContent {
+			t.Errorf("starring a post answered %d", w.Code)
+		}
+
+		var post apiPost
+		decode(t, e.api("GET", "/api/v1/posts/"+id, token, ""), http.StatusOK, &post)
+
+		if post.Title != "Post 3" || !post.Read || !post.Starred || post.FeedName != "Test feed" {
+			t.Errorf("post is %+v", post)
+		}
+
+		if got := titles("/api/v1/posts?unread=true&limit=2"); !slices.Equal(got, []string{"Post 5", "Post 4"}) {
+			t.Errorf("unread posts are %v", got)
+		}
+
+		if got := titles("/api/v1/posts?starred=true"); !slices.Equal(got, []string{"Post 3"}) {
+			t.Errorf("starred posts are %v", got)
+		}
+
+		apiError(t, e.api("GET", "/api/v1/posts/42", token, ""), http.StatusBadRequest, "invalid post id")
+		apiError(t, e.api("GET", "/api/v1/posts/"+uuid.NewString(), token, ""), http.StatusNotFound, "not found")
+
+		// posts are only for the feed's followers
+		e.register("bob")
+		apiError(t, e.api("GET", "/api/v1/posts/"+id, e.apiToken(), ""), http.StatusNotFound, "not found")
+	})
+}
+
+func TestAPIFeedsAndFollows(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		e.register("alice")
+		alice := e.apiToken()
+		e.register("bob")
+		bob := e.apiToken()
+
+		apiError(t, e.api("POST", "/api/v1/feeds", alice, `{"name": "Test feed"}`), http.StatusBadRequest, "name and url are required")
+		apiError(t, e.api("POST", "/api/v1/feeds", alice, `not json`), http.StatusBadRequest, "name and url are required")
+
+		var feed apiFeed
+		decode(t, e.api("POST", "/api/v1/feeds", alice, `{"name": "Test feed", "url": "https://example.com/feed"}`), http.StatusCreated, &feed)
+
+		if feed.Name != "Test feed" || feed.URL != "https://example.com/feed" || feed.UserID != e.user("alice").ID || feed.LastFetchedAt != nil {
+			t.Errorf("created feed is %+v", feed)
+		}
+
+		apiError(t, e.api("POST", "/api/v1/feeds", bob, `{"name": "Copy", "url": "https://example.com/feed"}`),
+			http.StatusConflict, "a feed with this url already exists")
+
+		var feeds []apiFeed
+		decode(t, e.api("GET", "/api/v1/feeds", bob, ""), http.StatusOK, &feeds)
+
+		if len(feeds) != 1 || feeds[0].ID != feed.ID {
+			t.Errorf("feeds are %+v", feeds)
+		}
+
+		apiError(t, e.api("POST", "/api/v1/follows", bob, `{}`), http.StatusBadRequest, "url is required")
+		apiError(t, e.api("POST", "/api/v1/follows", bob, `{"url": "https://example.com/other"}`), http.StatusNotFound, "not found")
+
+		if w := e.api("POST", "/api/v1/follows", bob, `{"url": "https://example.com/feed", "group": "news"}`); w.Code != http.StatusCreated {
+			t.Fatalf("following answered %d\n%s", w.Code, w.Body)
+		}
+
+		apiError(t, e.api("POST", "/api/v1/follows", bob, `{"url": "https://example.com/feed"}`), http.StatusConflict, "already following")
+
+		var follows []apiFollow
+		decode(t, e.api("GET", "/api/v1/follows", bob, ""), http.StatusOK, &follows)
+
+		if len(follows) != 1 || follows[0].FeedID != feed.ID || !slices.Equal(follows[0].Groups, []string{"news"}) {
+			t.Errorf("follows are %+v", follows)
+		}
+
+		path := "/api/v1/follows/" + feed.ID.String()
+
+		if w := e.api("DELETE", path, bob, ""); w.Code != http.StatusNoContent {
+			t.Errorf("unfollowing answered %d", w.Code)
+		}
+
+		apiError(t, e.api("DELETE", path, bob, ""), http.StatusNotFound, "not following")
+		apiError(t, e.api("DELETE", "/api/v1/follows/42", bob, ""), http.StatusBadRequest, "invalid feed id")
+	})
+}
+
+// brokenPostsStore fails listing posts.
+type brokenPostsStore struct {
+	database.Store
+}
+
+func (brokenPostsStore) ListPostsForUser(ctx context.Context, arg database.ListPostsForUserParams) ([]database.ListPostsForUserRow, error) {
+	return nil, errors.New(`pq: relation "posts" does not exist`)
+}
+
+// Internal errors are logged, not shown to clients.
+func TestAPIInternalError(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		e.register("alice")
+		token := e.apiToken()
+		e.s.db = brokenPostsStore{e.s.db}
+
+		apiError(t, e.api("GET", "/api/v1/posts", token, ""), http.StatusInternalServerError, "internal server error")
+	})
+}
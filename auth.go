@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+)
+
+const (
+	sessionTTL        = 30 * 24 * time.Hour
+	resetCodeTTL      = 24 * time.Hour
+	minPasswordLength = 8
+)
+
+var (
+	errBadLogin     = errors.New("invalid username or password")
+	errBadResetCode = errors.New("invalid or expired reset code")
+)
+
+func handlerPasswd(s *state, cmd command, user database.User) error {
+	if user.PasswordHash.Valid {
+		current, err := readPassword("Current password: ")
+
+		if err != nil {
+			return err
+		}
+
+		err = checkPassword(user, current)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	password, err := newPassword()
+
+	if err != nil {
+		return err
+	}
+
+	err = setPassword(s.db, user, password)
+
+	if err != nil {
+		return err
+	}
+
+	// Changing the password signs out every other session.
+	err = s.db.DeleteSessionsForUser(context.Background(), user.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed revoking sessions: %w", err)
+	}
+
+	token, err := createSession(context.Background(), s, user)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.cfg.SetSession(user.Name, token)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Password changed for %s. Other sessions have been logged out.\n", user.Name)
+
+	return nil
+}
+
+// newPassword asks for a password twice and returns it once both entries
+// match.
+func newPassword() (string, error) {
+	password, err := readPassword("Password: ")
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+
+	again, err := readPassword("Repeat password: ")
+
+	if err != nil {
+		return "", err
+	}
+
+	if password != again {
+		return "", fmt.Errorf("passwords do not match")
+	}
+
+	return password, nil
+}
+
+// readPassword reads a password without echoing it when stdin is a
+// terminal, and reads a plain line otherwise so scripts can pipe it in.
+func readPassword(msg string) (string, error) {
+	fd := int(os.Stdin.Fd())
+
+	if !term.IsTerminal(fd) {
+		return prompt(msg)
+	}
+
+	fmt.Print(msg)
+	b, err := term.ReadPassword(fd)
+	fmt.Println()
+
+	if err != nil {
+		return "", fmt.Errorf("failed reading password: %w", err)
+	}
+
+	return string(b), nil
+}
+
+func hashPassword(password string) (sql.NullString, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed hashing password: %w", err)
+	}
+
+	return sql.NullString{String: string(hash), Valid: true}, nil
+}
+
+func checkPassword(user database.User, password string) error {
+	if !user.PasswordHash.Valid {
+		return errBadLogin
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(password))
+
+	if err != nil {
+		return errBadLogin
+	}
+
+	return nil
+}
+
+func setPassword(db database.Store, user database.User, password string) error {
+	hash, err := hashPassword(password)
+
+	if err != nil {
+		return err
+	}
+
+	err = db.SetUserPassword(context.Background(),
+		database.SetUserPasswordParams{
+			PasswordHash: hash,
+			UpdatedAt:    time.Now(),
+			ID:           user.ID,
+		})
+
+	if err != nil {
+		return fmt.Errorf("failed saving password: %w", err)
+	}
+
+	return nil
+}
+
+// issueResetCode creates a one-time code that lets user choose a new password
+// with login --reset-code, replacing any code issued before. Only its hash is
+// stored.
+func issueResetCode(ctx context.Context, s *state, user database.User) (string, time.Time, error) {
+	code, err := newToken()
+
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expires := now.Add(resetCodeTTL)
+
+	err = s.db.SetPasswordReset(ctx,
+		database.SetPasswordResetParams{
+			UserID:    user.ID,
+			CreatedAt: now,
+			CodeHash:  hashToken(code),
+			ExpiresAt: expires,
+		})
+
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed saving reset code: %w", err)
+	}
+
+	return code, expires, nil
+}
+
+// resetPassword asks for user's reset code and a new password. The code is
+// used up and the user's other sessions are revoked.
+func resetPassword(s *state, user database.User) error {
+	ctx := context.Background()
+
+	code, err := readPassword("Reset code: ")
+
+	if err != nil {
+		return err
+	}
+
+	reset, err := s.db.GetPasswordReset(ctx,
+		database.GetPasswordResetParams{
+			UserID:    user.ID,
+			ExpiresAt: time.Now(),
+		})
+
+	if err != nil || subtle.ConstantTimeCompare([]byte(reset.CodeHash), []byte(hashToken(code))) != 1 {
+		return errBadResetCode
+	}
+
+	password, err := newPassword()
+
+	if err != nil {
+		return err
+	}
+
+	return s.db.InTx(ctx, func(tx database.Store) error {
+		// the code may have been used while the new password was typed in,
+		// only one of the resets gets to delete it
+		n, err := tx.DeletePasswordReset(ctx, user.ID)
+
+		if err != nil {
+			return fmt.Errorf("failed using up reset code: %w", err)
+		}
+
+		if n == 0 {
+			return errBadResetCode
+		}
+
+		err = setPassword(tx, user, password)
+
+		if err != nil {
+			return err
+		}
+
+		err = tx.DeleteSessionsForUser(ctx, user.ID)
+
+		if err != nil {
+			return fmt.Errorf("failed revoking sessions: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// createSession starts a new session for user and returns its token. Only
+// the token's hash is stored, so deleting the row revokes it.
+func createSession(ctx context.Context, s *state, user database.User) (string, error) {
+	now := time.Now()
+
+	err := s.db.DeleteExpiredSessions(ctx, now)
+
+	if err != nil {
+		return "", fmt.Errorf("failed cleaning up sessions: %w", err)
+	}
+
+	token, err := newToken()
+
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.db.CreateSession(ctx,
+		database.CreateSessionParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UserID:    user.ID,
+			TokenHash: hashToken(token),
+			ExpiresAt: now.Add(sessionTTL),
+		})
+
+	if err != nil {
+		return "", fmt.Errorf("failed creating session: %w", err)
+	}
+
+	return token, nil
+}
+
+func userFromSession(ctx context.Context, s *state, token string) (database.User, error) {
+	now := time.Now()
+
+	user, err := s.db.GetUserBySession(ctx,
+		database.GetUserBySessionParams{
+			TokenHash: hashToken(token),
+			ExpiresAt: now,
+		})
+
+	if err != nil {
+		return database.User{}, err
+	}
+
+	err = s.db.TouchSession(ctx,
+		database.TouchSessionParams{
+			LastUsedAt: sql.NullTime{Time: now, Valid: true},
+			TokenHash:  hashToken(token),
+		})
+
+	if err != nil {
+		return database.User{}, err
+	}
+
+	return user, nil
+}
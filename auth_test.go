@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/w0/aggregator/internal/database"
+)
+
+const newTestPassword = "battery staple"
+
+// resetCode has an admin issue a reset code for name and returns it.
+func (e *testEnv) resetCode(name string) string {
+	e.t.Helper()
+
+	out := e.must("", "user", "reset-code", name)
+	_, rest, _ := strings.Cut(out, "\n\n\t")
+	code, _, _ := strings.Cut(rest, "\n")
+
+	if code == "" {
+		e.t.Fatalf("user reset-code printed\n%s", out)
+	}
+
+	return code
+}
+
+func TestResetCode(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		e.register("alice")
+		e.register("bob")
+		session := e.s.cfg.SessionToken
+
+		e.login("alice")
+		code := e.resetCode("bob")
+
+		if err := e.fails("wrong code\n", "login", "bob", "--reset-code"); err != errBadResetCode {
+			t.Errorf("login with a wrong code: %v, want %v", err, errBadResetCode)
+		}
+
+		input := code + "\n" + newTestPassword + "\n" + newTestPassword + "\n"
+
+		if out := e.must(input, "login", "bob", "--reset-code"); !strings.HasSuffix(out, "Logged in as bob\n") {
+			t.Errorf("login --reset-code printed\n%s", out)
+		}
+
+		e.must(newTestPassword+"\n", "login", "bob")
+
+		if err := e.fails(testPassword+"\n", "login", "bob"); err != errBadLogin {
+			t.Errorf("login with the old password: %v, want %v", err, errBadLogin)
+		}
+
+		// the code is used up
+		if err := e.fails(input, "login", "bob", "--reset-code"); err != errBadResetCode {
+			t.Errorf("using a code again: %v, want %v", err, errBadResetCode)
+		}
+
+		// and the sessions from before the reset are revoked
+		e.s.cfg.SessionToken = session
+
+		if err := e.fails("", "following"); !strings.Contains(err.Error(), "log in again") {
+			t.Errorf("following with a session from before the reset: %v", err)
+		}
+	})
+}
+
+func TestResetCodeExpires(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		e.register("alice")
+		e.register("bob")
+
+		e.login("alice")
+		old := e.resetCode("bob")
+
+		// a newer code replaces it
+		code := e.resetCode("bob")
+
+		if err := e.fails(old+"\n", "login", "bob", "--reset-code"); err != errBadResetCode {
+			t.Errorf("login with a replaced code: %v, want %v", err, errBadResetCode)
+		}
+
+		now := time.Now()
+
+		err := e.s.db.SetPasswordReset(context.Background(),
+			database.SetPasswordResetParams{
+				UserID:    e.user("bob").ID,
+				CreatedAt: now.Add(-resetCodeTTL - time.Minute),
+				CodeHash:  hashToken(code),
+				ExpiresAt: now.Add(-time.Minute),
+			})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		input := code + "\n" + newTestPassword + "\n" + newTestPassword + "\n"
+
+		if err := e.fails(input, "login", "bob", "--reset-code"); err != errBadResetCode {
+			t.Errorf("login with an expired code: %v, want %v", err, errBadResetCode)
+		}
+	})
+}
+
+// Setting someone's password revokes their sessions and reset code.
+func TestUserPasswd(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		e.register("alice")
+		e.register("bob")
+		session := e.s.cfg.SessionToken
+
+		e.login("alice")
+		code := e.resetCode("bob")
+
+		if out := e.must(newTestPassword+"\n"+newTestPassword+"\n", "user", "passwd", "bob"); !strings.Contains(out, "Their sessions have been logged out.") {
+			t.Errorf("user passwd printed\n%s", out)
+		}
+
+		input := code + "\n" + newTestPassword + "\n" + newTestPassword + "\n"
+
+		if err := e.fails(input, "login", "bob", "--reset-code"); err != errBadResetCode {
+			t.Errorf("login with a code issued before the new password: %v, want %v", err, errBadResetCode)
+		}
+
+		e.s.cfg.SessionToken = session
+		e.s.cfg.CurrentUserName = "bob"
+
+		if err := e.fails("", "following"); !strings.Contains(err.Error(), "log in again") {
+			t.Errorf("following with a session from before the new password: %v", err)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/w0/aggregator/internal/database"
+)
+
+// snapshot is a full copy of the database written before destructive
+// commands. Every table is in it, secrets included as they are stored, which
+// is why backups are only readable by their owner.
+type snapshot struct {
+	CreatedAt           time.Time
+	Users               []database.User
+	Feeds               []database.Feed
+	FeedFollows         []database.FeedFollow
+	FeedFollowGroups    []database.FeedFollowGroup
+	Posts               []database.Post
+	PostStates          []database.PostState
+	PostTags            []database.PostTag
+	Filters             []database.Filter
+	ApiTokens           []database.ApiToken
+	Sessions            []database.Session
+	PasswordResets      []database.PasswordReset
+	Webhooks            []database.Webhook
+	WebhookDeliveries   []database.WebhookDelivery
+	DigestPrefs         []database.DigestPref
+	WebsubSubscriptions []database.WebsubSubscription
+	FeedRetention       []database.FeedRetention
+	PrunedUrls          []database.PrunedUrl
+	FeedFetches         []database.FeedFetch
+	FeedCredentials     []database.FeedCredential
+}
+
+func takeSnapshot(s *state) (snapshot, error) {
+	ctx := context.Background()
+	snap := snapshot{CreatedAt: time.Now()}
+
+	var err error
+
+	if snap.Users, err = s.db.GetUsers(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.Feeds, err = s.db.GetAllFeeds(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.FeedFollows, err = s.db.GetAllFeedFollows(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.FeedFollowGroups, err = s.db.GetAllFeedFollowGroups(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.Posts, err = s.db.GetAllPosts(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.PostStates, err = s.db.GetAllPostStates(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.PostTags, err = s.db.GetAllPostTags(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.Filters, err = s.db.GetAllFilters(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.ApiTokens, err = s.db.GetAllApiTokens(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.Sessions, err = s.db.GetAllSessions(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.PasswordResets, err = s.db.GetAllPasswordResets(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.Webhooks, err = s.db.GetAllWebhooks(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.WebhookDeliveries, err = s.db.GetAllWebhookDeliveries(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.DigestPrefs, err = s.db.GetAllDigestPrefs(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.WebsubSubscriptions, err = s.db.GetAllWebsubSubscriptions(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.FeedRetention, err = s.db.GetAllFeedRetention(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.PrunedUrls, err = s.db.GetAllPrunedUrls(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.FeedFetches, err = s.db.GetAllFeedFetches(ctx); err != nil {
+		return snapshot{}, err
+	}
+	if snap.FeedCredentials, err = s.db.GetAllFeedCredentials(ctx); err != nil {
+		return snapshot{}, err
+	}
+
+	return snap, nil
+}
+
+// writeBackup stores a JSON snapshot of the database in file.
+func writeBackup(s *state, file string) error {
+	snap, err := takeSnapshot(s)
+
+	if err != nil {
+		return fmt.Errorf("failed reading database for backup: %w", err)
+	}
+
+	b, err := json.MarshalIndent(snap, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(file, b, 0600)
+
+	if err != nil {
+		return fmt.Errorf("failed writing backup: %w", err)
+	}
+
+	fmt.Printf("Backup written to %s\n", file)
+
+	return nil
+}
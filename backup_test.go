@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Every table has a field in snapshot named after it, so reset can't delete
+// anything the backup leaves out.
+func TestSnapshotCoversEveryTable(t *testing.T) {
+	_, db := openTestDB(t, "sqlite://"+filepath.Join(t.TempDir(), "gator.db"))
+
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'goose_db_version'`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rows.Close()
+
+	fields := map[string]bool{}
+	upper := regexp.MustCompile(`[A-Z]`)
+
+	typ := reflect.TypeFor[snapshot]()
+
+	for i := range typ.NumField() {
+		if f := typ.Field(i); f.Type.Kind() == reflect.Slice {
+			snake := upper.ReplaceAllStringFunc(f.Name, func(s string) string { return "_" + strings.ToLower(s) })
+			fields[strings.TrimPrefix(snake, "_")] = true
+		}
+	}
+
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			t.Fatal(err)
+		}
+
+		if !fields[table] {
+			t.Errorf("snapshot has no field for %s", table)
+		}
+	}
+}
+
+func TestResetBackup(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed := newTestFeed(t, testItem(1))
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.must("", "feed", "retention", feed.URL, "--max-posts", "5")
+		e.must("", "token", "create")
+		e.must("", "webhook", "add", "https://example.com/hook")
+		e.fetchAll()
+
+		file := filepath.Join(t.TempDir(), "backup.json")
+		e.must("", "reset", "--yes", "--backup", file, "all")
+
+		if users, _ := e.s.db.GetUsers(context.Background()); len(users) != 0 {
+			t.Errorf("reset all left %d users", len(users))
+		}
+
+		b, err := os.ReadFile(file)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var snap snapshot
+
+		if err := json.Unmarshal(b, &snap); err != nil {
+			t.Fatal(err)
+		}
+
+		counts := map[string]int{
+			"users":          len(snap.Users),
+			"feeds":          len(snap.Feeds),
+			"posts":          len(snap.Posts),
+			"api tokens":     len(snap.ApiTokens),
+			"sessions":       len(snap.Sessions),
+			"webhooks":       len(snap.Webhooks),
+			"feed retention": len(snap.FeedRetention),
+			"feed fetches":   len(snap.FeedFetches),
+		}
+
+		for what, n := range counts {
+			if n != 1 {
+				t.Errorf("backup has %d %s, want 1", n, what)
+			}
+		}
+
+		info, err := os.Stat(file)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("backup is %v, want it readable only by its owner", info.Mode().Perm())
+		}
+	})
+}
@@ -2,8 +2,14 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"flag"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -27,26 +33,67 @@ func (c *commands) run(s *state, cmd command) error {
 	return c.cmds[cmd.name](s, cmd)
 }
 
+const loginUsage = `usage: aggregator login <username> [--reset-code]`
+
 func handlerLogin(s *state, cmd command) error {
 	if len(cmd.arguments) == 0 {
-		return fmt.Errorf("usage: aggregator login <username>")
+		return fmt.Errorf(loginUsage)
 	}
 
 	username := cmd.arguments[0]
 
-	_, err := s.db.GetUser(context.Background(), username)
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	withCode := fs.Bool("reset-code", false, "choose a new password with a one-time reset code")
+
+	if err := fs.Parse(cmd.arguments[1:]); err != nil || fs.NArg() > 0 {
+		return fmt.Errorf(loginUsage)
+	}
+
+	user, err := s.db.GetUser(context.Background(), username)
+
+	if err != nil {
+		return errBadLogin
+	}
+
+	if *withCode {
+		err = resetPassword(s, user)
+
+		if err != nil {
+			return err
+		}
+	} else {
+		// Accounts created before passwords existed can't log in until
+		// they are given one.
+		if !user.PasswordHash.Valid {
+			return fmt.Errorf("%s has no password yet, ask an admin to set one or for a reset code", username)
+		}
+
+		password, err := readPassword("Password: ")
+
+		if err != nil {
+			return err
+		}
+
+		err = checkPassword(user, password)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	token, err := createSession(context.Background(), s, user)
 
 	if err != nil {
-		return fmt.Errorf("user not found: %w", err)
+		return err
 	}
 
-	err = s.cfg.SetUser(username)
+	err = s.cfg.SetSession(username, token)
 
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("Set Username: %s\n", username)
+	fmt.Printf("Logged in as %s\n", username)
 
 	return nil
 }
@@ -56,44 +103,146 @@ func handlerRegister(s *state, cmd command) error {
 		return fmt.Errorf("usage: aggregator register <username>")
 	}
 
+	password, err := newPassword()
+
+	if err != nil {
+		return err
+	}
+
+	hash, err := hashPassword(password)
+
+	if err != nil {
+		return err
+	}
+
 	now := time.Now()
 
 	user, err := s.db.CreateUser(context.Background(), database.CreateUserParams{
-		ID:        uuid.New(),
-		CreatedAt: now,
-		UpdatedAt: now,
-		Name:      cmd.arguments[0],
+		ID:           uuid.New(),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Name:         cmd.arguments[0],
+		PasswordHash: hash,
 	})
 
 	if err != nil {
 		return fmt.Errorf("user already exists: %w", err)
 	}
 
-	err = s.cfg.SetUser(cmd.arguments[0])
+	token, err := createSession(context.Background(), s, user)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.cfg.SetSession(user.Name, token)
 
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("User: %s has been created.\n", cmd.arguments[0])
-	fmt.Printf("Data: %v\n", user)
+	fmt.Printf("User: %s has been created.\n", user.Name)
+	fmt.Printf("Data: %s created at %s\n", user.ID, user.CreatedAt.Format(time.DateTime))
 
 	return nil
 }
 
-func handlerReset(s *state, cmd command) error {
-	err := s.db.DeleteUsers(context.Background())
+const resetUsage = `usage: aggregator reset [--yes] [--backup <file>] [all||posts||fetch||user <username>]`
+
+func handlerReset(s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	backup := fs.String("backup", "", "write a backup snapshot to file before resetting")
+
+	if err := fs.Parse(cmd.arguments); err != nil {
+		return fmt.Errorf(resetUsage)
+	}
+
+	scope := "all"
+	if fs.NArg() > 0 {
+		scope = fs.Arg(0)
+	}
+
+	ctx := context.Background()
+	phrase := scope
+
+	var description, done string
+	var reset func() error
+
+	switch scope {
+	case "all":
+		description = "delete every user, feed, follow and post"
+		done = "All users have been deleted from the database."
+		reset = func() error {
+			return s.db.DeleteUsers(ctx)
+		}
+	case "posts":
+		description = "delete every saved post"
+		done = "All posts have been deleted from the database."
+		reset = func() error {
+			_, err := s.db.DeletePosts(ctx)
+			return err
+		}
+	case "fetch":
+		description = "mark every feed as never fetched"
+		done = "All feeds will be fetched again."
+		reset = func() error {
+			return s.db.ResetFeedsFetched(ctx, time.Now())
+		}
+	case "user":
+		if fs.NArg() < 2 {
+			return fmt.Errorf(resetUsage)
+		}
+
+		target, err := s.db.GetUser(ctx, fs.Arg(1))
+
+		if err != nil {
+			return fmt.Errorf("user not found: %w", err)
+		}
+
+		phrase = target.Name
+		description = fmt.Sprintf("delete user %s with their feeds, follows and filters", target.Name)
+		done = fmt.Sprintf("User %s has been deleted from the database.", target.Name)
+		reset = func() error {
+			_, err := s.db.DeleteUser(ctx, target.ID)
+			return err
+		}
+	default:
+		return fmt.Errorf(resetUsage)
+	}
+
+	if !*yes {
+		answer, err := prompt(fmt.Sprintf("This will %s.\nType %q to confirm: ", description, phrase))
+
+		if err != nil {
+			return err
+		}
+
+		if answer != phrase {
+			return fmt.Errorf("reset cancelled")
+		}
+	}
+
+	if *backup != "" {
+		err := writeBackup(s, *backup)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	err := reset()
 
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("All users have been deleted from the database.")
+	fmt.Println(done)
 
 	return nil
 }
 
-func handlerUsers(s *state, cmd command) error {
+func handlerUsers(s *state, cmd command, user database.User) error {
 	users, err := s.db.GetUsers(context.Background())
 
 	if err != nil {
@@ -102,7 +251,10 @@ func handlerUsers(s *state, cmd command) error {
 
 	for _, v := range users {
 		out := fmt.Sprintf("* %s", v.Name)
-		if v.Name == s.cfg.CurrentUserName {
+		if v.Role == roleAdmin {
+			out = fmt.Sprintf("%s [admin]", out)
+		}
+		if v.ID == user.ID {
 			out = fmt.Sprintf("%s (current)", out)
 		}
 		fmt.Println(out)
@@ -112,30 +264,80 @@ func handlerUsers(s *state, cmd command) error {
 }
 
 func handlerAgg(s *state, cmd command) error {
-	if len(cmd.arguments) == 0 {
-		return fmt.Errorf("usage: aggregator agg <1s>||<1m>||<1h>")
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	listen := fs.String("listen", "", "serve websub callbacks on this address")
+	metricsAddr := fs.String("metrics", "", "serve prometheus metrics on this address")
+	prune := fs.Bool("prune", false, "prune each feed by its retention policy after fetching it")
+	workers := fs.Int("workers", defaultFetchWorkers, "how many feeds to fetch at the same time on each tick")
+
+	if err := fs.Parse(cmd.arguments); err != nil || fs.NArg() != 1 || *workers <= 0 {
+		return fmt.Errorf("usage: aggregator agg [--listen <addr>] [--metrics <addr>] [--prune] [--workers <n>] <1s>||<1m>||<1h>")
 	}
 
-	timeBetweenRequests, err := time.ParseDuration(cmd.arguments[0])
+	timeBetweenRequests, err := time.ParseDuration(fs.Arg(0))
 
 	if err != nil {
 		return fmt.Errorf("failed to parse duration. %w", err)
 	}
 
-	fmt.Printf("Collecting feeds every %s\n", timeBetweenRequests)
+	if *metricsAddr != "" {
+		registerQueueLag(s)
+	}
+
+	if *listen != "" {
+		mux := http.NewServeMux()
+		(&server{s: s}).registerWebSub(mux)
+
+		// one listener can serve both
+		if *metricsAddr == *listen {
+			mux.Handle("GET /metrics", registry)
+		}
+
+		listenInBackground(*listen, mux, "websub")
+
+		slog.Info("listening for websub callbacks", "addr", *listen)
+	}
+
+	if *metricsAddr != "" {
+		if *metricsAddr != *listen {
+			mux := http.NewServeMux()
+			mux.Handle("GET /metrics", registry)
+
+			listenInBackground(*metricsAddr, mux, "metrics")
+		}
+
+		slog.Info("serving metrics", "addr", *metricsAddr, "path", "/metrics")
+	}
+
+	slog.Info("collecting feeds", "interval", timeBetweenRequests, "workers", *workers)
 
 	// ticker controls request loop. Loop each time the specified duration is reached.
 	ticker := time.NewTicker(timeBetweenRequests)
 
 	for ; ; <-ticker.C {
-		err = scrapeFeeds(s)
+		err = scrapeFeeds(s, *prune, *workers)
 		if err != nil {
-			fmt.Printf("save erro: %v\n", err)
+			slog.Error("scrape failed", "error", err)
 		}
 	}
 
 }
 
+// listenInBackground serves handler on addr for as long as agg runs.
+func listenInBackground(addr string, handler http.Handler, name string) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil {
+			slog.Error("listener stopped", "listener", name, "addr", addr, "error", err)
+		}
+	}()
+}
+
 func handlerAddFeed(s *state, cmd command, user database.User) error {
 	if len(cmd.arguments) == 0 {
 		return fmt.Errorf("usage: aggregator addfeed <feed name> <url>")
@@ -177,25 +379,135 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 }
 
 func handlerFeeds(s *state, cmd command) error {
-	res, err := s.db.GetFeeds(context.Background())
+	ctx := context.Background()
+
+	res, err := s.db.GetFeeds(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	visible, err := feedVisibility(ctx, s, sessionUser(s))
 
 	if err != nil {
 		return err
 	}
 
 	for _, v := range res {
+		if !visible(database.Feed{ID: v.ID, UserID: v.UserID}) {
+			continue
+		}
+
 		fmt.Printf("Feed: %s (%s), created by: %s\n", v.Name, v.Url, v.CreatedBy)
 	}
 
 	return nil
 }
 
+const feedUsage = `usage: aggregator feed rm <url>
+       aggregator feed rename <url> <name>
+       aggregator feed set-url <old url> <new url>
+       aggregator feed retention <url> [--max-age <days>] [--max-posts <n>] [--keep-unread-or-starred true||false] [--clear]
+       aggregator feed stats <url> [--days <n>]
+       aggregator feed auth <url> [list||basic <username>||bearer||header <name>||cookie <name>||rm <kind> [name]||clear]`
+
+func handlerFeed(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) < 2 {
+		return fmt.Errorf(feedUsage)
+	}
+
+	feed, err := s.db.GetFeedByUrl(context.Background(), cmd.arguments[1])
+
+	if err != nil {
+		return fmt.Errorf("feed %s not found: %w", cmd.arguments[1], err)
+	}
+
+	// anyone can look at a feed's stats
+	if cmd.arguments[0] == "stats" {
+		return handlerFeedStats(s, feed, cmd.arguments[2:])
+	}
+
+	if !canManageFeed(user, feed) {
+		return fmt.Errorf("feed %s can only be changed by the user who added it or an admin", feed.Url)
+	}
+
+	switch cmd.arguments[0] {
+	case "rm":
+		// follows and posts of the feed are removed by the cascade.
+		err = s.db.DeleteFeed(context.Background(), feed.ID)
+
+		if err != nil {
+			return fmt.Errorf("failed to remove feed: %w", err)
+		}
+
+		fmt.Printf("Feed: %s (%s) has been removed\n", feed.Name, feed.Url)
+	case "rename":
+		if len(cmd.arguments) < 3 {
+			return fmt.Errorf(feedUsage)
+		}
+
+		renamed, err := s.db.RenameFeed(context.Background(),
+			database.RenameFeedParams{
+				Name:      cmd.arguments[2],
+				UpdatedAt: time.Now(),
+				ID:        feed.ID,
+			})
+
+		if err != nil {
+			return fmt.Errorf("failed to rename feed: %w", err)
+		}
+
+		fmt.Printf("Feed: %s has been renamed to %s\n", feed.Name, renamed.Name)
+	case "set-url":
+		if len(cmd.arguments) < 3 {
+			return fmt.Errorf(feedUsage)
+		}
+
+		moved, err := s.db.UpdateFeedUrl(context.Background(),
+			database.UpdateFeedUrlParams{
+				Url:       cmd.arguments[2],
+				UpdatedAt: time.Now(),
+				ID:        feed.ID,
+			})
+
+		if err != nil {
+			return fmt.Errorf("failed to change feed url: %w", err)
+		}
+
+		fmt.Printf("Feed: %s moved from %s to %s\n", moved.Name, feed.Url, moved.Url)
+	case "retention":
+		return handlerFeedRetention(s, feed, cmd.arguments[2:])
+	case "auth":
+		return handlerFeedAuth(s, feed, cmd.arguments[2:])
+	default:
+		return fmt.Errorf(feedUsage)
+	}
+
+	return nil
+}
+
+// canManageFeed reports whether user may change or remove feed. Admins may
+// manage every feed.
+func canManageFeed(user database.User, feed database.Feed) bool {
+	return feed.UserID == user.ID || user.Role == roleAdmin
+}
+
 func handlerFollow(s *state, cmd command, user database.User) error {
-	if len(cmd.arguments) == 0 {
-		return fmt.Errorf("usage: aggregator follow <url>")
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	group := fs.String("group", "", "add the feed to a group")
+
+	// flags stop at the url, so anything after it would be silently ignored
+	if err := fs.Parse(cmd.arguments); err != nil || fs.NArg() != 1 {
+		return fmt.Errorf("usage: aggregator follow [--group <group>] <url>")
+	}
+
+	feed, err := s.db.GetFeedByUrl(context.Background(), fs.Arg(0))
+
+	if err != nil {
+		return err
 	}
 
-	feed, err := s.db.GetFeedByUrl(context.Background(), cmd.arguments[0])
+	err = canFollowFeed(context.Background(), s, user, feed)
 
 	if err != nil {
 		return err
@@ -218,6 +530,18 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 
 	fmt.Printf("%s followed %s", follow.UserName, follow.FeedName)
 
+	if *group != "" {
+		err = addFeedToGroup(s, user, feed, *group)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf(" in group %s", *group)
+	}
+
+	fmt.Println()
+
 	return nil
 }
 
@@ -228,8 +552,17 @@ func handlerFollowing(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("%s not following any feeds %w", user.Name, err)
 	}
 
+	groups, err := groupsByFeedUrl(s, user)
+	if err != nil {
+		return fmt.Errorf("failed to get groups %w", err)
+	}
+
 	fmt.Printf("%s is following:\n", user.Name)
 	for _, v := range feeds {
+		if g, ok := groups[v.Url]; ok {
+			fmt.Printf("\t * %s [%s]\n", v.FeedName, strings.Join(g, ", "))
+			continue
+		}
 		fmt.Printf("\t * %s\n", v.FeedName)
 	}
 
@@ -238,25 +571,116 @@ func handlerFollowing(s *state, cmd command, user database.User) error {
 
 func handlerUnfollow(s *state, cmd command, user database.User) error {
 	if len(cmd.arguments) == 0 {
-		return fmt.Errorf("usage: aggregator unfollow <url>")
+		return fmt.Errorf("usage: aggregator unfollow <url>||<name>||<id>||<glob>")
 	}
 
-	err := s.db.DeleteFeedFollow(context.Background(),
-		database.DeleteFeedFollowParams{
-			ID:  user.ID,
-			Url: cmd.arguments[0],
-		})
+	target := cmd.arguments[0]
+
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
 
 	if err != nil {
-		return fmt.Errorf("failed to unfollow: %w", err)
+		return fmt.Errorf("failed to get follows: %w", err)
+	}
+
+	matches, glob := matchFollows(follows, target)
+
+	if len(matches) == 0 {
+		return fmt.Errorf("%s is not following %s", user.Name, target)
+	}
+
+	// a glob may match other feeds than the ones meant, even just one
+	if glob || len(matches) > 1 {
+		fmt.Printf("%s matches %d feeds:\n", target, len(matches))
+		for _, v := range matches {
+			fmt.Printf("\t * %s (%s)\n", v.FeedName, v.Url)
+		}
+
+		question := "Unfollow all of them?"
+		if len(matches) == 1 {
+			question = "Unfollow it?"
+		}
+
+		ok, err := confirm(question)
+
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("unfollow cancelled")
+		}
 	}
 
-	fmt.Printf("unfollowed: %s", cmd.arguments[0])
+	for _, v := range matches {
+		n, err := s.db.DeleteFeedFollow(context.Background(),
+			database.DeleteFeedFollowParams{
+				UserID: user.ID,
+				FeedID: v.FeedID,
+			})
+
+		if err != nil {
+			return fmt.Errorf("failed to unfollow: %w", err)
+		}
+
+		if n == 0 {
+			fmt.Printf("not following: %s\n", v.Url)
+			continue
+		}
+
+		fmt.Printf("unfollowed: %s\n", v.Url)
+	}
 
 	return nil
 }
 
+// matchFollows picks the follows target refers to. target may be a feed url,
+// a feed id, a feed name or a glob matched against names and urls, in which
+// case glob is true.
+func matchFollows(follows []database.GetFeedFollowsForUserRow, target string) (matches []database.GetFeedFollowsForUserRow, glob bool) {
+	id, idErr := uuid.Parse(target)
+
+	for _, v := range follows {
+		if v.Url == target || (idErr == nil && v.FeedID == id) {
+			return []database.GetFeedFollowsForUserRow{v}, false
+		}
+
+		if v.FeedName == target {
+			matches = append(matches, v)
+		}
+	}
+
+	if len(matches) > 0 || !strings.ContainsAny(target, "*?") {
+		return matches, false
+	}
+
+	pattern := globRegexp(target)
+
+	for _, v := range follows {
+		if pattern.MatchString(v.FeedName) || pattern.MatchString(v.Url) {
+			matches = append(matches, v)
+		}
+	}
+
+	return matches, true
+}
+
+// globRegexp compiles a shell style pattern where * matches any run of
+// characters, slashes included, and ? matches a single character.
+func globRegexp(pattern string) *regexp.Regexp {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+
+	return regexp.MustCompile("^" + expr + "$")
+}
+
 func handlerBrowse(s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	group := fs.String("group", "", "only show posts from feeds in group")
+
+	if err := fs.Parse(cmd.arguments); err != nil {
+		return fmt.Errorf("usage: aggregator browse [--group <group>] [limit]")
+	}
 
 	limit, err := func(args []string) (int, error) {
 		if len(args) > 0 {
@@ -264,30 +688,93 @@ func handlerBrowse(s *state, cmd command, user database.User) error {
 		}
 		return 2, nil
 
-	}(cmd.arguments)
+	}(fs.Args())
 
 	if err != nil {
 		return fmt.Errorf("failed to parse limit %w", err)
 	}
 
-	posts, err := s.db.GetPostsForUser(context.Background(),
-		database.GetPostsForUserParams{
-			UserID: user.ID,
-			Limit:  int32(limit),
-		})
+	filters, err := s.db.GetFiltersForUser(context.Background(), user.ID)
 
 	if err != nil {
-		return fmt.Errorf("failed to fetch posts for user %w", err)
+		return fmt.Errorf("failed to fetch filters for user %w", err)
+	}
+
+	params := database.ListPostsForUserParams{
+		UserID: user.ID,
+		Limit:  int32(limit),
+	}
+
+	if *group != "" {
+		params.GroupName = sql.NullString{String: *group, Valid: true}
+	}
+
+	var posts []database.ListPostsForUserRow
+
+	// filters hide posts saved before they were added too, so it can take
+	// more than one page to fill the limit. Only hiding is decided here:
+	// the other actions ran as the post was saved, and running them again
+	// would undo what the user changed since.
+	for len(posts) < limit {
+		page, err := s.db.ListPostsForUser(context.Background(), params)
+
+		if err != nil {
+			return fmt.Errorf("failed to fetch posts for user %w", err)
+		}
+
+		for _, v := range page {
+			hidden, _ := filterOutcome(filters, user.ID, postFields{
+				feed:        []string{v.FeedName, v.FeedUrl},
+				title:       v.Title,
+				description: v.Description.String,
+				author:      v.Author.String,
+				categories:  splitCategories(v.Categories),
+			})
+
+			if !hidden && len(posts) < limit {
+				posts = append(posts, v)
+			}
+		}
+
+		if len(page) < int(params.Limit) {
+			break
+		}
+
+		params.Offset += params.Limit
 	}
 
 	fmt.Printf("\n%s's latest posts\n\n", user.Name)
 	for _, v := range posts {
-		fmt.Printf("%s from %s\n", v.PublishedAt.Time.Format("Mon Jan 2"), v.FeedName)
+		tags, err := s.db.GetPostTags(context.Background(),
+			database.GetPostTagsParams{
+				UserID: user.ID,
+				PostID: v.ID,
+			})
+
+		if err != nil {
+			return fmt.Errorf("failed to fetch tags for post %w", err)
+		}
+
+		fmt.Printf("%s from %s%s\n", v.PublishedAt.Time.Format("Mon Jan 2"), v.FeedName, postMarkers(v.Read, v.Starred))
 		fmt.Printf("--- %s ---\n", v.Title)
 		fmt.Printf("    %v\n", v.Description.String)
 		fmt.Printf("Link: %s\n", v.Url)
+		if len(tags) > 0 {
+			fmt.Printf("Tags: %s\n", strings.Join(tags, ", "))
+		}
 		fmt.Println("=====================================")
 	}
 
 	return nil
 }
+
+func postMarkers(read, starred bool) string {
+	out := ""
+	if read {
+		out += " (read)"
+	}
+	if starred {
+		out += " (starred)"
+	}
+	return out
+}
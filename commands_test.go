@@ -0,0 +1,214 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegisterAndLogin(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		e.register("alice")
+		e.register("bob")
+
+		if role := e.user("alice").Role; role != roleAdmin {
+			t.Errorf("first user is a %s, want an admin", role)
+		}
+
+		if role := e.user("bob").Role; role != roleMember {
+			t.Errorf("second user is a %s, want a member", role)
+		}
+
+		if e.s.cfg.CurrentUserName != "bob" || e.s.cfg.SessionToken == "" {
+			t.Errorf("register didn't log bob in: %+v", e.s.cfg)
+		}
+
+		e.fails(testPassword+"\n"+testPassword+"\n", "register", "alice")
+		e.fails("short\nshort\n", "register", "carol")
+
+		if err := e.fails("wrong password\n", "login", "alice"); err != errBadLogin {
+			t.Errorf("login with the wrong password: %v, want %v", err, errBadLogin)
+		}
+
+		if err := e.fails(testPassword+"\n", "login", "nobody"); err != errBadLogin {
+			t.Errorf("login as a missing user: %v, want %v", err, errBadLogin)
+		}
+
+		e.login("alice")
+
+		if out := e.must("", "users"); !strings.Contains(out, "* alice [admin] (current)") || !strings.Contains(out, "* bob\n") {
+			t.Errorf("users printed\n%s", out)
+		}
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		err := e.fails("", "following")
+
+		if !strings.Contains(err.Error(), "not logged in") {
+			t.Errorf("following without a session: %v", err)
+		}
+
+		e.register("alice")
+		e.register("bob")
+
+		err = e.fails("", "users")
+
+		if !strings.Contains(err.Error(), "requires an admin") {
+			t.Errorf("users as a member: %v", err)
+		}
+
+		e.must("", "logout")
+
+		if e.s.cfg.SessionToken != "" {
+			t.Error("logout kept the session token")
+		}
+
+		e.fails("", "following")
+
+		// a revoked session is refused even while the config still has it
+		e.login("bob")
+		token := e.s.cfg.SessionToken
+		e.must("", "logout")
+		e.s.cfg.SessionToken = token
+
+		err = e.fails("", "following")
+
+		if !strings.Contains(err.Error(), "log in again") {
+			t.Errorf("following with a revoked session: %v", err)
+		}
+	})
+}
+
+func TestFollowAndUnfollow(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed := newTestFeed(t)
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+
+		if out := e.must("", "following"); !strings.Contains(out, "* Test feed") {
+			t.Errorf("alice doesn't follow the feed she added:\n%s", out)
+		}
+
+		e.register("bob")
+
+		if out := e.must("", "feeds"); !strings.Contains(out, "Feed: Test feed ("+feed.URL+"), created by: alice") {
+			t.Errorf("feeds printed\n%s", out)
+		}
+
+		e.must("", "follow", feed.URL)
+		e.fails("", "follow", feed.URL)
+
+		if out := e.must("", "following"); !strings.Contains(out, "* Test feed") {
+			t.Errorf("bob doesn't follow the feed:\n%s", out)
+		}
+
+		if out := e.must("", "unfollow", "Test feed"); !strings.Contains(out, "unfollowed: "+feed.URL) {
+			t.Errorf("unfollow printed\n%s", out)
+		}
+
+		if out := e.must("", "following"); strings.Contains(out, "Test feed") {
+			t.Errorf("bob still follows the feed:\n%s", out)
+		}
+
+		e.fails("", "unfollow", feed.URL)
+	})
+}
+
+func TestUnfollowMatches(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		goBlog, goNews, rustBlog := "https://go.example.com/blog", "https://go.example.com/news", "https://rust.example.com/blog"
+
+		e.register("alice")
+		e.must("", "addfeed", "Go blog", goBlog)
+		e.must("", "addfeed", "Go news", goNews)
+		e.must("", "addfeed", "Rust blog", rustBlog)
+
+		e.register("bob")
+		e.must("", "follow", goBlog)
+		e.must("", "follow", goNews)
+
+		// globs only match bob's own follows
+		if err := e.fails("y\n", "unfollow", "Rust*"); !strings.Contains(err.Error(), "bob is not following Rust*") {
+			t.Errorf("unfollowing a glob matching only alice's follows: %v", err)
+		}
+
+		e.fails("", "unfollow", e.feed(rustBlog).ID.String())
+
+		// a glob asks first, even when it matches one feed
+		out, err := e.run("n\n", "unfollow", "*news")
+
+		if err == nil || !strings.Contains(out, "*news matches 1 feeds:") || !strings.Contains(out, "Unfollow it? [y/N]") {
+			t.Errorf("unfollow of a glob answered no printed\n%s\n%v", out, err)
+		}
+
+		if out := e.must("", "following"); !strings.Contains(out, "* Go news") {
+			t.Errorf("answering no unfollowed the feed:\n%s", out)
+		}
+
+		if out := e.must("y\n", "unfollow", "*news"); !strings.Contains(out, "unfollowed: "+goNews) {
+			t.Errorf("unfollow of a glob printed\n%s", out)
+		}
+
+		// ids don't ask
+		if out := e.must("", "unfollow", e.feed(goBlog).ID.String()); out != "unfollowed: "+goBlog+"\n" {
+			t.Errorf("unfollow by id printed %q", out)
+		}
+
+		if out := e.must("", "following"); strings.Contains(out, "Go") {
+			t.Errorf("bob still follows\n%s", out)
+		}
+
+		// none of it touched alice's follows
+		e.login("alice")
+
+		if out := e.must("", "following"); strings.Count(out, "* ") != 3 {
+			t.Errorf("alice follows\n%s", out)
+		}
+
+		// names don't ask either
+		if out := e.must("", "unfollow", "Go blog"); out != "unfollowed: "+goBlog+"\n" {
+			t.Errorf("unfollow by name printed %q", out)
+		}
+
+		out = e.must("y\n", "unfollow", "https://*")
+
+		if !strings.Contains(out, "matches 2 feeds:") || !strings.Contains(out, "Unfollow all of them?") ||
+			!strings.Contains(out, "unfollowed: "+goNews) || !strings.Contains(out, "unfollowed: "+rustBlog) {
+			t.Errorf("unfollow of a glob matching two feeds printed\n%s", out)
+		}
+	})
+}
+
+func TestBrowse(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed := newTestFeed(t, testItem(1), testItem(2), testItem(3))
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.fetchAll()
+
+		out := e.must("", "browse", "10")
+
+		if n := strings.Count(out, "Link: "); n != 3 {
+			t.Fatalf("browse showed %d posts, want 3:\n%s", n, out)
+		}
+
+		// newest first
+		if strings.Index(out, "Post 3") > strings.Index(out, "Post 1") {
+			t.Errorf("browse isn't newest first:\n%s", out)
+		}
+
+		if n := strings.Count(e.must("", "browse"), "Link: "); n != 2 {
+			t.Errorf("browse without a limit showed %d posts, want 2", n)
+		}
+
+		// posts only show up for followers
+		e.register("bob")
+
+		if n := strings.Count(e.must("", "browse", "10"), "Link: "); n != 0 {
+			t.Errorf("bob sees %d posts of a feed he doesn't follow", n)
+		}
+	})
+}
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"slices"
+	"strconv"
+	textTemplate "text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/config"
+	"github.com/w0/aggregator/internal/database"
+)
+
+const digestUsage = `usage: aggregator digest set [--frequency daily||weekly] [--group-by feed||group] <email>
+       aggregator digest show
+       aggregator digest off
+       aggregator digest send [--dry-run]
+       aggregator digest run <1m>||<1h>`
+
+const maxDigestPosts = 200
+
+var digestPeriods = map[string]time.Duration{
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+var (
+	digestHTML = template.Must(template.ParseFS(templateFS, "templates/digest.html"))
+	digestText = textTemplate.Must(textTemplate.ParseFS(templateFS, "templates/digest.txt"))
+)
+
+// digest lists at most maxDigestPosts of the Total unread posts; Count is
+// how many it lists.
+type digest struct {
+	User     string
+	Since    time.Time
+	Count    int
+	Total    int
+	Sections []digestSection
+}
+
+// Omitted is how many unread posts the digest leaves out.
+func (d digest) Omitted() int {
+	return d.Total - d.Count
+}
+
+type digestSection struct {
+	Name  string
+	Posts []digestPost
+}
+
+type digestPost struct {
+	Title       string
+	URL         string
+	FeedName    string
+	PublishedAt *time.Time
+}
+
+// handlerDigest runs the scheduled mode for every user without a login, and
+// everything else for the logged in user.
+func handlerDigest(s *state, cmd command) error {
+	if len(cmd.arguments) > 0 && cmd.arguments[0] == "run" {
+		return runDigests(s, cmd.arguments[1:])
+	}
+
+	return middlewareLoggedIn(handlerUserDigest)(s, cmd)
+}
+
+func handlerUserDigest(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf(digestUsage)
+	}
+
+	ctx := context.Background()
+	args := cmd.arguments[1:]
+
+	switch cmd.arguments[0] {
+	case "set":
+		fs := flag.NewFlagSet("digest set", flag.ContinueOnError)
+		frequency := fs.String("frequency", "daily", "how often to send the digest, daily or weekly")
+		groupBy := fs.String("group-by", "feed", "group posts by feed or group")
+
+		if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+			return fmt.Errorf(digestUsage)
+		}
+
+		if _, ok := digestPeriods[*frequency]; !ok {
+			return fmt.Errorf("frequency must be daily or weekly")
+		}
+
+		if *groupBy != "feed" && *groupBy != "group" {
+			return fmt.Errorf("group-by must be feed or group")
+		}
+
+		addr, err := mail.ParseAddress(fs.Arg(0))
+
+		if err != nil {
+			return fmt.Errorf("invalid email address: %w", err)
+		}
+
+		now := time.Now()
+
+		prefs, err := s.db.SetDigestPrefs(ctx,
+			database.SetDigestPrefsParams{
+				UserID:    user.ID,
+				CreatedAt: now,
+				UpdatedAt: now,
+				Email:     addr.Address,
+				Frequency: *frequency,
+				GroupBy:   *groupBy,
+			})
+
+		if err != nil {
+			return fmt.Errorf("failed saving digest settings: %w", err)
+		}
+
+		fmt.Printf("Sending a %s digest to %s, grouped by %s.\n", prefs.Frequency, prefs.Email, prefs.GroupBy)
+	case "show":
+		prefs, err := s.db.GetDigestPrefs(ctx, user.ID)
+
+		if errors.Is(err, sql.ErrNoRows) {
+			fmt.Println("Digests are off.")
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed reading digest settings: %w", err)
+		}
+
+		lastSent := "never"
+		if prefs.LastSentAt.Valid {
+			lastSent = prefs.LastSentAt.Time.Format(time.DateTime)
+		}
+
+		fmt.Printf("Email:     %s\n", prefs.Email)
+		fmt.Printf("Frequency: %s\n", prefs.Frequency)
+		fmt.Printf("Group by:  %s\n", prefs.GroupBy)
+		fmt.Printf("Last sent: %s\n", lastSent)
+	case "off":
+		n, err := s.db.DeleteDigestPrefs(ctx, user.ID)
+
+		if err != nil {
+			return fmt.Errorf("failed turning digests off: %w", err)
+		}
+
+		if n == 0 {
+			fmt.Println("Digests were already off.")
+			return nil
+		}
+
+		fmt.Println("Digests are off.")
+	case "send":
+		fs := flag.NewFlagSet("digest send", flag.ContinueOnError)
+		dryRun := fs.Bool("dry-run", false, "print the digest instead of sending it")
+
+		if err := fs.Parse(args); err != nil {
+			return fmt.Errorf(digestUsage)
+		}
+
+		prefs, err := s.db.GetDigestPrefs(ctx, user.ID)
+
+		if err != nil {
+			return fmt.Errorf("digests are off, turn them on with aggregator digest set <email>: %w", err)
+		}
+
+		if *dryRun {
+			d, err := buildDigest(s, user, prefs, time.Now())
+
+			if err != nil {
+				return err
+			}
+
+			return digestText.Execute(os.Stdout, d)
+		}
+
+		n, err := sendDigest(s, user, prefs, time.Now())
+
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			fmt.Println("No unread posts, nothing was sent.")
+			return nil
+		}
+
+		fmt.Printf("Sent %d posts to %s\n", n, prefs.Email)
+	default:
+		return fmt.Errorf(digestUsage)
+	}
+
+	return nil
+}
+
+// runDigests sends every user's digest when it is due, checking on each tick.
+func runDigests(s *state, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(digestUsage)
+	}
+
+	interval, err := time.ParseDuration(args[0])
+
+	if err != nil {
+		return fmt.Errorf("failed to parse duration. %w", err)
+	}
+
+	if s.cfg.SMTP == nil {
+		return fmt.Errorf("no smtp server configured in .gatorconfig.json")
+	}
+
+	slog.Info("checking for due digests", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+
+	for ; ; <-ticker.C {
+		err = sendDueDigests(s, time.Now())
+		if err != nil {
+			slog.Error("digest check failed", "error", err)
+		}
+	}
+}
+
+func sendDueDigests(s *state, now time.Time) error {
+	ctx := context.Background()
+
+	all, err := s.db.GetAllDigestPrefs(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed reading digest settings: %w", err)
+	}
+
+	for _, prefs := range all {
+		if prefs.LastSentAt.Valid && now.Sub(prefs.LastSentAt.Time) < digestPeriods[prefs.Frequency] {
+			continue
+		}
+
+		// one user failing doesn't hold up everyone else's digest
+		user, err := s.db.GetUserById(ctx, prefs.UserID)
+
+		if err != nil {
+			slog.Error("digest failed", "user", prefs.UserID, "error", fmt.Errorf("failed getting user: %w", err))
+			continue
+		}
+
+		n, err := sendDigest(s, user, prefs, now)
+
+		if err != nil {
+			slog.Error("digest failed", "user", user.ID, "error", err)
+			continue
+		}
+
+		slog.Info("sent digest", "user", user.ID, "posts", n)
+	}
+
+	return nil
+}
+
+// sendDigest mails the user's unread posts since their last digest and
+// returns how many were sent. Nothing is mailed when there are none, but
+// either way the next digest starts from now. Posts past maxDigestPosts
+// aren't carried over, the digest says how many it left out instead.
+func sendDigest(s *state, user database.User, prefs database.DigestPref, now time.Time) (int, error) {
+	if s.cfg.SMTP == nil {
+		return 0, fmt.Errorf("no smtp server configured in .gatorconfig.json")
+	}
+
+	d, err := buildDigest(s, user, prefs, now)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if d.Count > 0 {
+		msg, err := composeDigest(s.cfg.SMTP.From, prefs.Email, d, now)
+
+		if err != nil {
+			return 0, err
+		}
+
+		err = sendMail(s.cfg.SMTP, prefs.Email, msg)
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = s.db.MarkDigestSent(context.Background(),
+		database.MarkDigestSentParams{
+			LastSentAt: sql.NullTime{Time: now, Valid: true},
+			UserID:     user.ID,
+		})
+
+	if err != nil {
+		return 0, fmt.Errorf("failed recording digest: %w", err)
+	}
+
+	return d.Count, nil
+}
+
+func buildDigest(s *state, user database.User, prefs database.DigestPref, now time.Time) (digest, error) {
+	since := now.Add(-digestPeriods[prefs.Frequency])
+	if prefs.LastSentAt.Valid {
+		since = prefs.LastSentAt.Time
+	}
+
+	posts, err := s.db.GetUnreadPostsSince(context.Background(),
+		database.GetUnreadPostsSinceParams{
+			UserID:    user.ID,
+			CreatedAt: since,
+			Limit:     maxDigestPosts,
+		})
+
+	if err != nil {
+		return digest{}, fmt.Errorf("failed getting posts: %w", err)
+	}
+
+	total, err := s.db.CountUnreadPostsSince(context.Background(),
+		database.CountUnreadPostsSinceParams{
+			UserID:    user.ID,
+			CreatedAt: since,
+		})
+
+	if err != nil {
+		return digest{}, fmt.Errorf("failed counting posts: %w", err)
+	}
+
+	var groups map[string][]string
+
+	if prefs.GroupBy == "group" {
+		groups, err = groupsByFeedUrl(s, user)
+
+		if err != nil {
+			return digest{}, fmt.Errorf("failed getting groups: %w", err)
+		}
+	}
+
+	d := digest{User: user.Name, Since: since, Count: len(posts), Total: int(total)}
+	sections := make(map[string]*digestSection)
+	var names []string
+
+	for _, v := range posts {
+		keys := []string{v.FeedName}
+		if groups != nil {
+			keys = groups[v.FeedUrl]
+			if len(keys) == 0 {
+				keys = []string{"Ungrouped"}
+			}
+		}
+
+		for _, k := range keys {
+			section, ok := sections[k]
+			if !ok {
+				section = &digestSection{Name: k}
+				sections[k] = section
+				names = append(names, k)
+			}
+
+			section.Posts = append(section.Posts, digestPost{
+				Title:       v.Title,
+				URL:         v.Url,
+				FeedName:    v.FeedName,
+				PublishedAt: nullTime(v.PublishedAt),
+			})
+		}
+	}
+
+	slices.Sort(names)
+	for _, k := range names {
+		d.Sections = append(d.Sections, *sections[k])
+	}
+
+	return d, nil
+}
+
+// composeDigest renders d as a multipart/alternative email with a plain
+// text and an HTML part.
+func composeDigest(from, to string, d digest, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+
+	subject := fmt.Sprintf("Your aggreGator digest: %d unread posts", d.Total)
+	if d.Omitted() > 0 {
+		subject = fmt.Sprintf("Your aggreGator digest: %d of %d unread posts", d.Count, d.Total)
+	}
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@aggregator>\r\n", uuid.New())
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", body.Boundary())
+
+	parts := []struct {
+		contentType string
+		execute     func(*quotedprintable.Writer) error
+	}{
+		{"text/plain", func(w *quotedprintable.Writer) error { return digestText.Execute(w, d) }},
+		{"text/html", func(w *quotedprintable.Writer) error { return digestHTML.Execute(w, d) }},
+	}
+
+	for _, p := range parts {
+		part, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(part)
+
+		err = p.execute(qp)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed rendering digest: %w", err)
+		}
+
+		err = qp.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func sendMail(cfg *config.SMTP, to string, msg []byte) error {
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+
+	// from may include a display name, the envelope only wants the address
+	from, err := mail.ParseAddress(cfg.From)
+
+	if err != nil {
+		return fmt.Errorf("invalid smtp from address: %w", err)
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	err = smtp.SendMail(net.JoinHostPort(cfg.Host, strconv.Itoa(port)), auth, from.Address, []string{to}, msg)
+
+	if err != nil {
+		return fmt.Errorf("failed sending mail to %s: %w", to, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/config"
+	"github.com/w0/aggregator/internal/database"
+)
+
+// smtpServer is enough of a mail server for net/smtp to hand it messages,
+// which it keeps.
+type smtpServer struct {
+	net.Listener
+
+	mu       sync.Mutex
+	messages []*mail.Message
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { l.Close() })
+
+	srv := &smtpServer{Listener: l}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go srv.serve(conn)
+		}
+	}()
+
+	return srv
+}
+
+func (srv *smtpServer) serve(conn net.Conn) {
+	c := textproto.NewConn(conn)
+	defer c.Close()
+
+	c.PrintfLine("220 localhost ESMTP")
+
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, _, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "DATA":
+			c.PrintfLine("354 go ahead")
+
+			msg, err := mail.ReadMessage(c.DotReader())
+			if err != nil {
+				return
+			}
+
+			// read the body before the reply so it is there when SendMail returns
+			body, _ := io.ReadAll(msg.Body)
+			msg.Body = strings.NewReader(string(body))
+
+			srv.mu.Lock()
+			srv.messages = append(srv.messages, msg)
+			srv.mu.Unlock()
+
+			c.PrintfLine("250 queued")
+		case "QUIT":
+			c.PrintfLine("221 bye")
+			return
+		default:
+			c.PrintfLine("250 ok")
+		}
+	}
+}
+
+func (srv *smtpServer) received() []*mail.Message {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	return append([]*mail.Message(nil), srv.messages...)
+}
+
+func (srv *smtpServer) config() *config.SMTP {
+	return &config.SMTP{Host: "127.0.0.1", Port: srv.Addr().(*net.TCPAddr).Port, From: "aggreGator <gator@example.com>"}
+}
+
+// digestParts returns the decoded text and HTML parts of a digest.
+func digestParts(t *testing.T, msg *mail.Message) (string, string) {
+	t.Helper()
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("digest is %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+	}
+
+	parts := map[string]string{}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// quoted-printable parts come out decoded
+		body, err := io.ReadAll(part)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[mediaType] = string(body)
+	}
+
+	return parts["text/plain"], parts["text/html"]
+}
+
+func subject(t *testing.T, msg *mail.Message) string {
+	t.Helper()
+
+	s, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestDigestSend(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		smtp := newSMTPServer(t)
+		feed := newTestFeed(t, testItem(1), testItem(2))
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.fetchAll()
+		e.must("", "digest", "set", "alice@example.com")
+
+		if err := e.fails("", "digest", "send"); !strings.Contains(err.Error(), "no smtp server") {
+			t.Errorf("digest send without a mail server returned %v", err)
+		}
+
+		e.s.cfg.SMTP = smtp.config()
+
+		// a dry run prints the digest and doesn't count as sending it
+		out := e.must("", "digest", "send", "--dry-run")
+
+		if !strings.HasPrefix(out, "2 unread posts since") || !strings.Contains(out, "Post 1") || !strings.Contains(out, "Post 2") {
+			t.Errorf("digest send --dry-run printed\n%s", out)
+		}
+
+		if n := len(smtp.received()); n != 0 {
+			t.Fatalf("dry run sent %d messages", n)
+		}
+
+		if out := e.must("", "digest", "send"); out != "Sent 2 posts to alice@example.com\n" {
+			t.Errorf("digest send printed %q", out)
+		}
+
+		msgs := smtp.received()
+
+		if len(msgs) != 1 {
+			t.Fatalf("mail server got %d messages, want 1", len(msgs))
+		}
+
+		msg := msgs[0]
+
+		if got := msg.Header.Get("To"); got != "alice@example.com" {
+			t.Errorf("digest is to %q", got)
+		}
+
+		if got := subject(t, msg); got != "Your aggreGator digest: 2 unread posts" {
+			t.Errorf("subject is %q", got)
+		}
+
+		text, html := digestParts(t, msg)
+
+		for _, want := range []string{"Post 1", "https://example.com/posts/1", "Post 2", "Test feed"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("text part doesn't mention %q:\n%s", want, text)
+			}
+
+			if !strings.Contains(html, want) {
+				t.Errorf("HTML part doesn't mention %q:\n%s", want, html)
+			}
+		}
+
+		if strings.Contains(text, "Only the first") {
+			t.Errorf("a whole digest says it was cut short:\n%s", text)
+		}
+
+		// the next digest starts after this one
+		if out := e.must("", "digest", "send"); out != "No unread posts, nothing was sent.\n" {
+			t.Errorf("sending again printed %q", out)
+		}
+
+		if n := len(smtp.received()); n != 1 {
+			t.Errorf("mail server got %d messages after sending again, want 1", n)
+		}
+
+		if out := e.must("", "digest", "show"); strings.Contains(out, "Last sent: never") {
+			t.Errorf("digest show printed\n%s", out)
+		}
+	})
+}
+
+// A digest with more than maxDigestPosts says how many it leaves out.
+func TestDigestTruncated(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		smtp := newSMTPServer(t)
+		e.s.cfg.SMTP = smtp.config()
+
+		var items []RSSItem
+		for i := range maxDigestPosts + 5 {
+			items = append(items, testItem(i+1))
+		}
+
+		feed := newTestFeed(t, items...)
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.fetchAll()
+		e.must("", "digest", "set", "--frequency", "weekly", "alice@example.com")
+
+		if out := e.must("", "digest", "send"); out != "Sent 200 posts to alice@example.com\n" {
+			t.Errorf("digest send printed %q", out)
+		}
+
+		msgs := smtp.received()
+
+		if len(msgs) != 1 {
+			t.Fatalf("mail server got %d messages, want 1", len(msgs))
+		}
+
+		if got := subject(t, msgs[0]); got != "Your aggreGator digest: 200 of 205 unread posts" {
+			t.Errorf("subject is %q", got)
+		}
+
+		text, html := digestParts(t, msgs[0])
+
+		if !strings.HasPrefix(text, "205 unread posts since") || !strings.Contains(text, "Only the first 200 are listed here. Read the other 5 with aggregator browse.") {
+			t.Errorf("text part doesn't say it was cut short:\n%.300s", text)
+		}
+
+		if !strings.Contains(html, "Only the first 200 are listed here. Read the other 5 with <code>aggregator browse</code>.") {
+			t.Errorf("HTML part doesn't say it was cut short:\n%.600s", html)
+		}
+
+		if n := strings.Count(text, "https://example.com/posts/"); n != maxDigestPosts {
+			t.Errorf("text part lists %d posts, want %d", n, maxDigestPosts)
+		}
+	})
+}
+
+// missingUserStore fails looking up one user by id.
+type missingUserStore struct {
+	database.Store
+	id uuid.UUID
+}
+
+func (s missingUserStore) GetUserById(ctx context.Context, id uuid.UUID) (database.User, error) {
+	if id == s.id {
+		return database.User{}, errors.New("connection reset")
+	}
+	return s.Store.GetUserById(ctx, id)
+}
+
+// A user who can't be looked up doesn't keep the others from their digests.
+func TestSendDueDigestsSkipsFailures(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		smtp := newSMTPServer(t)
+		e.s.cfg.SMTP = smtp.config()
+		feed := newTestFeed(t, testItem(1))
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.must("", "digest", "set", "alice@example.com")
+
+		e.register("bob")
+		e.must("", "follow", feed.URL)
+		e.must("", "digest", "set", "bob@example.com")
+		e.fetchAll()
+
+		e.s.db = missingUserStore{e.s.db, e.user("alice").ID}
+
+		if err := sendDueDigests(e.s, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		msgs := smtp.received()
+
+		if len(msgs) != 1 || msgs[0].Header.Get("To") != "bob@example.com" {
+			t.Errorf("mail server got %d messages, want one to bob", len(msgs))
+		}
+	})
+}
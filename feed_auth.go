@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+	"golang.org/x/net/http/httpguts"
+)
+
+const feedAuthUsage = `usage: aggregator feed auth <url> [list]
+       aggregator feed auth <url> basic <username>
+       aggregator feed auth <url> bearer
+       aggregator feed auth <url> header <name>
+       aggregator feed auth <url> cookie <name>
+       aggregator feed auth <url> rm basic||bearer
+       aggregator feed auth <url> rm header||cookie <name>
+       aggregator feed auth <url> clear`
+
+// handlerFeedAuth manages the credentials a feed is fetched with. Secrets
+// are always prompted for, so they don't end up in the shell's history.
+func handlerFeedAuth(s *state, feed database.Feed, arguments []string) error {
+	if len(arguments) == 0 {
+		return printCredentials(s, feed)
+	}
+
+	ctx := context.Background()
+
+	switch arguments[0] {
+	case "list":
+		if len(arguments) != 1 {
+			return fmt.Errorf(feedAuthUsage)
+		}
+
+		return printCredentials(s, feed)
+	case credBasic:
+		if len(arguments) != 2 {
+			return fmt.Errorf(feedAuthUsage)
+		}
+
+		if arguments[1] == "" || strings.Contains(arguments[1], ":") {
+			return fmt.Errorf("username can't be empty or contain a colon")
+		}
+
+		return setCredential(s, feed, credBasic, arguments[1], "Password: ")
+	case credBearer:
+		if len(arguments) != 1 {
+			return fmt.Errorf(feedAuthUsage)
+		}
+
+		return setCredential(s, feed, credBearer, "", "Token: ")
+	case credHeader:
+		if len(arguments) != 2 {
+			return fmt.Errorf(feedAuthUsage)
+		}
+
+		name := http.CanonicalHeaderKey(arguments[1])
+
+		if !httpguts.ValidHeaderFieldName(name) {
+			return fmt.Errorf("%q isn't a valid header name", arguments[1])
+		}
+
+		switch name {
+		case "Authorization":
+			return fmt.Errorf("use basic or bearer to set the Authorization header")
+		case "Cookie":
+			return fmt.Errorf("use cookie to set cookies")
+		case "Host", "User-Agent":
+			return fmt.Errorf("the %s header can't be set per feed", name)
+		}
+
+		return setCredential(s, feed, credHeader, name, "Value: ")
+	case credCookie:
+		if len(arguments) != 2 {
+			return fmt.Errorf(feedAuthUsage)
+		}
+
+		if !httpguts.ValidHeaderFieldName(arguments[1]) {
+			return fmt.Errorf("%q isn't a valid cookie name", arguments[1])
+		}
+
+		return setCredential(s, feed, credCookie, arguments[1], "Value: ")
+	case "rm":
+		return removeCredential(s, feed, arguments[1:])
+	case "clear":
+		if len(arguments) != 1 {
+			return fmt.Errorf(feedAuthUsage)
+		}
+
+		err := s.db.InTx(ctx, func(tx database.Store) error {
+			for _, kind := range []string{credBasic, credBearer, credHeader, credCookie} {
+				_, err := tx.DeleteFeedCredentialsByKind(ctx,
+					database.DeleteFeedCredentialsByKindParams{
+						FeedID: feed.ID,
+						Kind:   kind,
+					})
+
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return fmt.Errorf("failed clearing credentials: %w", err)
+		}
+
+		fmt.Printf("Removed all credentials of %s\n", feed.Url)
+	default:
+		return fmt.Errorf(feedAuthUsage)
+	}
+
+	return nil
+}
+
+// setCredential prompts for the secret and saves it encrypted. Basic and
+// bearer credentials replace each other, as both set the Authorization
+// header.
+func setCredential(s *state, feed database.Feed, kind, name, msg string) error {
+	ctx := context.Background()
+
+	key, err := credentialsKey(s.cfg)
+
+	if err != nil {
+		return err
+	}
+
+	// whoever follows the feed reads what the credentials fetch
+	followers, err := s.db.GetFollowersOfFeed(ctx, feed.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed getting followers: %w", err)
+	}
+
+	var others []string
+	for _, v := range followers {
+		if !canManageFeed(v, feed) {
+			others = append(others, v.Name)
+		}
+	}
+
+	if len(others) > 0 {
+		return fmt.Errorf("%s is followed by %s, who could read what the credentials fetch; they have to unfollow it first", feed.Url, strings.Join(others, ", "))
+	}
+
+	secret, err := readPassword(msg)
+
+	if err != nil {
+		return err
+	}
+
+	if secret == "" {
+		return fmt.Errorf("secret can't be empty")
+	}
+
+	if !validSecret(kind, name, secret) {
+		return fmt.Errorf("secret contains characters that can't be sent in a request")
+	}
+
+	sealed, err := sealSecret(key, feed.ID, secret)
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	err = s.db.InTx(ctx, func(tx database.Store) error {
+		if kind == credBasic || kind == credBearer {
+			for _, k := range []string{credBasic, credBearer} {
+				_, err := tx.DeleteFeedCredentialsByKind(ctx,
+					database.DeleteFeedCredentialsByKindParams{
+						FeedID: feed.ID,
+						Kind:   k,
+					})
+
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		_, err := tx.SetFeedCredential(ctx,
+			database.SetFeedCredentialParams{
+				FeedID:    feed.ID,
+				CreatedAt: now,
+				UpdatedAt: now,
+				Kind:      kind,
+				Name:      name,
+				Secret:    sealed,
+			})
+
+		return err
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed saving credential: %w", err)
+	}
+
+	fmt.Printf("Saved %s for %s\n", describeCredential(kind, name), feed.Url)
+
+	return nil
+}
+
+func validSecret(kind, name, secret string) bool {
+	switch kind {
+	case credBearer, credHeader:
+		return httpguts.ValidHeaderFieldValue(secret)
+	case credCookie:
+		return (&http.Cookie{Name: name, Value: secret}).Valid() == nil
+	default:
+		return true
+	}
+}
+
+func removeCredential(s *state, feed database.Feed, arguments []string) error {
+	if len(arguments) == 0 {
+		return fmt.Errorf(feedAuthUsage)
+	}
+
+	ctx := context.Background()
+	kind := arguments[0]
+	name := strings.Join(arguments[1:], "")
+
+	if kind == credHeader {
+		name = http.CanonicalHeaderKey(name)
+	}
+
+	var (
+		n   int64
+		err error
+	)
+
+	switch {
+	case (kind == credBasic || kind == credBearer) && len(arguments) == 1:
+		n, err = s.db.DeleteFeedCredentialsByKind(ctx,
+			database.DeleteFeedCredentialsByKindParams{
+				FeedID: feed.ID,
+				Kind:   kind,
+			})
+	case (kind == credHeader || kind == credCookie) && len(arguments) == 2:
+		n, err = s.db.DeleteFeedCredential(ctx,
+			database.DeleteFeedCredentialParams{
+				FeedID: feed.ID,
+				Kind:   kind,
+				Name:   name,
+			})
+	default:
+		return fmt.Errorf(feedAuthUsage)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed removing credential: %w", err)
+	}
+
+	if n == 0 {
+		return fmt.Errorf("%s has no such credential", feed.Url)
+	}
+
+	fmt.Printf("Removed %s from %s\n", describeCredential(kind, name), feed.Url)
+
+	return nil
+}
+
+func describeCredential(kind, name string) string {
+	switch kind {
+	case credBasic:
+		if name == "" {
+			return "basic auth"
+		}
+		return fmt.Sprintf("basic auth for %s", name)
+	case credBearer:
+		return "bearer token"
+	default:
+		return fmt.Sprintf("%s %s", kind, name)
+	}
+}
+
+// printCredentials lists what a feed is fetched with, leaving the secrets
+// out.
+func printCredentials(s *state, feed database.Feed) error {
+	creds, err := s.db.GetFeedCredentials(context.Background(), feed.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed getting credentials: %w", err)
+	}
+
+	if len(creds) == 0 {
+		fmt.Printf("%s is fetched without credentials\n", feed.Url)
+		return nil
+	}
+
+	fmt.Printf("Credentials for %s (%s)\n\n", feed.Name, feed.Url)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tUPDATED")
+
+	for _, v := range creds {
+		name := v.Name
+		if name == "" {
+			name = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", v.Kind, name, v.UpdatedAt.Format(time.DateTime))
+	}
+
+	return w.Flush()
+}
+
+var errPrivateFeed = errors.New("private to the user who added it")
+
+// canFollowFeed fails unless user may follow feed. Posts of a feed fetched
+// with credentials are only for the user who added it and admins.
+func canFollowFeed(ctx context.Context, s *state, user database.User, feed database.Feed) error {
+	if canManageFeed(user, feed) {
+		return nil
+	}
+
+	n, err := s.db.CountFeedCredentials(ctx, feed.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed checking credentials of %s: %w", feed.Url, err)
+	}
+
+	if n > 0 {
+		return fmt.Errorf("%s is %w", feed.Url, errPrivateFeed)
+	}
+
+	return nil
+}
+
+// feedVisibility returns whether user, nil when nobody is logged in, can see
+// a feed. Feeds fetched with credentials are hidden from those who can't
+// follow them.
+func feedVisibility(ctx context.Context, s *state, user *database.User) (func(database.Feed) bool, error) {
+	ids, err := s.db.GetFeedIdsWithCredentials(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed getting private feeds: %w", err)
+	}
+
+	private := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		private[id] = true
+	}
+
+	return func(feed database.Feed) bool {
+		return !private[feed.ID] || (user != nil && canManageFeed(*user, feed))
+	}, nil
+}
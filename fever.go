@@ -0,0 +1,505 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+// The Fever API (https://feedafever.com/api) is spoken by mobile readers
+// such as Reeder. Items and feeds are addressed by their seq column, groups
+// by a checksum of their name.
+
+const feverPageSize = 50
+
+type feverGroup struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+type feverFeedsGroup struct {
+	GroupID int64  `json:"group_id"`
+	FeedIDs string `json:"feed_ids"`
+}
+
+type feverFeed struct {
+	ID                int64  `json:"id"`
+	FaviconID         int64  `json:"favicon_id"`
+	Title             string `json:"title"`
+	URL               string `json:"url"`
+	SiteURL           string `json:"site_url"`
+	IsSpark           int    `json:"is_spark"`
+	LastUpdatedOnTime int64  `json:"last_updated_on_time"`
+}
+
+type feverItem struct {
+	ID            int64  `json:"id"`
+	FeedID        int64  `json:"feed_id"`
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	HTML          string `json:"html"`
+	URL           string `json:"url"`
+	IsSaved       int    `json:"is_saved"`
+	IsRead        int    `json:"is_read"`
+	CreatedOnTime int64  `json:"created_on_time"`
+}
+
+// feverKey is the api_key a Fever client sends: md5 of "username:password".
+// The password is an api token created with `token create --fever`.
+func feverKey(username, token string) string {
+	sum := md5.Sum([]byte(username + ":" + token))
+	return hex.EncodeToString(sum[:])
+}
+
+func feverGroupID(name string) int64 {
+	return int64(crc32.ChecksumIEEE([]byte(name)))
+}
+
+func (srv *server) handleFever(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseMultipartForm(1 << 20)
+
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res := map[string]any{
+		"api_version": 3,
+		"auth":        0,
+	}
+
+	user, err := srv.s.db.GetUserByApiToken(r.Context(), hashToken(strings.ToLower(r.FormValue("api_key"))))
+
+	if err != nil {
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+
+	res["auth"] = 1
+
+	has := func(key string) bool {
+		_, ok := r.Form[key]
+		return ok
+	}
+
+	follows, err := srv.s.db.GetFeedFollowsForUser(r.Context(), user.ID)
+
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	var refreshed int64
+	for _, v := range follows {
+		if v.LastFetchedAt.Valid && v.LastFetchedAt.Time.Unix() > refreshed {
+			refreshed = v.LastFetchedAt.Time.Unix()
+		}
+	}
+	res["last_refreshed_on_time"] = refreshed
+
+	if r.FormValue("mark") != "" {
+		err = srv.feverMark(r, user, follows)
+
+		if err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+	}
+
+	if has("groups") || has("feeds") {
+		groups, feedsGroups, err := srv.feverGroups(r, user)
+
+		if err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+
+		if has("groups") {
+			res["groups"] = groups
+		}
+		res["feeds_groups"] = feedsGroups
+	}
+
+	if has("feeds") {
+		feeds := make([]feverFeed, 0, len(follows))
+		for _, v := range follows {
+			feed := feverFeed{
+				ID:      v.Seq,
+				Title:   v.FeedName,
+				URL:     v.Url,
+				SiteURL: v.Url,
+			}
+			if v.LastFetchedAt.Valid {
+				feed.LastUpdatedOnTime = v.LastFetchedAt.Time.Unix()
+			}
+			feeds = append(feeds, feed)
+		}
+		res["feeds"] = feeds
+	}
+
+	if has("favicons") {
+		res["favicons"] = []any{}
+	}
+
+	if has("links") {
+		res["links"] = []any{}
+	}
+
+	if has("items") {
+		items, err := srv.feverItems(r, user)
+
+		if err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+
+		total, err := srv.s.db.CountPostsForUser(r.Context(), user.ID)
+
+		if err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+
+		res["items"] = items
+		res["total_items"] = total
+	}
+
+	if has("unread_item_ids") {
+		ids, err := srv.s.db.GetUnreadPostSeqsForUser(r.Context(), user.ID)
+
+		if err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+
+		res["unread_item_ids"] = joinSeqs(ids)
+	}
+
+	if has("saved_item_ids") {
+		ids, err := srv.s.db.GetStarredPostSeqsForUser(r.Context(), user.ID)
+
+		if err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+
+		res["saved_item_ids"] = joinSeqs(ids)
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (srv *server) feverGroups(r *http.Request, user database.User) ([]feverGroup, []feverFeedsGroup, error) {
+	rows, err := srv.s.db.GetGroupsForUser(r.Context(), user.ID)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	follows, err := srv.s.db.GetFeedFollowsForUser(r.Context(), user.ID)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seqs := make(map[string]int64)
+	for _, v := range follows {
+		seqs[v.Url] = v.Seq
+	}
+
+	groups := []feverGroup{}
+	feedIDs := make(map[string][]int64)
+
+	for _, v := range rows {
+		if _, ok := feedIDs[v.Name]; !ok {
+			groups = append(groups, feverGroup{ID: feverGroupID(v.Name), Title: v.Name})
+		}
+		feedIDs[v.Name] = append(feedIDs[v.Name], seqs[v.FeedUrl])
+	}
+
+	feedsGroups := []feverFeedsGroup{}
+	for _, g := range groups {
+		feedsGroups = append(feedsGroups, feverFeedsGroup{
+			GroupID: g.ID,
+			FeedIDs: joinSeqs(feedIDs[g.Title]),
+		})
+	}
+
+	return groups, feedsGroups, nil
+}
+
+// feverItems pages through the user's posts by seq using the with_ids,
+// max_id or since_id parameters.
+func (srv *server) feverItems(r *http.Request, user database.User) ([]feverItem, error) {
+	var posts []database.GetPostsForUserAfterSeqRow
+
+	switch {
+	case r.FormValue("with_ids") != "":
+		ids := strings.Split(r.FormValue("with_ids"), ",")
+		if len(ids) > feverPageSize {
+			ids = ids[:feverPageSize]
+		}
+
+		for _, id := range ids {
+			seq, err := strconv.ParseInt(strings.TrimSpace(id), 10, 64)
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid item id %s", id)
+			}
+
+			post, err := srv.s.db.GetPostForUserBySeq(r.Context(),
+				database.GetPostForUserBySeqParams{
+					UserID: user.ID,
+					Seq:    seq,
+				})
+
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			posts = append(posts, database.GetPostsForUserAfterSeqRow(post))
+		}
+	case r.FormValue("max_id") != "":
+		maxID, err := strconv.ParseInt(r.FormValue("max_id"), 10, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_id")
+		}
+
+		rows, err := srv.s.db.GetPostsForUserBeforeSeq(r.Context(),
+			database.GetPostsForUserBeforeSeqParams{
+				UserID: user.ID,
+				Seq:    maxID,
+				Limit:  feverPageSize,
+			})
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range rows {
+			posts = append(posts, database.GetPostsForUserAfterSeqRow(v))
+		}
+	default:
+		sinceID, err := queryInt(r.FormValue("since_id"), 0)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid since_id")
+		}
+
+		posts, err = srv.s.db.GetPostsForUserAfterSeq(r.Context(),
+			database.GetPostsForUserAfterSeqParams{
+				UserID: user.ID,
+				Seq:    int64(sinceID),
+				Limit:  feverPageSize,
+			})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	items := make([]feverItem, 0, len(posts))
+	for _, v := range posts {
+		items = append(items, feverItem{
+			ID:            v.Seq,
+			FeedID:        v.FeedSeq,
+			Title:         v.Title,
+			Author:        v.Author.String,
+			HTML:          v.Description.String,
+			URL:           v.Url,
+			IsSaved:       feverBool(v.Starred),
+			IsRead:        feverBool(v.Read),
+			CreatedOnTime: postTime(v.PublishedAt, v.CreatedAt).Unix(),
+		})
+	}
+
+	return items, nil
+}
+
+// feverMark handles mark=item|feed|group with as=read|unread|saved|unsaved.
+// Feeds and groups can only be marked read, up to the before timestamp.
+func (srv *server) feverMark(r *http.Request, user database.User, follows []database.GetFeedFollowsForUserRow) error {
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+
+	if err != nil {
+		return fmt.Errorf("invalid id")
+	}
+
+	as := r.FormValue("as")
+
+	switch r.FormValue("mark") {
+	case "item":
+		post, err := srv.s.db.GetPostForUserBySeq(r.Context(),
+			database.GetPostForUserBySeqParams{
+				UserID: user.ID,
+				Seq:    id,
+			})
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		switch as {
+		case "read", "unread":
+			return setPostRead(srv.s, user, post.ID, as == "read")
+		case "saved", "unsaved":
+			return setPostStarred(srv.s, user, post.ID, as == "saved")
+		}
+	case "feed", "group":
+		if as != "read" {
+			return nil
+		}
+
+		before := time.Now()
+		if v := r.FormValue("before"); v != "" {
+			ts, err := strconv.ParseInt(v, 10, 64)
+
+			if err != nil {
+				return fmt.Errorf("invalid before")
+			}
+
+			before = time.Unix(ts, 0)
+		}
+
+		params := database.ListPostsForUserParams{
+			UserID:     user.ID,
+			UnreadOnly: true,
+		}
+
+		if r.FormValue("mark") == "feed" {
+			for _, v := range follows {
+				if v.Seq == id {
+					params.FeedID = uuid.NullUUID{UUID: v.FeedID, Valid: true}
+				}
+			}
+
+			if !params.FeedID.Valid {
+				return nil
+			}
+		} else if id != 0 {
+			groups, err := srv.s.db.GetGroupsForUser(r.Context(), user.ID)
+
+			if err != nil {
+				return err
+			}
+
+			for _, v := range groups {
+				if feverGroupID(v.Name) == id {
+					params.GroupName = sql.NullString{String: v.Name, Valid: true}
+				}
+			}
+
+			if !params.GroupName.Valid {
+				return nil
+			}
+		}
+
+		return markAllRead(srv.s, user, params, before)
+	}
+
+	return nil
+}
+
+// markAllRead marks every post selected by params as read when it was
+// published before the given time.
+func markAllRead(s *state, user database.User, params database.ListPostsForUserParams, before time.Time) error {
+	params.Limit = maxPageSize
+	params.Offset = 0
+
+	for {
+		posts, err := s.db.ListPostsForUser(context.Background(), params)
+
+		if err != nil {
+			return err
+		}
+
+		for _, v := range posts {
+			if postTime(v.PublishedAt, v.CreatedAt).After(before) {
+				// still unread, so it stays in the next page.
+				params.Offset++
+				continue
+			}
+
+			err = setPostRead(s, user, v.ID, true)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(posts) < int(params.Limit) {
+			return nil
+		}
+	}
+}
+
+func setPostRead(s *state, user database.User, postID uuid.UUID, read bool) error {
+	now := time.Now()
+
+	return s.db.SetPostRead(context.Background(),
+		database.SetPostReadParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			UserID:    user.ID,
+			PostID:    postID,
+			Read:      read,
+		})
+}
+
+func setPostStarred(s *state, user database.User, postID uuid.UUID, starred bool) error {
+	now := time.Now()
+
+	return s.db.SetPostStarred(context.Background(),
+		database.SetPostStarredParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			UserID:    user.ID,
+			PostID:    postID,
+			Starred:   starred,
+		})
+}
+
+// postTime is when a post was published, or saved if the feed had no date.
+func postTime(published sql.NullTime, created time.Time) time.Time {
+	if published.Valid {
+		return published.Time
+	}
+	return created
+}
+
+func feverBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func joinSeqs(seqs []int64) string {
+	out := make([]string, 0, len(seqs))
+	for _, v := range seqs {
+		out = append(out, strconv.FormatInt(v, 10))
+	}
+	return strings.Join(out, ",")
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type feverResponse struct {
+	APIVersion    int               `json:"api_version"`
+	Auth          int               `json:"auth"`
+	Groups        []feverGroup      `json:"groups"`
+	FeedsGroups   []feverFeedsGroup `json:"feeds_groups"`
+	Feeds         []feverFeed       `json:"feeds"`
+	Items         []feverItem       `json:"items"`
+	TotalItems    int               `json:"total_items"`
+	UnreadItemIDs string            `json:"unread_item_ids"`
+	SavedItemIDs  string            `json:"saved_item_ids"`
+}
+
+// feverClient makes Fever API calls the way a mobile reader does.
+type feverClient struct {
+	t   *testing.T
+	url string
+	key string
+}
+
+func newFeverClient(t *testing.T, e *testEnv, username, password string) feverClient {
+	srv := httptest.NewServer(newServer(e.s))
+	t.Cleanup(srv.Close)
+
+	return feverClient{t: t, url: srv.URL + "/fever/", key: feverKey(username, password)}
+}
+
+// call posts the api_key and form to the endpoint with query, such as
+// "api&items&since_id=3".
+func (c feverClient) call(query string, form url.Values) feverResponse {
+	c.t.Helper()
+
+	if form == nil {
+		form = url.Values{}
+	}
+	form.Set("api_key", c.key)
+
+	res, err := http.PostForm(c.url+"?"+query, form)
+
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		c.t.Fatalf("%s: status %s", query, res.Status)
+	}
+
+	var body feverResponse
+
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		c.t.Fatal(err)
+	}
+
+	return body
+}
+
+// mark sends mark=what&as=as&id=id.
+func (c feverClient) mark(what, as string, id int64) {
+	c.t.Helper()
+
+	c.call("api", url.Values{"mark": {what}, "as": {as}, "id": {strconv.FormatInt(id, 10)}})
+}
+
+// feverToken creates a Fever password for the logged in user.
+func (e *testEnv) feverToken() string {
+	e.t.Helper()
+
+	out := e.must("", "token", "create", "--fever", "reeder")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+
+	return lines[len(lines)-1]
+}
+
+func itemIDs(items []feverItem) []int64 {
+	ids := []int64{}
+	for _, v := range items {
+		ids = append(ids, v.ID)
+	}
+	return ids
+}
+
+func TestFeverAuth(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		e.register("alice")
+		password := e.feverToken()
+
+		// a plain api token isn't a Fever password
+		out := e.must("", "token", "create", "cli")
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		plain := lines[len(lines)-1]
+
+		e.register("bob")
+
+		for _, tc := range []struct {
+			name     string
+			user     string
+			password string
+			auth     int
+		}{
+			{"fever password", "alice", password, 1},
+			{"upper case key", "alice", password, 1},
+			{"wrong password", "alice", "nope", 0},
+			{"plain api token", "alice", plain, 0},
+			{"someone else's password", "bob", password, 0},
+		} {
+			c := newFeverClient(t, e, tc.user, tc.password)
+			if tc.name == "upper case key" {
+				c.key = strings.ToUpper(c.key)
+			}
+
+			res := c.call("api&items", nil)
+
+			if res.APIVersion != 3 || res.Auth != tc.auth {
+				t.Errorf("%s: api_version %d, auth %d, want 3 and %d", tc.name, res.APIVersion, res.Auth, tc.auth)
+			}
+
+			if tc.auth == 0 && res.Items != nil {
+				t.Errorf("%s: got items without auth", tc.name)
+			}
+		}
+
+		c := newFeverClient(t, e, "alice", password)
+		c.key = ""
+
+		if res := c.call("api", nil); res.Auth != 0 {
+			t.Error("no api_key authenticated")
+		}
+	})
+}
+
+func TestFeverGroupsAndFeeds(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		news := newTestFeed(t, testItem(1))
+		other := newTestFeed(t, testItem(2))
+
+		e.register("alice")
+		e.must("", "addfeed", "News", news.URL)
+		e.must("", "addfeed", "Other", other.URL)
+		e.must("", "group", "add", "tech", news.URL)
+		e.fetchAll()
+
+		c := newFeverClient(t, e, "alice", e.feverToken())
+		res := c.call("api&groups&feeds", nil)
+
+		if len(res.Feeds) != 2 {
+			t.Fatalf("got %d feeds, want 2: %+v", len(res.Feeds), res.Feeds)
+		}
+
+		seqs := map[string]int64{}
+		for _, v := range res.Feeds {
+			seqs[v.Title] = v.ID
+
+			if v.LastUpdatedOnTime == 0 {
+				t.Errorf("%s has no last_updated_on_time", v.Title)
+			}
+		}
+
+		if seqs["News"] == seqs["Other"] || seqs["News"] == 0 {
+			t.Errorf("feeds don't have ids of their own: %v", seqs)
+		}
+
+		want := []feverGroup{{ID: feverGroupID("tech"), Title: "tech"}}
+
+		if !slices.Equal(res.Groups, want) {
+			t.Errorf("groups are %+v, want %+v", res.Groups, want)
+		}
+
+		wantFeeds := []feverFeedsGroup{{GroupID: feverGroupID("tech"), FeedIDs: strconv.FormatInt(seqs["News"], 10)}}
+
+		if !slices.Equal(res.FeedsGroups, wantFeeds) {
+			t.Errorf("feeds_groups are %+v, want %+v", res.FeedsGroups, wantFeeds)
+		}
+
+		// feeds_groups comes with feeds alone as well
+		res = c.call("api&feeds", nil)
+
+		if res.Groups != nil || !slices.Equal(res.FeedsGroups, wantFeeds) {
+			t.Errorf("api&feeds returned groups %+v and feeds_groups %+v", res.Groups, res.FeedsGroups)
+		}
+	})
+}
+
+func TestFeverItems(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		var items []RSSItem
+		for i := range feverPageSize + 5 {
+			items = append(items, testItem(i+1))
+		}
+
+		feed := newTestFeed(t, items...)
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.fetchAll()
+
+		c := newFeverClient(t, e, "alice", e.feverToken())
+
+		// since_id pages forward from the oldest
+		first := c.call("api&items", nil)
+
+		if len(first.Items) != feverPageSize || first.TotalItems != len(items) {
+			t.Fatalf("got %d items of %d, want %d of %d", len(first.Items), first.TotalItems, feverPageSize, len(items))
+		}
+
+		ids := itemIDs(first.Items)
+
+		if !slices.IsSorted(ids) {
+			t.Errorf("since_id page isn't in ascending order: %v", ids)
+		}
+
+		last := ids[len(ids)-1]
+		rest := c.call(fmt.Sprintf("api&items&since_id=%d", last), nil)
+
+		if len(rest.Items) != 5 {
+			t.Fatalf("since_id=%d returned %d items, want 5", last, len(rest.Items))
+		}
+
+		all := append(ids, itemIDs(rest.Items)...)
+
+		if distinct := slices.Compact(slices.Sorted(slices.Values(all))); len(distinct) != len(items) {
+			t.Errorf("paging returned %d distinct items, want %d", len(distinct), len(items))
+		}
+
+		if res := c.call(fmt.Sprintf("api&items&since_id=%d", all[len(all)-1]), nil); len(res.Items) != 0 {
+			t.Errorf("since_id past the newest returned %d items", len(res.Items))
+		}
+
+		// max_id pages backward from the newest
+		res := c.call(fmt.Sprintf("api&items&max_id=%d", all[2]), nil)
+
+		if got := itemIDs(res.Items); !slices.Equal(got, []int64{all[1], all[0]}) {
+			t.Errorf("max_id=%d returned %v, want %v", all[2], got, []int64{all[1], all[0]})
+		}
+
+		res = c.call(fmt.Sprintf("api&items&with_ids=%d,%d", all[3], all[7]), nil)
+
+		if got := itemIDs(res.Items); !slices.Equal(got, []int64{all[3], all[7]}) {
+			t.Errorf("with_ids returned %v", got)
+		}
+
+		item := first.Items[0]
+
+		if item.Title != "Post 1" || item.URL != "https://example.com/posts/1" || item.HTML != "Body of post 1" {
+			t.Errorf("first item is %+v", item)
+		}
+
+		if want := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).Unix(); item.CreatedOnTime != want {
+			t.Errorf("created_on_time is %d, want %d", item.CreatedOnTime, want)
+		}
+
+		feeds := c.call("api&feeds", nil).Feeds
+
+		if len(feeds) != 1 || item.FeedID != feeds[0].ID {
+			t.Errorf("item's feed_id %d isn't the feed's id in %+v", item.FeedID, feeds)
+		}
+
+		for _, tc := range []string{"since_id=x", "max_id=x", "with_ids=1,x"} {
+			t.Run(tc, func(t *testing.T) {
+				res, err := http.PostForm(c.url+"?api&items&"+tc, url.Values{"api_key": {c.key}})
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				res.Body.Close()
+
+				if res.StatusCode != http.StatusInternalServerError {
+					t.Errorf("got %s", res.Status)
+				}
+			})
+		}
+	})
+}
+
+func TestFeverMark(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		news := newTestFeed(t, testItem(1), testItem(2))
+		other := newTestFeed(t, testItem(3))
+
+		e.register("alice")
+		e.must("", "addfeed", "News", news.URL)
+		e.must("", "addfeed", "Other", other.URL)
+		e.must("", "group", "add", "tech", news.URL)
+		e.fetchAll()
+
+		c := newFeverClient(t, e, "alice", e.feverToken())
+
+		items := c.call("api&items", nil).Items
+		ids := itemIDs(items)
+
+		unread := func() string {
+			t.Helper()
+			return c.call("api&unread_item_ids", nil).UnreadItemIDs
+		}
+
+		join := func(ids ...int64) string {
+			slices.Sort(ids)
+			return joinSeqs(ids)
+		}
+
+		if got := unread(); got != join(ids...) {
+			t.Fatalf("unread_item_ids is %q, want %q", got, join(ids...))
+		}
+
+		c.mark("item", "read", ids[0])
+
+		if got := unread(); got != join(ids[1:]...) {
+			t.Errorf("after marking %d read, unread_item_ids is %q", ids[0], got)
+		}
+
+		if res := c.call("api&items&with_ids="+strconv.FormatInt(ids[0], 10), nil); len(res.Items) != 1 || res.Items[0].IsRead != 1 {
+			t.Errorf("item marked read is %+v", res.Items)
+		}
+
+		c.mark("item", "unread", ids[0])
+
+		if got := unread(); got != join(ids...) {
+			t.Errorf("after marking %d unread, unread_item_ids is %q", ids[0], got)
+		}
+
+		c.mark("item", "saved", ids[1])
+
+		if got := c.call("api&saved_item_ids", nil).SavedItemIDs; got != join(ids[1]) {
+			t.Errorf("saved_item_ids is %q, want %q", got, join(ids[1]))
+		}
+
+		c.mark("item", "unsaved", ids[1])
+
+		if got := c.call("api&saved_item_ids", nil).SavedItemIDs; got != "" {
+			t.Errorf("saved_item_ids after unsaving is %q", got)
+		}
+
+		// marking a group read leaves the feeds outside it alone
+		c.mark("group", "read", feverGroupID("tech"))
+
+		var otherIDs []int64
+		for _, v := range items {
+			if v.Title == "Post 3" {
+				otherIDs = append(otherIDs, v.ID)
+			}
+		}
+
+		if got := unread(); got != join(otherIDs...) {
+			t.Errorf("after marking the group read, unread_item_ids is %q, want %q", got, join(otherIDs...))
+		}
+
+		// only what was published before "before" is marked
+		feeds := c.call("api&feeds", nil).Feeds
+		var otherSeq int64
+		for _, v := range feeds {
+			if v.Title == "Other" {
+				otherSeq = v.ID
+			}
+		}
+
+		c.call("api", url.Values{"mark": {"feed"}, "as": {"read"}, "id": {strconv.FormatInt(otherSeq, 10)},
+			"before": {strconv.FormatInt(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC).Unix(), 10)}})
+
+		if got := unread(); got != join(otherIDs...) {
+			t.Errorf("marking the feed read before its post marked it: %q", got)
+		}
+
+		c.mark("feed", "read", otherSeq)
+
+		if got := unread(); got != "" {
+			t.Errorf("after marking every feed read, unread_item_ids is %q", got)
+		}
+	})
+}
+
+// Posts hidden by a filter are left out of items and of total_items.
+func TestFeverHiddenItems(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed := newTestFeed(t, testItem(1), testItem(2), testItem(3))
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.must("", "filter", "add", "title", "post 2", "hide")
+		e.fetchAll()
+
+		c := newFeverClient(t, e, "alice", e.feverToken())
+		res := c.call("api&items&unread_item_ids", nil)
+
+		var titles []string
+		for _, v := range res.Items {
+			titles = append(titles, v.Title)
+		}
+
+		if !slices.Equal(titles, []string{"Post 1", "Post 3"}) || res.TotalItems != 2 {
+			t.Errorf("items are %v of %d, want posts 1 and 3 of 2", titles, res.TotalItems)
+		}
+
+		if n := len(strings.Split(res.UnreadItemIDs, ",")); n != 2 {
+			t.Errorf("unread_item_ids is %q, want 2 ids", res.UnreadItemIDs)
+		}
+	})
+}
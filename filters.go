@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+const filterUsage = `usage: aggregator filter add [--regex] <feed|title|description|author|category> <pattern> <hide|read|star|tag> [tag]
+       aggregator filter list
+       aggregator filter rm <id>`
+
+var (
+	filterFields  = []string{"feed", "title", "description", "author", "category"}
+	filterActions = []string{"hide", "read", "star", "tag"}
+)
+
+// postFields holds the parts of a post a filter can match against.
+type postFields struct {
+	feed        []string
+	title       string
+	description string
+	author      string
+	categories  []string
+}
+
+func handlerFilter(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf(filterUsage)
+	}
+
+	switch cmd.arguments[0] {
+	case "add":
+		fs := flag.NewFlagSet("filter add", flag.ContinueOnError)
+		regex := fs.Bool("regex", false, "treat pattern as a regular expression")
+
+		if err := fs.Parse(cmd.arguments[1:]); err != nil || fs.NArg() < 3 {
+			return fmt.Errorf(filterUsage)
+		}
+
+		field, pattern, action := fs.Arg(0), fs.Arg(1), fs.Arg(2)
+
+		if !slices.Contains(filterFields, field) {
+			return fmt.Errorf("unknown filter field %s, expected one of %s", field, strings.Join(filterFields, ", "))
+		}
+
+		if !slices.Contains(filterActions, action) {
+			return fmt.Errorf("unknown filter action %s, expected one of %s", action, strings.Join(filterActions, ", "))
+		}
+
+		matchType := "substring"
+		if *regex {
+			matchType = "regex"
+
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid pattern: %w", err)
+			}
+		}
+
+		tag := sql.NullString{}
+		if action == "tag" {
+			if fs.NArg() < 4 {
+				return fmt.Errorf("filter action tag requires a tag name")
+			}
+			tag.String = fs.Arg(3)
+			tag.Valid = true
+		}
+
+		now := time.Now()
+
+		filter, err := s.db.CreateFilter(context.Background(),
+			database.CreateFilterParams{
+				ID:        uuid.New(),
+				CreatedAt: now,
+				UpdatedAt: now,
+				UserID:    user.ID,
+				Field:     field,
+				MatchType: matchType,
+				Pattern:   pattern,
+				Action:    action,
+				Tag:       tag,
+			})
+
+		if err != nil {
+			return fmt.Errorf("failed to add filter: %w", err)
+		}
+
+		fmt.Printf("Added filter %s\n", formatFilter(filter))
+	case "list":
+		filters, err := s.db.GetFiltersForUser(context.Background(), user.ID)
+
+		if err != nil {
+			return fmt.Errorf("failed to list filters: %w", err)
+		}
+
+		fmt.Printf("%s's filters:\n", user.Name)
+		for _, v := range filters {
+			fmt.Printf("\t * %s\n", formatFilter(v))
+		}
+	case "rm":
+		if len(cmd.arguments) < 2 {
+			return fmt.Errorf(filterUsage)
+		}
+
+		id, err := uuid.Parse(cmd.arguments[1])
+
+		if err != nil {
+			return fmt.Errorf("invalid filter id: %w", err)
+		}
+
+		n, err := s.db.DeleteFilter(context.Background(),
+			database.DeleteFilterParams{
+				ID:     id,
+				UserID: user.ID,
+			})
+
+		if err != nil {
+			return fmt.Errorf("failed to remove filter: %w", err)
+		}
+
+		if n == 0 {
+			return fmt.Errorf("filter %s not found", id)
+		}
+
+		fmt.Printf("Removed filter %s\n", id)
+	default:
+		return fmt.Errorf(filterUsage)
+	}
+
+	return nil
+}
+
+func formatFilter(f database.Filter) string {
+	out := fmt.Sprintf("%s: %s %s %q -> %s", f.ID, f.Field, f.MatchType, f.Pattern, f.Action)
+	if f.Tag.Valid {
+		out = fmt.Sprintf("%s %s", out, f.Tag.String)
+	}
+	return out
+}
+
+// filterMatches reports whether any value of the filter's field matches its
+// pattern. Substring matches ignore case.
+func filterMatches(f database.Filter, p postFields) bool {
+	var values []string
+
+	switch f.Field {
+	case "feed":
+		values = p.feed
+	case "title":
+		values = []string{p.title}
+	case "description":
+		values = []string{p.description}
+	case "author":
+		values = []string{p.author}
+	case "category":
+		values = p.categories
+	}
+
+	var re *regexp.Regexp
+	if f.MatchType == "regex" {
+		var err error
+		re, err = regexp.Compile(f.Pattern)
+		if err != nil {
+			return false
+		}
+	}
+
+	for _, v := range values {
+		if re != nil {
+			if re.MatchString(v) {
+				return true
+			}
+			continue
+		}
+
+		if strings.Contains(strings.ToLower(v), strings.ToLower(f.Pattern)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filterOutcome reports whether the filters owned by userID hide p, and the
+// tags they add to it.
+func filterOutcome(filters []database.Filter, userID uuid.UUID, p postFields) (bool, []string) {
+	hidden := false
+	var tags []string
+
+	for _, f := range filters {
+		if f.UserID != userID || !filterMatches(f, p) {
+			continue
+		}
+
+		switch f.Action {
+		case "hide":
+			hidden = true
+		case "tag":
+			tags = append(tags, f.Tag.String)
+		}
+	}
+
+	return hidden, tags
+}
+
+// applyFilters runs the action of every matching filter against the post for
+// the user owning the filter. It reports whether a matching filter hid the post.
+func applyFilters(db database.Store, filters []database.Filter, postID uuid.UUID, p postFields) (bool, error) {
+	hidden := false
+
+	for _, f := range filters {
+		if !filterMatches(f, p) {
+			continue
+		}
+
+		now := time.Now()
+		var err error
+
+		switch f.Action {
+		case "hide":
+			hidden = true
+			err = db.SetPostHidden(context.Background(),
+				database.SetPostHiddenParams{
+					ID:        uuid.New(),
+					CreatedAt: now,
+					UpdatedAt: now,
+					UserID:    f.UserID,
+					PostID:    postID,
+					Hidden:    true,
+				})
+		case "read":
+			err = db.SetPostRead(context.Background(),
+				database.SetPostReadParams{
+					ID:        uuid.New(),
+					CreatedAt: now,
+					UpdatedAt: now,
+					UserID:    f.UserID,
+					PostID:    postID,
+					Read:      true,
+				})
+		case "star":
+			err = db.SetPostStarred(context.Background(),
+				database.SetPostStarredParams{
+					ID:        uuid.New(),
+					CreatedAt: now,
+					UpdatedAt: now,
+					UserID:    f.UserID,
+					PostID:    postID,
+					Starred:   true,
+				})
+		case "tag":
+			err = db.AddPostTag(context.Background(),
+				database.AddPostTagParams{
+					ID:        uuid.New(),
+					CreatedAt: now,
+					UserID:    f.UserID,
+					PostID:    postID,
+					Tag:       f.Tag.String,
+				})
+		}
+
+		if err != nil {
+			return hidden, fmt.Errorf("failed applying filter %s: %w", f.ID, err)
+		}
+	}
+
+	return hidden, nil
+}
+
+// splitCategories reverses the newline joined form categories are stored in.
+func splitCategories(categories sql.NullString) []string {
+	if !categories.Valid || categories.String == "" {
+		return nil
+	}
+	return strings.Split(categories.String, "\n")
+}
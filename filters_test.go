@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func TestFilterMatches(t *testing.T) {
+	p := postFields{
+		feed:        []string{"Go Blog", "https://go.dev/blog/feed.atom"},
+		title:       "Go 1.22 is released",
+		description: "Range over integers",
+		author:      "Russ Cox",
+		categories:  []string{"release", "Generics"},
+	}
+
+	for _, tc := range []struct {
+		field, matchType, pattern string
+		want                      bool
+	}{
+		{"feed", "substring", "go blog", true},
+		{"feed", "substring", "go.dev/blog", true},
+		{"feed", "substring", "rust", false},
+		{"title", "substring", "RELEASED", true},
+		{"title", "substring", "integers", false},
+		// substrings are taken literally
+		{"title", "substring", "1.2.", false},
+		{"title", "regex", "1.2.", true},
+		{"title", "regex", `^Go 1\.\d+ `, true},
+		// regexes don't ignore case unless they say so
+		{"title", "regex", "^go", false},
+		{"title", "regex", "(?i)^go", true},
+		{"title", "regex", "(", false},
+		{"description", "substring", "range over", true},
+		{"description", "substring", "released", false},
+		{"author", "substring", "cox", true},
+		{"author", "regex", "^Russ", true},
+		{"author", "regex", "^Cox", false},
+		{"category", "substring", "generics", true},
+		{"category", "regex", "^release$", true},
+		{"category", "regex", "^rel$", false},
+	} {
+		f := database.Filter{Field: tc.field, MatchType: tc.matchType, Pattern: tc.pattern}
+
+		if got := filterMatches(f, p); got != tc.want {
+			t.Errorf("%s %s %q matches: %v, want %v", tc.field, tc.matchType, tc.pattern, got, tc.want)
+		}
+	}
+}
+
+// browsed splits browse output into the posts it shows, by title.
+func browsed(out string) map[string]string {
+	posts := map[string]string{}
+
+	for _, block := range strings.Split(out, "=====================================") {
+		_, rest, ok := strings.Cut(block, "--- ")
+		if !ok {
+			continue
+		}
+
+		title, _, _ := strings.Cut(rest, " ---")
+		posts[title] = block
+	}
+
+	return posts
+}
+
+func TestFilterActions(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		hidden := testItem(5)
+		hidden.Title = "Sponsored: buy now"
+
+		read := testItem(4)
+		read.Author = "Spammer"
+
+		starred := testItem(3)
+		starred.Categories = []string{"golang"}
+
+		tagged := testItem(2)
+		tagged.Description = "Running it on Kubernetes"
+
+		feed := newTestFeed(t, hidden, read, starred, tagged, testItem(1))
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.must("", "filter", "add", "title", "sponsored", "hide")
+		e.must("", "filter", "add", "author", "spammer", "read")
+		e.must("", "filter", "add", "--regex", "category", "^go", "star")
+		e.must("", "filter", "add", "description", "kubernetes", "tag", "k8s")
+
+		// bob follows the feed too, alice's filters leave his posts alone
+		e.register("bob")
+		e.must("", "follow", feed.URL)
+		e.fetchAll()
+
+		if got := browsed(e.must("", "browse", "10")); len(got) != 5 || strings.Contains(got["Post 4"], "(read)") {
+			t.Errorf("bob's posts are changed by alice's filters:\n%v", got)
+		}
+
+		e.login("alice")
+		got := browsed(e.must("", "browse", "10"))
+
+		if len(got) != 4 || got["Sponsored: buy now"] != "" {
+			t.Fatalf("browse shows %d posts, want all but the hidden one:\n%v", len(got), got)
+		}
+
+		if !strings.Contains(got["Post 4"], "(read)") {
+			t.Errorf("read filter didn't mark the post read:\n%s", got["Post 4"])
+		}
+
+		if !strings.Contains(got["Post 3"], "(starred)") {
+			t.Errorf("star filter didn't star the post:\n%s", got["Post 3"])
+		}
+
+		if !strings.Contains(got["Post 2"], "Tags: k8s") {
+			t.Errorf("tag filter didn't tag the post:\n%s", got["Post 2"])
+		}
+
+		if p := got["Post 1"]; strings.Contains(p, "(read)") || strings.Contains(p, "(starred)") || strings.Contains(p, "Tags:") {
+			t.Errorf("no filter matches, but the post shows\n%s", p)
+		}
+
+		// what the user changes afterwards sticks: browsing doesn't run
+		// the filters again
+		ctx := context.Background()
+		alice := e.user("alice").ID
+		now := time.Now()
+
+		err := e.s.db.SetPostRead(ctx,
+			database.SetPostReadParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, UserID: alice, PostID: e.postID(feed.URL, read.Link), Read: false})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = e.s.db.SetPostStarred(ctx,
+			database.SetPostStarredParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, UserID: alice, PostID: e.postID(feed.URL, starred.Link), Starred: false})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got = browsed(e.must("", "browse", "10"))
+
+		if strings.Contains(got["Post 4"], "(read)") || strings.Contains(got["Post 3"], "(starred)") {
+			t.Errorf("browsing undid marking a post unread and unstarring one:\n%s\n%s", got["Post 4"], got["Post 3"])
+		}
+	})
+}
+
+// A hide filter added after the posts were saved hides them while browsing,
+// still shows as many posts as asked for, and writes nothing.
+func TestFilterHideWhileBrowsing(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed := newTestFeed(t, testItem(1), testItem(2), testItem(3), testItem(4))
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.fetchAll()
+
+		out := e.must("", "filter", "add", "--regex", "title", "^Post [34]$", "hide")
+		id := strings.Fields(strings.TrimPrefix(out, "Added filter "))[0]
+		id = strings.TrimSuffix(id, ":")
+
+		got := browsed(e.must("", "browse", "2"))
+
+		if len(got) != 2 || got["Post 2"] == "" || got["Post 1"] == "" {
+			t.Errorf("browse 2 shows %v, want posts 2 and 1", got)
+		}
+
+		// removing the filter brings the posts back
+		e.must("", "filter", "rm", id)
+
+		if got := browsed(e.must("", "browse", "10")); len(got) != 4 {
+			t.Errorf("after removing the filter browse shows %d posts, want 4", len(got))
+		}
+	})
+}
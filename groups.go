@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+const groupUsage = `usage: aggregator group add <group> <url>
+       aggregator group rm <group> [url]
+       aggregator group list [group]`
+
+func handlerGroup(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf(groupUsage)
+	}
+
+	args := cmd.arguments[1:]
+
+	switch cmd.arguments[0] {
+	case "add":
+		if len(args) < 2 {
+			return fmt.Errorf(groupUsage)
+		}
+
+		feed, err := s.db.GetFeedByUrl(context.Background(), args[1])
+
+		if err != nil {
+			return fmt.Errorf("feed %s not found: %w", args[1], err)
+		}
+
+		err = addFeedToGroup(s, user, feed, args[0])
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Added %s to group %s\n", feed.Name, args[0])
+	case "rm":
+		if len(args) == 0 {
+			return fmt.Errorf(groupUsage)
+		}
+
+		if len(args) == 1 {
+			n, err := s.db.DeleteGroupForUser(context.Background(),
+				database.DeleteGroupForUserParams{
+					Name:   args[0],
+					UserID: user.ID,
+				})
+
+			if err != nil {
+				return fmt.Errorf("failed to remove group: %w", err)
+			}
+
+			if n == 0 {
+				return fmt.Errorf("group %s not found", args[0])
+			}
+
+			fmt.Printf("Removed group %s from %d feeds\n", args[0], n)
+			return nil
+		}
+
+		follow, err := getFollowByUrl(s, user, args[1])
+
+		if err != nil {
+			return err
+		}
+
+		n, err := s.db.RemoveFeedFollowGroup(context.Background(),
+			database.RemoveFeedFollowGroupParams{
+				FeedFollowID: follow.ID,
+				Name:         args[0],
+			})
+
+		if err != nil {
+			return fmt.Errorf("failed to remove feed from group: %w", err)
+		}
+
+		if n == 0 {
+			return fmt.Errorf("%s is not in group %s", args[1], args[0])
+		}
+
+		fmt.Printf("Removed %s from group %s\n", args[1], args[0])
+	case "list":
+		groups, err := s.db.GetGroupsForUser(context.Background(), user.ID)
+
+		if err != nil {
+			return fmt.Errorf("failed to list groups: %w", err)
+		}
+
+		current := ""
+		for _, v := range groups {
+			if len(args) > 0 && v.Name != args[0] {
+				continue
+			}
+
+			if v.Name != current {
+				fmt.Printf("%s:\n", v.Name)
+				current = v.Name
+			}
+			fmt.Printf("\t * %s (%s)\n", v.FeedName, v.FeedUrl)
+		}
+	default:
+		return fmt.Errorf(groupUsage)
+	}
+
+	return nil
+}
+
+// addFeedToGroup places the user's follow of feed into group. The user must
+// already follow the feed.
+func addFeedToGroup(s *state, user database.User, feed database.Feed, group string) error {
+	follow, err := s.db.GetFeedFollow(context.Background(),
+		database.GetFeedFollowParams{
+			UserID: user.ID,
+			FeedID: feed.ID,
+		})
+
+	if err != nil {
+		return fmt.Errorf("%s is not following %s: %w", user.Name, feed.Url, err)
+	}
+
+	err = s.db.AddFeedFollowGroup(context.Background(),
+		database.AddFeedFollowGroupParams{
+			ID:           uuid.New(),
+			CreatedAt:    time.Now(),
+			FeedFollowID: follow.ID,
+			Name:         group,
+		})
+
+	if err != nil {
+		return fmt.Errorf("failed adding %s to group %s: %w", feed.Url, group, err)
+	}
+
+	return nil
+}
+
+func getFollowByUrl(s *state, user database.User, url string) (database.FeedFollow, error) {
+	feed, err := s.db.GetFeedByUrl(context.Background(), url)
+
+	if err != nil {
+		return database.FeedFollow{}, fmt.Errorf("feed %s not found: %w", url, err)
+	}
+
+	follow, err := s.db.GetFeedFollow(context.Background(),
+		database.GetFeedFollowParams{
+			UserID: user.ID,
+			FeedID: feed.ID,
+		})
+
+	if err != nil {
+		return database.FeedFollow{}, fmt.Errorf("%s is not following %s: %w", user.Name, url, err)
+	}
+
+	return follow, nil
+}
+
+// groupsByFeedUrl maps each followed feed url to the names of the groups it
+// belongs to.
+func groupsByFeedUrl(s *state, user database.User) (map[string][]string, error) {
+	groups, err := s.db.GetGroupsForUser(context.Background(), user.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string][]string)
+	for _, v := range groups {
+		res[v.FeedUrl] = append(res[v.FeedUrl], v.Name)
+	}
+
+	return res, nil
+}
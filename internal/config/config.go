@@ -10,8 +10,61 @@ import (
 const configFile = ".gatorconfig.json"
 
 type Config struct {
-	DbURL           string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name"`
+	DbURL           string     `json:"db_url"`
+	CurrentUserName string     `json:"current_user_name"`
+	SessionToken    string     `json:"session_token,omitempty"`
+	SMTP            *SMTP      `json:"smtp,omitempty"`
+	WebSubCallback  string     `json:"websub_callback,omitempty"`
+	Retention       *Retention `json:"retention,omitempty"`
+	Log             *Log       `json:"log,omitempty"`
+	Fetch           *Fetch     `json:"fetch,omitempty"`
+}
+
+// Fetch sets how agg treats the hosts it fetches feeds from. Feeds are
+// fetched as "UserAgent (+Contact)". HostConcurrency caps the requests to
+// one host at a time and HostDelay, a duration such as "2s", is the least
+// time between the start of two of them. Zero values use the defaults.
+//
+// Proxy and NoProxy take the place of the HTTPS_PROXY, HTTP_PROXY and
+// NO_PROXY environment variables. CAFile names a PEM bundle trusted on top
+// of the system's roots, and ClientCert and ClientKey the PEM files of the
+// certificate agg presents to hosts that ask for one. CredentialsKey is the
+// base64 encoded 32 byte key feed credentials are encrypted with.
+type Fetch struct {
+	UserAgent       string `json:"user_agent"`
+	Contact         string `json:"contact"`
+	HostConcurrency int    `json:"host_concurrency"`
+	HostDelay       string `json:"host_delay"`
+	Proxy           string `json:"proxy,omitempty"`
+	NoProxy         string `json:"no_proxy,omitempty"`
+	CAFile          string `json:"ca_file,omitempty"`
+	ClientCert      string `json:"client_cert,omitempty"`
+	ClientKey       string `json:"client_key,omitempty"`
+	CredentialsKey  string `json:"credentials_key,omitempty"`
+}
+
+// Log sets how much is logged and how. Level is one of debug, info, warn or
+// error, and Format is text or json.
+type Log struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+}
+
+// Retention is the default policy for how long posts are kept. Zero values
+// mean no limit; feeds can override each field.
+type Retention struct {
+	MaxAgeDays          int  `json:"max_age_days"`
+	MaxPosts            int  `json:"max_posts"`
+	KeepUnreadOrStarred bool `json:"keep_unread_or_starred"`
+}
+
+// SMTP is the mail server digests are sent through.
+type SMTP struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	From     string `json:"from"`
 }
 
 func Read() (Config, error) {
@@ -47,8 +100,9 @@ func Read() (Config, error) {
 
 }
 
-func (cfg *Config) SetUser(username string) error {
+func (cfg *Config) SetSession(username, token string) error {
 	cfg.CurrentUserName = username
+	cfg.SessionToken = token
 	return setContent(*cfg)
 }
 
@@ -82,7 +136,15 @@ func setContent(cfg Config) error {
 		return err
 	}
 
-	err = os.WriteFile(configPath, b, 0644)
+	err = os.WriteFile(configPath, b, 0600)
+
+	if err != nil {
+		return err
+	}
+
+	// the file holds a session token, keep it private even if it was
+	// created with looser permissions
+	err = os.Chmod(configPath, 0600)
 
 	if err != nil {
 		return err
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// createPostsBatch is how many posts CreatePosts inserts per statement. At
+// ten arguments a post it stays well under the argument limits of both
+// PostgreSQL (65535) and SQLite (32766).
+const createPostsBatch = 500
+
+// CreatePosts inserts posts with multi-row INSERT statements, skipping the
+// ones whose url is already saved, and returns the posts it inserted. sqlc
+// can't generate a VALUES list of variable length, so it is written by hand
+// in the same portable SQL as CreatePost.
+func (q *Queries) CreatePosts(ctx context.Context, args []CreatePostParams) ([]Post, error) {
+	var items []Post
+
+	for len(args) > 0 {
+		batch := args[:min(len(args), createPostsBatch)]
+		args = args[len(batch):]
+
+		var query strings.Builder
+		query.WriteString("INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories)\nVALUES ")
+
+		values := make([]any, 0, len(batch)*10)
+
+		for i, arg := range batch {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+
+			n := len(values)
+			fmt.Fprintf(&query, "($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+				n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10)
+
+			values = append(values,
+				arg.ID,
+				arg.CreatedAt,
+				arg.UpdatedAt,
+				arg.Title,
+				arg.Url,
+				arg.Description,
+				arg.PublishedAt,
+				arg.FeedID,
+				arg.Author,
+				arg.Categories,
+			)
+		}
+
+		query.WriteString("\nON CONFLICT (url) DO NOTHING\nRETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, seq")
+
+		rows, err := q.db.QueryContext(ctx, query.String(), values...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var i Post
+			if err := rows.Scan(
+				&i.ID,
+				&i.CreatedAt,
+				&i.UpdatedAt,
+				&i.Title,
+				&i.Url,
+				&i.Description,
+				&i.PublishedAt,
+				&i.FeedID,
+				&i.Author,
+				&i.Categories,
+				&i.Seq,
+			); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			items = append(items, i)
+		}
+		if err := rows.Close(); err != nil {
+			return nil, err
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
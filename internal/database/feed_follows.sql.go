@@ -13,25 +13,30 @@ import (
 	"github.com/google/uuid"
 )
 
+const countFeedFollowsForUser = `-- name: CountFeedFollowsForUser :one
+SELECT COUNT(*) FROM feed_follows
+WHERE user_id = $1
+`
+
+func (q *Queries) CountFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFeedFollowsForUser, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFeedFollow = `-- name: CreateFeedFollow :one
-WITH inserted_feed_follow as (
-    INSERT INTO feed_follows(id, created_at, updated_at, user_id, feed_id)
-    VALUES (
-        $1,
-        $2,
-        $3,
-        $4,
-        $5
-    )
-    RETURNING id, created_at, updated_at, user_id, feed_id
+INSERT INTO feed_follows(id, created_at, updated_at, user_id, feed_id)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
 )
-SELECT
-    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id,
-    feeds.name AS feed_name,
-    users.name AS user_name
-FROM inserted_feed_follow
-INNER JOIN users ON users.id = inserted_feed_follow.user_id
-INNER JOIN feeds ON feeds.id = inserted_feed_follow.feed_id
+RETURNING id, created_at, updated_at, user_id, feed_id,
+    (SELECT feeds.name FROM feeds WHERE feeds.id = feed_follows.feed_id) AS feed_name,
+    (SELECT users.name FROM users WHERE users.id = feed_follows.user_id) AS user_name
 `
 
 type CreateFeedFollowParams struct {
@@ -73,24 +78,82 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 	return i, err
 }
 
-const deleteFeedFollow = `-- name: DeleteFeedFollow :exec
+const deleteFeedFollow = `-- name: DeleteFeedFollow :execrows
 DELETE FROM feed_follows
-USING feeds, users
-WHERE users.id = $1 and feeds.url = $2
+WHERE user_id = $1 AND feed_id = $2
 `
 
 type DeleteFeedFollowParams struct {
-	ID  uuid.UUID
-	Url string
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedFollow, arg.UserID, arg.FeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAllFeedFollows = `-- name: GetAllFeedFollows :many
+SELECT id, created_at, updated_at, user_id, feed_id FROM feed_follows
+`
+
+func (q *Queries) GetAllFeedFollows(ctx context.Context) ([]FeedFollow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllFeedFollows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeedFollow
+	for rows.Next() {
+		var i FeedFollow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowParams) error {
-	_, err := q.db.ExecContext(ctx, deleteFeedFollow, arg.ID, arg.Url)
-	return err
+const getFeedFollow = `-- name: GetFeedFollow :one
+SELECT id, created_at, updated_at, user_id, feed_id FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type GetFeedFollowParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) GetFeedFollow(ctx context.Context, arg GetFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollow, arg.UserID, arg.FeedID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+	)
+	return i, err
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_id, users.id, users.created_at, users.updated_at, users.name, feeds.id, feeds.created_at, feeds.updated_at, feeds.name, url, feeds.user_id, last_fetched_at,
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_id, users.id, users.created_at, users.updated_at, users.name, password_hash, role, feeds.id, feeds.created_at, feeds.updated_at, feeds.name, url, feeds.user_id, last_fetched_at, seq,
     feeds.name AS feed_name,
     users.name AS user_name
 FROM feed_follows
@@ -109,6 +172,8 @@ type GetFeedFollowsForUserRow struct {
 	CreatedAt_2   time.Time
 	UpdatedAt_2   time.Time
 	Name          string
+	PasswordHash  sql.NullString
+	Role          string
 	ID_3          uuid.UUID
 	CreatedAt_3   time.Time
 	UpdatedAt_3   time.Time
@@ -116,6 +181,7 @@ type GetFeedFollowsForUserRow struct {
 	Url           string
 	UserID_2      uuid.UUID
 	LastFetchedAt sql.NullTime
+	Seq           int64
 	FeedName      string
 	UserName      string
 }
@@ -139,6 +205,8 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.CreatedAt_2,
 			&i.UpdatedAt_2,
 			&i.Name,
+			&i.PasswordHash,
+			&i.Role,
 			&i.ID_3,
 			&i.CreatedAt_3,
 			&i.UpdatedAt_3,
@@ -146,6 +214,7 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.Url,
 			&i.UserID_2,
 			&i.LastFetchedAt,
+			&i.Seq,
 			&i.FeedName,
 			&i.UserName,
 		); err != nil {
@@ -161,3 +230,91 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const getFollowersOfFeed = `-- name: GetFollowersOfFeed :many
+SELECT users.id, users.created_at, users.updated_at, users.name, users.password_hash, users.role FROM users
+INNER JOIN feed_follows ON feed_follows.user_id = users.id
+WHERE feed_follows.feed_id = $1
+ORDER BY users.name
+`
+
+func (q *Queries) GetFollowersOfFeed(ctx context.Context, feedID uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowersOfFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.PasswordHash,
+			&i.Role,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnreadCountsForUser = `-- name: GetUnreadCountsForUser :many
+SELECT feeds.id, feeds.name, feeds.url, COUNT(posts.id) AS unread
+FROM feed_follows
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+LEFT JOIN posts ON posts.feed_id = feeds.id
+    AND NOT EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.post_id = posts.id
+            AND post_states.user_id = feed_follows.user_id
+            AND (post_states.read OR post_states.hidden)
+    )
+WHERE feed_follows.user_id = $1
+GROUP BY feeds.id, feeds.name, feeds.url
+ORDER BY feeds.name
+`
+
+type GetUnreadCountsForUserRow struct {
+	ID     uuid.UUID
+	Name   string
+	Url    string
+	Unread int64
+}
+
+func (q *Queries) GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]GetUnreadCountsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUnreadCountsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnreadCountsForUserRow
+	for rows.Next() {
+		var i GetUnreadCountsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Url,
+			&i.Unread,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
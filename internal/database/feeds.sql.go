@@ -13,6 +13,18 @@ import (
 	"github.com/google/uuid"
 )
 
+const countFeedsForUser = `-- name: CountFeedsForUser :one
+SELECT COUNT(*) FROM feeds
+WHERE user_id = $1
+`
+
+func (q *Queries) CountFeedsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFeedsForUser, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds(id, created_at, updated_at, name, url, user_id)
 VALUES (
@@ -23,7 +35,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, seq
 `
 
 type CreateFeedParams struct {
@@ -53,12 +65,78 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Seq,
+	)
+	return i, err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const getAllFeeds = `-- name: GetAllFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, seq FROM feeds
+`
+
+func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getAllFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Seq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedById = `-- name: GetFeedById :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, seq FROM feeds WHERE id = $1
+`
+
+func (q *Queries) GetFeedById(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedById, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Seq,
 	)
 	return i, err
 }
 
 const getFeedByUrl = `-- name: GetFeedByUrl :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, seq FROM feeds WHERE url = $1
 `
 
 func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
@@ -72,12 +150,15 @@ func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Seq,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT feeds.name,
+SELECT feeds.id,
+    feeds.user_id,
+    feeds.name,
     feeds.url,
     users.name AS created_by
 FROM feeds
@@ -85,6 +166,8 @@ INNER JOIN users ON feeds.user_id = users.id
 `
 
 type GetFeedsRow struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
 	Name      string
 	Url       string
 	CreatedBy string
@@ -99,7 +182,65 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	var items []GetFeedsRow
 	for rows.Next() {
 		var i GetFeedsRow
-		if err := rows.Scan(&i.Name, &i.Url, &i.CreatedBy); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Url,
+			&i.CreatedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNextFeedsToFetch = `-- name: GetNextFeedsToFetch :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, seq FROM feeds
+WHERE last_fetched_at IS NULL
+OR last_fetched_at < $1
+OR NOT EXISTS (
+    SELECT 1 FROM websub_subscriptions
+    WHERE websub_subscriptions.feed_id = feeds.id
+    AND websub_subscriptions.state = 'active'
+    AND websub_subscriptions.lease_expires_at > $2
+)
+ORDER BY last_fetched_at NULLS FIRST
+LIMIT $3
+`
+
+type GetNextFeedsToFetchParams struct {
+	LastFetchedAt  sql.NullTime
+	LeaseExpiresAt sql.NullTime
+	Limit          int32
+}
+
+func (q *Queries) GetNextFeedsToFetch(ctx context.Context, arg GetNextFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getNextFeedsToFetch, arg.LastFetchedAt, arg.LeaseExpiresAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Seq,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -114,13 +255,26 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, seq FROM feeds
+WHERE last_fetched_at IS NULL
+OR last_fetched_at < $1
+OR NOT EXISTS (
+    SELECT 1 FROM websub_subscriptions
+    WHERE websub_subscriptions.feed_id = feeds.id
+    AND websub_subscriptions.state = 'active'
+    AND websub_subscriptions.lease_expires_at > $2
+)
 ORDER BY last_fetched_at NULLS FIRST
 LIMIT 1
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
+type GetNextFeedToFetchParams struct {
+	LastFetchedAt  sql.NullTime
+	LeaseExpiresAt sql.NullTime
+}
+
+func (q *Queries) GetNextFeedToFetch(ctx context.Context, arg GetNextFeedToFetchParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getNextFeedToFetch, arg.LastFetchedAt, arg.LeaseExpiresAt)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -130,6 +284,7 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Seq,
 	)
 	return i, err
 }
@@ -150,3 +305,91 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams
 	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.UpdatedAt, arg.LastFetchedAt, arg.ID)
 	return err
 }
+
+const reassignFeeds = `-- name: ReassignFeeds :execrows
+UPDATE feeds
+SET user_id = $1, updated_at = $2
+WHERE user_id = $3
+`
+
+type ReassignFeedsParams struct {
+	UserID    uuid.UUID
+	UpdatedAt time.Time
+	UserID_2  uuid.UUID
+}
+
+func (q *Queries) ReassignFeeds(ctx context.Context, arg ReassignFeedsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, reassignFeeds, arg.UserID, arg.UpdatedAt, arg.UserID_2)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const renameFeed = `-- name: RenameFeed :one
+UPDATE feeds
+SET name = $1, updated_at = $2
+WHERE id = $3
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, seq
+`
+
+type RenameFeedParams struct {
+	Name      string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) RenameFeed(ctx context.Context, arg RenameFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, renameFeed, arg.Name, arg.UpdatedAt, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Seq,
+	)
+	return i, err
+}
+
+const resetFeedsFetched = `-- name: ResetFeedsFetched :exec
+UPDATE feeds
+SET last_fetched_at = NULL, updated_at = $1
+`
+
+func (q *Queries) ResetFeedsFetched(ctx context.Context, updatedAt time.Time) error {
+	_, err := q.db.ExecContext(ctx, resetFeedsFetched, updatedAt)
+	return err
+}
+
+const updateFeedUrl = `-- name: UpdateFeedUrl :one
+UPDATE feeds
+SET url = $1, updated_at = $2, last_fetched_at = NULL
+WHERE id = $3
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, seq
+`
+
+type UpdateFeedUrlParams struct {
+	Url       string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) UpdateFeedUrl(ctx context.Context, arg UpdateFeedUrlParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedUrl, arg.Url, arg.UpdatedAt, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Seq,
+	)
+	return i, err
+}
@@ -11,6 +11,25 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApiToken struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	LastUsedAt sql.NullTime
+}
+
+type DigestPref struct {
+	UserID     uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Email      string
+	Frequency  string
+	GroupBy    string
+	LastSentAt sql.NullTime
+}
+
 type Feed struct {
 	ID            uuid.UUID
 	CreatedAt     time.Time
@@ -19,6 +38,28 @@ type Feed struct {
 	Url           string
 	UserID        uuid.UUID
 	LastFetchedAt sql.NullTime
+	Seq           int64
+}
+
+type FeedCredential struct {
+	FeedID    uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Kind      string
+	Name      string
+	Secret    string
+}
+
+type FeedFetch struct {
+	ID         uuid.UUID
+	FeedID     uuid.UUID
+	StartedAt  time.Time
+	DurationMs int32
+	StatusCode sql.NullInt32
+	Bytes      int64
+	Items      int32
+	NewPosts   int32
+	Error      sql.NullString
 }
 
 type FeedFollow struct {
@@ -29,6 +70,41 @@ type FeedFollow struct {
 	FeedID    uuid.UUID
 }
 
+type FeedFollowGroup struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	FeedFollowID uuid.UUID
+	Name         string
+}
+
+type FeedRetention struct {
+	FeedID              uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	MaxAgeDays          sql.NullInt32
+	MaxPosts            sql.NullInt32
+	KeepUnreadOrStarred sql.NullBool
+}
+
+type Filter struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Action    string
+	Tag       sql.NullString
+}
+
+type PasswordReset struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	CodeHash  string
+	ExpiresAt time.Time
+}
+
 type Post struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
@@ -38,11 +114,87 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Author      sql.NullString
+	Categories  sql.NullString
+	Seq         int64
+}
+
+type PostState struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	Read      bool
+	Starred   bool
+	Hidden    bool
+}
+
+type PostTag struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	Tag       string
+}
+
+type PrunedUrl struct {
+	FeedID   uuid.UUID
+	Url      string
+	PrunedAt time.Time
+}
+
+type Session struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	TokenHash  string
+	ExpiresAt  time.Time
+	LastUsedAt sql.NullTime
 }
 
 type User struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	PasswordHash sql.NullString
+	Role         string
+}
+
+type Webhook struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Name      string
+	UserID    uuid.UUID
+	Url       string
+	Secret    string
+	FeedID    uuid.NullUUID
+	GroupName sql.NullString
+	Tag       sql.NullString
+	Template  sql.NullString
+	Batch     bool
+}
+
+type WebhookDelivery struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	WebhookID  uuid.UUID
+	Event      string
+	Posts      int32
+	Attempts   int32
+	StatusCode sql.NullInt32
+	Error      sql.NullString
+	Succeeded  bool
+}
+
+type WebsubSubscription struct {
+	FeedID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Hub            string
+	Topic          string
+	Secret         string
+	State          string
+	LeaseExpiresAt sql.NullTime
 }
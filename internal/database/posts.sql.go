@@ -13,8 +13,45 @@ import (
 	"github.com/google/uuid"
 )
 
+const countPostsForUser = `-- name: CountPostsForUser :one
+SELECT COUNT(*) FROM posts
+INNER JOIN feed_follows on posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states on post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND NOT COALESCE(post_states.hidden, FALSE)
+`
+
+func (q *Queries) CountPostsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPostsForUser, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUnreadPostsSince = `-- name: CountUnreadPostsSince :one
+SELECT COUNT(*) FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND posts.created_at > $2
+AND NOT COALESCE(post_states.read, FALSE)
+AND NOT COALESCE(post_states.hidden, FALSE)
+`
+
+type CountUnreadPostsSinceParams struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) CountUnreadPostsSince(ctx context.Context, arg CountUnreadPostsSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUnreadPostsSince, arg.UserID, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories)
 VALUES(
     $1,
     $2,
@@ -23,9 +60,12 @@ VALUES(
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10
 )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+ON CONFLICT (url) DO NOTHING
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, seq
 `
 
 type CreatePostParams struct {
@@ -37,6 +77,8 @@ type CreatePostParams struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Author      sql.NullString
+	Categories  sql.NullString
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -49,6 +91,8 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Author,
+		arg.Categories,
 	)
 	var i Post
 	err := row.Scan(
@@ -60,25 +104,213 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Author,
+		&i.Categories,
+		&i.Seq,
 	)
 	return i, err
 }
 
-const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, feeds.name as feed_name FROM posts
+const deletePost = `-- name: DeletePost :execrows
+DELETE FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) DeletePost(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePost, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePosts = `-- name: DeletePosts :execrows
+DELETE FROM posts
+`
+
+func (q *Queries) DeletePosts(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePosts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAllPosts = `-- name: GetAllPosts :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, seq FROM posts
+`
+
+func (q *Queries) GetAllPosts(ctx context.Context) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getAllPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Categories,
+			&i.Seq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostForUser = `-- name: GetPostForUser :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.seq, feeds.name as feed_name, feeds.url as feed_url,
+    COALESCE(post_states.read, FALSE) AS read,
+    COALESCE(post_states.starred, FALSE) AS starred
+FROM posts
 INNER JOIN feed_follows on posts.feed_id = feed_follows.feed_id
 INNER JOIN feeds on posts.feed_id = feeds.id
-WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2
+LEFT JOIN post_states on post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND posts.id = $2
 `
 
-type GetPostsForUserParams struct {
+type GetPostForUserParams struct {
 	UserID uuid.UUID
+	ID     uuid.UUID
+}
+
+type GetPostForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      sql.NullString
+	Categories  sql.NullString
+	Seq         int64
+	FeedName    string
+	FeedUrl     string
+	Read        bool
+	Starred     bool
+}
+
+func (q *Queries) GetPostForUser(ctx context.Context, arg GetPostForUserParams) (GetPostForUserRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostForUser, arg.UserID, arg.ID)
+	var i GetPostForUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Categories,
+		&i.Seq,
+		&i.FeedName,
+		&i.FeedUrl,
+		&i.Read,
+		&i.Starred,
+	)
+	return i, err
+}
+
+const getPostForUserBySeq = `-- name: GetPostForUserBySeq :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.seq, feeds.name as feed_name, feeds.url as feed_url, feeds.seq as feed_seq,
+    COALESCE(post_states.read, FALSE) AS read,
+    COALESCE(post_states.starred, FALSE) AS starred
+FROM posts
+INNER JOIN feed_follows on posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds on posts.feed_id = feeds.id
+LEFT JOIN post_states on post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND posts.seq = $2
+`
+
+type GetPostForUserBySeqParams struct {
+	UserID uuid.UUID
+	Seq    int64
+}
+
+type GetPostForUserBySeqRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      sql.NullString
+	Categories  sql.NullString
+	Seq         int64
+	FeedName    string
+	FeedUrl     string
+	FeedSeq     int64
+	Read        bool
+	Starred     bool
+}
+
+func (q *Queries) GetPostForUserBySeq(ctx context.Context, arg GetPostForUserBySeqParams) (GetPostForUserBySeqRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostForUserBySeq, arg.UserID, arg.Seq)
+	var i GetPostForUserBySeqRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Categories,
+		&i.Seq,
+		&i.FeedName,
+		&i.FeedUrl,
+		&i.FeedSeq,
+		&i.Read,
+		&i.Starred,
+	)
+	return i, err
+}
+
+const getPostsForUserAfterSeq = `-- name: GetPostsForUserAfterSeq :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.seq, feeds.name as feed_name, feeds.url as feed_url, feeds.seq as feed_seq,
+    COALESCE(post_states.read, FALSE) AS read,
+    COALESCE(post_states.starred, FALSE) AS starred
+FROM posts
+INNER JOIN feed_follows on posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds on posts.feed_id = feeds.id
+LEFT JOIN post_states on post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND posts.seq > $2 AND NOT COALESCE(post_states.hidden, FALSE)
+ORDER BY posts.seq
+LIMIT $3
+`
+
+type GetPostsForUserAfterSeqParams struct {
+	UserID uuid.UUID
+	Seq    int64
 	Limit  int32
 }
 
-type GetPostsForUserRow struct {
+type GetPostsForUserAfterSeqRow struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
@@ -87,18 +319,376 @@ type GetPostsForUserRow struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Author      sql.NullString
+	Categories  sql.NullString
+	Seq         int64
 	FeedName    string
+	FeedUrl     string
+	FeedSeq     int64
+	Read        bool
+	Starred     bool
 }
 
-func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+func (q *Queries) GetPostsForUserAfterSeq(ctx context.Context, arg GetPostsForUserAfterSeqParams) ([]GetPostsForUserAfterSeqRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUserAfterSeq, arg.UserID, arg.Seq, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForUserAfterSeqRow
+	for rows.Next() {
+		var i GetPostsForUserAfterSeqRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Categories,
+			&i.Seq,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.FeedSeq,
+			&i.Read,
+			&i.Starred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsForUserBeforeSeq = `-- name: GetPostsForUserBeforeSeq :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.seq, feeds.name as feed_name, feeds.url as feed_url, feeds.seq as feed_seq,
+    COALESCE(post_states.read, FALSE) AS read,
+    COALESCE(post_states.starred, FALSE) AS starred
+FROM posts
+INNER JOIN feed_follows on posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds on posts.feed_id = feeds.id
+LEFT JOIN post_states on post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND posts.seq < $2 AND NOT COALESCE(post_states.hidden, FALSE)
+ORDER BY posts.seq DESC
+LIMIT $3
+`
+
+type GetPostsForUserBeforeSeqParams struct {
+	UserID uuid.UUID
+	Seq    int64
+	Limit  int32
+}
+
+type GetPostsForUserBeforeSeqRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      sql.NullString
+	Categories  sql.NullString
+	Seq         int64
+	FeedName    string
+	FeedUrl     string
+	FeedSeq     int64
+	Read        bool
+	Starred     bool
+}
+
+func (q *Queries) GetPostsForUserBeforeSeq(ctx context.Context, arg GetPostsForUserBeforeSeqParams) ([]GetPostsForUserBeforeSeqRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUserBeforeSeq, arg.UserID, arg.Seq, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForUserBeforeSeqRow
+	for rows.Next() {
+		var i GetPostsForUserBeforeSeqRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Categories,
+			&i.Seq,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.FeedSeq,
+			&i.Read,
+			&i.Starred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostTimesForFeed = `-- name: GetPostTimesForFeed :many
+SELECT published_at, created_at FROM posts
+WHERE feed_id = $1
+`
+
+type GetPostTimesForFeedRow struct {
+	PublishedAt sql.NullTime
+	CreatedAt   time.Time
+}
+
+func (q *Queries) GetPostTimesForFeed(ctx context.Context, feedID uuid.UUID) ([]GetPostTimesForFeedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostTimesForFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostTimesForFeedRow
+	for rows.Next() {
+		var i GetPostTimesForFeedRow
+		if err := rows.Scan(&i.PublishedAt, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStarredPostSeqsForUser = `-- name: GetStarredPostSeqsForUser :many
+SELECT posts.seq FROM posts
+INNER JOIN feed_follows on posts.feed_id = feed_follows.feed_id
+INNER JOIN post_states on post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND post_states.starred
+ORDER BY posts.seq
+`
+
+func (q *Queries) GetStarredPostSeqsForUser(ctx context.Context, userID uuid.UUID) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, getStarredPostSeqsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var seq int64
+		if err := rows.Scan(&seq); err != nil {
+			return nil, err
+		}
+		items = append(items, seq)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnreadPostSeqsForUser = `-- name: GetUnreadPostSeqsForUser :many
+SELECT posts.seq FROM posts
+INNER JOIN feed_follows on posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states on post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND NOT COALESCE(post_states.read, FALSE)
+    AND NOT COALESCE(post_states.hidden, FALSE)
+ORDER BY posts.seq
+`
+
+func (q *Queries) GetUnreadPostSeqsForUser(ctx context.Context, userID uuid.UUID) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, getUnreadPostSeqsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var seq int64
+		if err := rows.Scan(&seq); err != nil {
+			return nil, err
+		}
+		items = append(items, seq)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnreadPostsSince = `-- name: GetUnreadPostsSince :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.seq, feeds.name AS feed_name, feeds.url AS feed_url
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND posts.created_at > $2
+AND NOT COALESCE(post_states.read, FALSE)
+AND NOT COALESCE(post_states.hidden, FALSE)
+ORDER BY feeds.name, posts.published_at DESC NULLS FIRST
+LIMIT $3
+`
+
+type GetUnreadPostsSinceParams struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	Limit     int32
+}
+
+type GetUnreadPostsSinceRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      sql.NullString
+	Categories  sql.NullString
+	Seq         int64
+	FeedName    string
+	FeedUrl     string
+}
+
+func (q *Queries) GetUnreadPostsSince(ctx context.Context, arg GetUnreadPostsSinceParams) ([]GetUnreadPostsSinceRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUnreadPostsSince, arg.UserID, arg.CreatedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnreadPostsSinceRow
+	for rows.Next() {
+		var i GetUnreadPostsSinceRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Categories,
+			&i.Seq,
+			&i.FeedName,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPostsForUser = `-- name: ListPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.seq, feeds.name as feed_name, feeds.url as feed_url,
+    COALESCE(post_states.read, FALSE) AS read,
+    COALESCE(post_states.starred, FALSE) AS starred
+FROM posts
+INNER JOIN feed_follows on posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds on posts.feed_id = feeds.id
+LEFT JOIN post_states on post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND NOT COALESCE(post_states.hidden, FALSE)
+    AND ($2 IS NULL OR posts.feed_id = $2)
+    AND ($3 IS NULL OR EXISTS (
+        SELECT 1 FROM feed_follow_groups
+        WHERE feed_follow_groups.feed_follow_id = feed_follows.id
+            AND feed_follow_groups.name = $3
+    ))
+    AND ($4 = FALSE OR NOT COALESCE(post_states.read, FALSE))
+    AND ($5 = FALSE OR COALESCE(post_states.starred, FALSE))
+    AND ($6 IS NULL
+        OR LOWER(posts.title) LIKE $6
+        OR LOWER(posts.description) LIKE $6)
+ORDER BY posts.published_at DESC NULLS FIRST, posts.id
+LIMIT $7 OFFSET $8
+`
+
+type ListPostsForUserParams struct {
+	UserID      uuid.UUID
+	FeedID      uuid.NullUUID
+	GroupName   sql.NullString
+	UnreadOnly  bool
+	StarredOnly bool
+	Keyword     sql.NullString
+	Limit       int32
+	Offset      int32
+}
+
+type ListPostsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Author      sql.NullString
+	Categories  sql.NullString
+	Seq         int64
+	FeedName    string
+	FeedUrl     string
+	Read        bool
+	Starred     bool
+}
+
+func (q *Queries) ListPostsForUser(ctx context.Context, arg ListPostsForUserParams) ([]ListPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPostsForUser,
+		arg.UserID,
+		arg.FeedID,
+		arg.GroupName,
+		arg.UnreadOnly,
+		arg.StarredOnly,
+		arg.Keyword,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetPostsForUserRow
+	var items []ListPostsForUserRow
 	for rows.Next() {
-		var i GetPostsForUserRow
+		var i ListPostsForUserRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
@@ -108,7 +698,13 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Author,
+			&i.Categories,
+			&i.Seq,
 			&i.FeedName,
+			&i.FeedUrl,
+			&i.Read,
+			&i.Starred,
 		); err != nil {
 			return nil, err
 		}
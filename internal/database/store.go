@@ -0,0 +1,16 @@
+package database
+
+import "context"
+
+// Store is the storage the aggregator runs on. Package storage implements it
+// on top of a database connection; package memstore implements it in memory.
+type Store interface {
+	Querier
+
+	CreatePosts(ctx context.Context, args []CreatePostParams) ([]Post, error)
+
+	// InTx runs fn against a Store whose changes are committed together
+	// when fn returns nil and rolled back when it returns an error. InTx
+	// on the Store fn is given joins the transaction already running.
+	InTx(ctx context.Context, fn func(Store) error) error
+}
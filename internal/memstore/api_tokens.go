@@ -0,0 +1,83 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func (s *Store) CreateApiToken(ctx context.Context, arg database.CreateApiTokenParams) (database.ApiToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return database.ApiToken{}, errForeignKey("api_tokens", "api_tokens_user_id_fkey")
+	}
+
+	if _, ok := find(s.apiTokens, func(v database.ApiToken) bool { return v.TokenHash == arg.TokenHash }); ok {
+		return database.ApiToken{}, errUnique("api_tokens_token_hash_key")
+	}
+
+	v := database.ApiToken{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UserID:    arg.UserID,
+		Name:      arg.Name,
+		TokenHash: arg.TokenHash,
+	}
+	s.apiTokens = append(s.apiTokens, v)
+
+	return v, nil
+}
+
+func (s *Store) GetApiTokensForUser(ctx context.Context, userID uuid.UUID) ([]database.ApiToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := where(s.apiTokens, func(v database.ApiToken) bool { return v.UserID == userID })
+	slices.SortStableFunc(res, func(a, b database.ApiToken) int { return compareTime(a.CreatedAt, b.CreatedAt) })
+
+	return res, nil
+}
+
+func (s *Store) GetUserByApiToken(ctx context.Context, tokenHash string) (database.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, err := one(s.apiTokens, func(v database.ApiToken) bool { return v.TokenHash == tokenHash })
+
+	if err != nil {
+		return database.User{}, err
+	}
+
+	return one(s.users, func(u database.User) bool { return u.ID == token.UserID })
+}
+
+func (s *Store) TouchApiToken(ctx context.Context, arg database.TouchApiTokenParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update(s.apiTokens, func(v database.ApiToken) bool { return v.TokenHash == arg.TokenHash }, func(v *database.ApiToken) {
+		v.LastUsedAt = arg.LastUsedAt
+	})
+
+	return nil
+}
+
+func (s *Store) DeleteApiToken(ctx context.Context, arg database.DeleteApiTokenParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(remove(&s.apiTokens, func(v database.ApiToken) bool {
+		return v.ID == arg.ID && v.UserID == arg.UserID
+	}))), nil
+}
+
+func (s *Store) GetAllApiTokens(ctx context.Context) ([]database.ApiToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.apiTokens, func(database.ApiToken) bool { return true }), nil
+}
@@ -0,0 +1,77 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func (s *Store) SetDigestPrefs(ctx context.Context, arg database.SetDigestPrefsParams) (database.DigestPref, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return database.DigestPref{}, errForeignKey("digest_prefs", "digest_prefs_user_id_fkey")
+	}
+
+	match := func(d database.DigestPref) bool { return d.UserID == arg.UserID }
+
+	n := update(s.digestPrefs, match, func(d *database.DigestPref) {
+		d.UpdatedAt = arg.UpdatedAt
+		d.Email = arg.Email
+		d.Frequency = arg.Frequency
+		d.GroupBy = arg.GroupBy
+	})
+
+	if n == 0 {
+		s.digestPrefs = append(s.digestPrefs, database.DigestPref{
+			UserID:    arg.UserID,
+			CreatedAt: arg.CreatedAt,
+			UpdatedAt: arg.UpdatedAt,
+			Email:     arg.Email,
+			Frequency: arg.Frequency,
+			GroupBy:   arg.GroupBy,
+		})
+	}
+
+	return one(s.digestPrefs, match)
+}
+
+func (s *Store) GetDigestPrefs(ctx context.Context, userID uuid.UUID) (database.DigestPref, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return one(s.digestPrefs, func(d database.DigestPref) bool { return d.UserID == userID })
+}
+
+func (s *Store) GetAllDigestPrefs(ctx context.Context) ([]database.DigestPref, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := where(s.digestPrefs, func(database.DigestPref) bool { return true })
+	slices.SortStableFunc(res, func(a, b database.DigestPref) int {
+		return compareTime(a.CreatedAt, b.CreatedAt)
+	})
+
+	return res, nil
+}
+
+func (s *Store) DeleteDigestPrefs(ctx context.Context, userID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(remove(&s.digestPrefs, func(d database.DigestPref) bool { return d.UserID == userID }))), nil
+}
+
+func (s *Store) MarkDigestSent(ctx context.Context, arg database.MarkDigestSentParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update(s.digestPrefs, func(d database.DigestPref) bool { return d.UserID == arg.UserID }, func(d *database.DigestPref) {
+		d.LastSentAt = arg.LastSentAt
+	})
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package memstore
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func (s *Store) SetFeedCredential(ctx context.Context, arg database.SetFeedCredentialParams) (database.FeedCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.feedExists(arg.FeedID) {
+		return database.FeedCredential{}, errForeignKey("feed_credentials", "feed_credentials_feed_id_fkey")
+	}
+
+	match := func(c database.FeedCredential) bool {
+		return c.FeedID == arg.FeedID && c.Kind == arg.Kind && c.Name == arg.Name
+	}
+
+	n := update(s.credentials, match, func(c *database.FeedCredential) {
+		c.UpdatedAt = arg.UpdatedAt
+		c.Secret = arg.Secret
+	})
+
+	if n == 0 {
+		s.credentials = append(s.credentials, database.FeedCredential(arg))
+	}
+
+	return one(s.credentials, match)
+}
+
+func (s *Store) GetFeedCredentials(ctx context.Context, feedID uuid.UUID) ([]database.FeedCredential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	creds := where(s.credentials, func(c database.FeedCredential) bool { return c.FeedID == feedID })
+
+	slices.SortFunc(creds, func(a, b database.FeedCredential) int {
+		return cmp.Or(cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Name, b.Name))
+	})
+
+	return creds, nil
+}
+
+func (s *Store) DeleteFeedCredential(ctx context.Context, arg database.DeleteFeedCredentialParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(remove(&s.credentials, func(c database.FeedCredential) bool {
+		return c.FeedID == arg.FeedID && c.Kind == arg.Kind && c.Name == arg.Name
+	}))), nil
+}
+
+func (s *Store) DeleteFeedCredentialsByKind(ctx context.Context, arg database.DeleteFeedCredentialsByKindParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(remove(&s.credentials, func(c database.FeedCredential) bool {
+		return c.FeedID == arg.FeedID && c.Kind == arg.Kind
+	}))), nil
+}
+
+func (s *Store) GetFeedIdsWithCredentials(ctx context.Context) ([]uuid.UUID, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var ids []uuid.UUID
+	for _, c := range s.credentials {
+		if !slices.Contains(ids, c.FeedID) {
+			ids = append(ids, c.FeedID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (s *Store) CountFeedCredentials(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(where(s.credentials, func(c database.FeedCredential) bool { return c.FeedID == feedID }))), nil
+}
+
+func (s *Store) GetAllFeedCredentials(ctx context.Context) ([]database.FeedCredential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.credentials, func(database.FeedCredential) bool { return true }), nil
+}
@@ -0,0 +1,51 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+
+	"github.com/w0/aggregator/internal/database"
+)
+
+func (s *Store) CreateFeedFetch(ctx context.Context, arg database.CreateFeedFetchParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.feedExists(arg.FeedID) {
+		return errForeignKey("feed_fetches", "feed_fetches_feed_id_fkey")
+	}
+
+	s.feedFetches = append(s.feedFetches, database.FeedFetch(arg))
+
+	return nil
+}
+
+func (s *Store) GetFeedFetchesSince(ctx context.Context, arg database.GetFeedFetchesSinceParams) ([]database.FeedFetch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows := where(s.feedFetches, func(v database.FeedFetch) bool {
+		return v.FeedID == arg.FeedID && v.StartedAt.After(arg.StartedAt)
+	})
+	slices.SortStableFunc(rows, func(a, b database.FeedFetch) int { return b.StartedAt.Compare(a.StartedAt) })
+
+	return rows, nil
+}
+
+func (s *Store) DeleteFeedFetchesBefore(ctx context.Context, arg database.DeleteFeedFetchesBeforeParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := remove(&s.feedFetches, func(v database.FeedFetch) bool {
+		return v.FeedID == arg.FeedID && v.StartedAt.Before(arg.StartedAt)
+	})
+
+	return int64(len(removed)), nil
+}
+
+func (s *Store) GetAllFeedFetches(ctx context.Context) ([]database.FeedFetch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.feedFetches, func(database.FeedFetch) bool { return true }), nil
+}
@@ -0,0 +1,101 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func (s *Store) AddFeedFollowGroup(ctx context.Context, arg database.AddFeedFollowGroupParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := find(s.feedFollows, func(f database.FeedFollow) bool { return f.ID == arg.FeedFollowID }); !ok {
+		return errForeignKey("feed_follow_groups", "feed_follow_groups_feed_follow_id_fkey")
+	}
+
+	// ON CONFLICT DO NOTHING
+	if _, ok := find(s.followGroups, func(g database.FeedFollowGroup) bool {
+		return g.FeedFollowID == arg.FeedFollowID && g.Name == arg.Name
+	}); ok {
+		return nil
+	}
+
+	s.followGroups = append(s.followGroups, database.FeedFollowGroup{
+		ID:           arg.ID,
+		CreatedAt:    arg.CreatedAt,
+		FeedFollowID: arg.FeedFollowID,
+		Name:         arg.Name,
+	})
+
+	return nil
+}
+
+func (s *Store) RemoveFeedFollowGroup(ctx context.Context, arg database.RemoveFeedFollowGroupParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(remove(&s.followGroups, func(g database.FeedFollowGroup) bool {
+		return g.FeedFollowID == arg.FeedFollowID && g.Name == arg.Name
+	}))), nil
+}
+
+func (s *Store) DeleteGroupForUser(ctx context.Context, arg database.DeleteGroupForUserParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(remove(&s.followGroups, func(g database.FeedFollowGroup) bool {
+		if g.Name != arg.Name {
+			return false
+		}
+		_, ok := find(s.feedFollows, func(f database.FeedFollow) bool {
+			return f.ID == g.FeedFollowID && f.UserID == arg.UserID
+		})
+		return ok
+	}))), nil
+}
+
+func (s *Store) GetGroupsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetGroupsForUserRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var res []database.GetGroupsForUserRow
+	for _, g := range s.followGroups {
+		ff, ok := find(s.feedFollows, func(f database.FeedFollow) bool {
+			return f.ID == g.FeedFollowID && f.UserID == userID
+		})
+		if !ok {
+			continue
+		}
+
+		f, ok := find(s.feeds, func(f database.Feed) bool { return f.ID == ff.FeedID })
+		if !ok {
+			continue
+		}
+
+		res = append(res, database.GetGroupsForUserRow{
+			Name:     g.Name,
+			FeedName: f.Name,
+			FeedUrl:  f.Url,
+		})
+	}
+
+	slices.SortStableFunc(res, func(a, b database.GetGroupsForUserRow) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.FeedName, b.FeedName)
+	})
+
+	return res, nil
+}
+
+func (s *Store) GetAllFeedFollowGroups(ctx context.Context) ([]database.FeedFollowGroup, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.followGroups, func(database.FeedFollowGroup) bool { return true }), nil
+}
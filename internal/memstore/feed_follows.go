@@ -0,0 +1,172 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func (s *Store) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := find(s.users, func(u database.User) bool { return u.ID == arg.UserID })
+	if !ok {
+		return database.CreateFeedFollowRow{}, errForeignKey("feed_follows", "feed_follows_user_id_fkey")
+	}
+
+	feed, ok := find(s.feeds, func(f database.Feed) bool { return f.ID == arg.FeedID })
+	if !ok {
+		return database.CreateFeedFollowRow{}, errForeignKey("feed_follows", "feed_follows_feed_id_fkey")
+	}
+
+	if _, ok := find(s.feedFollows, func(f database.FeedFollow) bool {
+		return f.UserID == arg.UserID && f.FeedID == arg.FeedID
+	}); ok {
+		return database.CreateFeedFollowRow{}, errUnique("feed_follows_user_id_feed_id_key")
+	}
+
+	s.feedFollows = append(s.feedFollows, database.FeedFollow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+	})
+
+	return database.CreateFeedFollowRow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+		FeedName:  feed.Name,
+		UserName:  user.Name,
+	}, nil
+}
+
+func (s *Store) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var res []database.GetFeedFollowsForUserRow
+	for _, ff := range where(s.feedFollows, func(f database.FeedFollow) bool { return f.UserID == userID }) {
+		u, ok := find(s.users, func(u database.User) bool { return u.ID == ff.UserID })
+		if !ok {
+			continue
+		}
+
+		f, ok := find(s.feeds, func(f database.Feed) bool { return f.ID == ff.FeedID })
+		if !ok {
+			continue
+		}
+
+		res = append(res, database.GetFeedFollowsForUserRow{
+			ID:            ff.ID,
+			CreatedAt:     ff.CreatedAt,
+			UpdatedAt:     ff.UpdatedAt,
+			UserID:        ff.UserID,
+			FeedID:        ff.FeedID,
+			ID_2:          u.ID,
+			CreatedAt_2:   u.CreatedAt,
+			UpdatedAt_2:   u.UpdatedAt,
+			Name:          u.Name,
+			PasswordHash:  u.PasswordHash,
+			Role:          u.Role,
+			ID_3:          f.ID,
+			CreatedAt_3:   f.CreatedAt,
+			UpdatedAt_3:   f.UpdatedAt,
+			Name_2:        f.Name,
+			Url:           f.Url,
+			UserID_2:      f.UserID,
+			LastFetchedAt: f.LastFetchedAt,
+			Seq:           f.Seq,
+			FeedName:      f.Name,
+			UserName:      u.Name,
+		})
+	}
+
+	return res, nil
+}
+
+func (s *Store) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteFeedFollows(func(f database.FeedFollow) bool {
+		return f.UserID == arg.UserID && f.FeedID == arg.FeedID
+	}), nil
+}
+
+func (s *Store) GetFeedFollow(ctx context.Context, arg database.GetFeedFollowParams) (database.FeedFollow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return one(s.feedFollows, func(f database.FeedFollow) bool {
+		return f.UserID == arg.UserID && f.FeedID == arg.FeedID
+	})
+}
+
+func (s *Store) GetAllFeedFollows(ctx context.Context) ([]database.FeedFollow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.feedFollows, func(database.FeedFollow) bool { return true }), nil
+}
+
+func (s *Store) GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetUnreadCountsForUserRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var res []database.GetUnreadCountsForUserRow
+	for _, ff := range where(s.feedFollows, func(f database.FeedFollow) bool { return f.UserID == userID }) {
+		f, ok := find(s.feeds, func(f database.Feed) bool { return f.ID == ff.FeedID })
+		if !ok {
+			continue
+		}
+
+		row := database.GetUnreadCountsForUserRow{ID: f.ID, Name: f.Name, Url: f.Url}
+		for _, p := range s.posts {
+			if p.FeedID != f.ID {
+				continue
+			}
+
+			state := s.postState(userID, p.ID)
+			if !state.Read && !state.Hidden {
+				row.Unread++
+			}
+		}
+
+		res = append(res, row)
+	}
+
+	slices.SortStableFunc(res, func(a, b database.GetUnreadCountsForUserRow) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return res, nil
+}
+
+func (s *Store) CountFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(where(s.feedFollows, func(f database.FeedFollow) bool { return f.UserID == userID }))), nil
+}
+
+func (s *Store) GetFollowersOfFeed(ctx context.Context, feedID uuid.UUID) ([]database.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := where(s.users, func(u database.User) bool {
+		_, ok := find(s.feedFollows, func(f database.FeedFollow) bool { return f.UserID == u.ID && f.FeedID == feedID })
+		return ok
+	})
+
+	slices.SortFunc(users, func(a, b database.User) int { return strings.Compare(a.Name, b.Name) })
+
+	return users, nil
+}
@@ -0,0 +1,220 @@
+package memstore
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func (s *Store) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return database.Feed{}, errForeignKey("feeds", "feeds_user_id_fkey")
+	}
+
+	if _, ok := find(s.feeds, func(f database.Feed) bool { return f.Url == arg.Url }); ok {
+		return database.Feed{}, errUnique("feeds_url_key")
+	}
+
+	s.feedSeq++
+	f := database.Feed{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+		Seq:       s.feedSeq,
+	}
+	s.feeds = append(s.feeds, f)
+
+	return f, nil
+}
+
+func (s *Store) GetFeeds(ctx context.Context) ([]database.GetFeedsRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var res []database.GetFeedsRow
+	for _, f := range s.feeds {
+		u, ok := find(s.users, func(u database.User) bool { return u.ID == f.UserID })
+		if !ok {
+			continue
+		}
+
+		res = append(res, database.GetFeedsRow{
+			ID:        f.ID,
+			UserID:    f.UserID,
+			Name:      f.Name,
+			Url:       f.Url,
+			CreatedBy: u.Name,
+		})
+	}
+
+	return res, nil
+}
+
+func (s *Store) GetAllFeeds(ctx context.Context) ([]database.Feed, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.feeds, func(database.Feed) bool { return true }), nil
+}
+
+func (s *Store) GetFeedByUrl(ctx context.Context, url string) (database.Feed, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return one(s.feeds, func(f database.Feed) bool { return f.Url == url })
+}
+
+func (s *Store) GetFeedById(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return one(s.feeds, func(f database.Feed) bool { return f.ID == id })
+}
+
+func (s *Store) MarkFeedFetched(ctx context.Context, arg database.MarkFeedFetchedParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update(s.feeds, func(f database.Feed) bool { return f.ID == arg.ID }, func(f *database.Feed) {
+		f.UpdatedAt = arg.UpdatedAt
+		f.LastFetchedAt = arg.LastFetchedAt
+	})
+
+	return nil
+}
+
+func (s *Store) GetNextFeedToFetch(ctx context.Context, arg database.GetNextFeedToFetchParams) (database.Feed, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	due := s.dueFeeds(arg.LastFetchedAt, arg.LeaseExpiresAt)
+
+	if len(due) == 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+
+	return due[0], nil
+}
+
+func (s *Store) GetNextFeedsToFetch(ctx context.Context, arg database.GetNextFeedsToFetchParams) ([]database.Feed, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return limit(s.dueFeeds(arg.LastFetchedAt, arg.LeaseExpiresAt), arg.Limit), nil
+}
+
+// dueFeeds lists the feeds to fetch, longest waiting first: those never
+// fetched or fetched before lastFetchedAt, and those no hub pushes to with
+// a lease past leaseExpiresAt.
+func (s *Store) dueFeeds(lastFetchedAt, leaseExpiresAt sql.NullTime) []database.Feed {
+	pushed := func(f database.Feed) bool {
+		_, ok := find(s.websubs, func(w database.WebsubSubscription) bool {
+			return w.FeedID == f.ID && w.State == "active" &&
+				w.LeaseExpiresAt.Valid && leaseExpiresAt.Valid &&
+				w.LeaseExpiresAt.Time.After(leaseExpiresAt.Time)
+		})
+		return ok
+	}
+
+	due := where(s.feeds, func(f database.Feed) bool {
+		return !f.LastFetchedAt.Valid ||
+			(lastFetchedAt.Valid && f.LastFetchedAt.Time.Before(lastFetchedAt.Time)) ||
+			!pushed(f)
+	})
+
+	// NULLS FIRST
+	slices.SortStableFunc(due, func(a, b database.Feed) int {
+		switch {
+		case !a.LastFetchedAt.Valid && !b.LastFetchedAt.Valid:
+			return 0
+		case !a.LastFetchedAt.Valid:
+			return -1
+		case !b.LastFetchedAt.Valid:
+			return 1
+		}
+		return compareTime(a.LastFetchedAt.Time, b.LastFetchedAt.Time)
+	})
+
+	return due
+}
+
+func (s *Store) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteFeeds(func(f database.Feed) bool { return f.ID == id })
+
+	return nil
+}
+
+func (s *Store) RenameFeed(ctx context.Context, arg database.RenameFeedParams) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update(s.feeds, func(f database.Feed) bool { return f.ID == arg.ID }, func(f *database.Feed) {
+		f.Name = arg.Name
+		f.UpdatedAt = arg.UpdatedAt
+	})
+
+	return one(s.feeds, func(f database.Feed) bool { return f.ID == arg.ID })
+}
+
+func (s *Store) UpdateFeedUrl(ctx context.Context, arg database.UpdateFeedUrlParams) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := find(s.feeds, func(f database.Feed) bool { return f.Url == arg.Url && f.ID != arg.ID }); ok {
+		return database.Feed{}, errUnique("feeds_url_key")
+	}
+
+	update(s.feeds, func(f database.Feed) bool { return f.ID == arg.ID }, func(f *database.Feed) {
+		f.Url = arg.Url
+		f.UpdatedAt = arg.UpdatedAt
+		f.LastFetchedAt = sql.NullTime{}
+	})
+
+	return one(s.feeds, func(f database.Feed) bool { return f.ID == arg.ID })
+}
+
+func (s *Store) ResetFeedsFetched(ctx context.Context, updatedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update(s.feeds, func(database.Feed) bool { return true }, func(f *database.Feed) {
+		f.LastFetchedAt = sql.NullTime{}
+		f.UpdatedAt = updatedAt
+	})
+
+	return nil
+}
+
+func (s *Store) ReassignFeeds(ctx context.Context, arg database.ReassignFeedsParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return 0, errForeignKey("feeds", "feeds_user_id_fkey")
+	}
+
+	return update(s.feeds, func(f database.Feed) bool { return f.UserID == arg.UserID_2 }, func(f *database.Feed) {
+		f.UserID = arg.UserID
+		f.UpdatedAt = arg.UpdatedAt
+	}), nil
+}
+
+func (s *Store) CountFeedsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(where(s.feeds, func(f database.Feed) bool { return f.UserID == userID }))), nil
+}
@@ -0,0 +1,78 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func byFilterCreated(a, b database.Filter) int {
+	return compareTime(a.CreatedAt, b.CreatedAt)
+}
+
+func (s *Store) CreateFilter(ctx context.Context, arg database.CreateFilterParams) (database.Filter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return database.Filter{}, errForeignKey("filters", "filters_user_id_fkey")
+	}
+
+	f := database.Filter{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		Field:     arg.Field,
+		MatchType: arg.MatchType,
+		Pattern:   arg.Pattern,
+		Action:    arg.Action,
+		Tag:       arg.Tag,
+	}
+	s.filters = append(s.filters, f)
+
+	return f, nil
+}
+
+func (s *Store) GetFiltersForUser(ctx context.Context, userID uuid.UUID) ([]database.Filter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := where(s.filters, func(f database.Filter) bool { return f.UserID == userID })
+	slices.SortStableFunc(res, byFilterCreated)
+
+	return res, nil
+}
+
+func (s *Store) GetFiltersForFeed(ctx context.Context, feedID uuid.UUID) ([]database.Filter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := where(s.filters, func(f database.Filter) bool {
+		_, ok := find(s.feedFollows, func(ff database.FeedFollow) bool {
+			return ff.UserID == f.UserID && ff.FeedID == feedID
+		})
+		return ok
+	})
+	slices.SortStableFunc(res, byFilterCreated)
+
+	return res, nil
+}
+
+func (s *Store) DeleteFilter(ctx context.Context, arg database.DeleteFilterParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(remove(&s.filters, func(f database.Filter) bool {
+		return f.ID == arg.ID && f.UserID == arg.UserID
+	}))), nil
+}
+
+func (s *Store) GetAllFilters(ctx context.Context) ([]database.Filter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.filters, func(database.Filter) bool { return true }), nil
+}
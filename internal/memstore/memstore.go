@@ -0,0 +1,318 @@
+// Package memstore is a thread-safe, in-memory database.Store. It keeps the
+// schema's unique constraints, foreign keys and cascading deletes, so code
+// written against Postgres behaves the same on top of it, which makes it
+// suitable for tests and throwaway instances.
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+type Store struct {
+	mu sync.RWMutex
+
+	tables
+}
+
+type tables struct {
+	users        []database.User
+	feeds        []database.Feed
+	feedFollows  []database.FeedFollow
+	followGroups []database.FeedFollowGroup
+	posts        []database.Post
+	postStates   []database.PostState
+	postTags     []database.PostTag
+	filters      []database.Filter
+	apiTokens    []database.ApiToken
+	sessions     []database.Session
+	webhooks     []database.Webhook
+	deliveries   []database.WebhookDelivery
+	digestPrefs  []database.DigestPref
+	websubs      []database.WebsubSubscription
+	retentions   []database.FeedRetention
+	feedFetches  []database.FeedFetch
+	credentials  []database.FeedCredential
+	resets       []database.PasswordReset
+	prunedUrls   []database.PrunedUrl
+
+	feedSeq int64
+	postSeq int64
+}
+
+var _ database.Store = (*Store)(nil)
+
+func New() *Store {
+	return &Store{}
+}
+
+// clone copies the tables so changes to s don't show up in the copy.
+func (t tables) clone() tables {
+	t.users = slices.Clone(t.users)
+	t.feeds = slices.Clone(t.feeds)
+	t.feedFollows = slices.Clone(t.feedFollows)
+	t.followGroups = slices.Clone(t.followGroups)
+	t.posts = slices.Clone(t.posts)
+	t.postStates = slices.Clone(t.postStates)
+	t.postTags = slices.Clone(t.postTags)
+	t.filters = slices.Clone(t.filters)
+	t.apiTokens = slices.Clone(t.apiTokens)
+	t.sessions = slices.Clone(t.sessions)
+	t.webhooks = slices.Clone(t.webhooks)
+	t.deliveries = slices.Clone(t.deliveries)
+	t.digestPrefs = slices.Clone(t.digestPrefs)
+	t.websubs = slices.Clone(t.websubs)
+	t.retentions = slices.Clone(t.retentions)
+	t.feedFetches = slices.Clone(t.feedFetches)
+	t.credentials = slices.Clone(t.credentials)
+	t.resets = slices.Clone(t.resets)
+	t.prunedUrls = slices.Clone(t.prunedUrls)
+	return t
+}
+
+// InTx runs fn against a copy of the tables and keeps the copy when fn
+// succeeds. The store stays locked until then, so other writes wait for the
+// transaction instead of being lost when it commits or rolls back, and
+// nothing outside it sees its writes before it commits.
+func (s *Store) InTx(ctx context.Context, fn func(database.Store) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	work := &Store{tables: s.tables.clone()}
+
+	err := fn(tx{work})
+
+	if err != nil {
+		return err
+	}
+
+	s.tables = work.tables
+
+	return nil
+}
+
+// tx is the store inside a transaction.
+type tx struct {
+	*Store
+}
+
+func (t tx) InTx(ctx context.Context, fn func(database.Store) error) error {
+	return fn(t)
+}
+
+// errUnique mimics the error Postgres returns for a unique violation, which
+// callers such as saveFeeds look for.
+func errUnique(constraint string) error {
+	return fmt.Errorf("duplicate key value violates unique constraint %q", constraint)
+}
+
+func errForeignKey(table, constraint string) error {
+	return fmt.Errorf("insert or update on table %q violates foreign key constraint %q", table, constraint)
+}
+
+// find returns the first row matching match.
+func find[T any](rows []T, match func(T) bool) (T, bool) {
+	i := slices.IndexFunc(rows, match)
+	if i < 0 {
+		var zero T
+		return zero, false
+	}
+	return rows[i], true
+}
+
+// one returns the first row matching match, or sql.ErrNoRows.
+func one[T any](rows []T, match func(T) bool) (T, error) {
+	row, ok := find(rows, match)
+	if !ok {
+		return row, sql.ErrNoRows
+	}
+	return row, nil
+}
+
+// where returns every row matching match, nil when there are none.
+func where[T any](rows []T, match func(T) bool) []T {
+	var res []T
+	for _, v := range rows {
+		if match(v) {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// update applies fn to every row matching match and returns how many it
+// changed.
+func update[T any](rows []T, match func(T) bool, fn func(*T)) int64 {
+	var n int64
+	for i := range rows {
+		if match(rows[i]) {
+			fn(&rows[i])
+			n++
+		}
+	}
+	return n
+}
+
+// remove deletes every row matching match and returns the deleted rows.
+func remove[T any](rows *[]T, match func(T) bool) []T {
+	removed := where(*rows, match)
+	*rows = slices.DeleteFunc(*rows, match)
+	return removed
+}
+
+func limit[T any](rows []T, n int32) []T {
+	if n >= 0 && int(n) < len(rows) {
+		return rows[:n]
+	}
+	return rows
+}
+
+func offset[T any](rows []T, n int32) []T {
+	if n <= 0 {
+		return rows
+	}
+	if int(n) >= len(rows) {
+		return nil
+	}
+	return rows[n:]
+}
+
+// compareNullTime orders like Postgres, where NULL sorts after every value.
+func compareNullTime(a, b sql.NullTime) int {
+	switch {
+	case !a.Valid && !b.Valid:
+		return 0
+	case !a.Valid:
+		return 1
+	case !b.Valid:
+		return -1
+	}
+	return a.Time.Compare(b.Time)
+}
+
+func compareUUID(a, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+func compareTime(a, b time.Time) int {
+	return a.Compare(b)
+}
+
+// like implements SQL LIKE, where % matches any run of characters and _ any
+// single character. A NULL string never matches.
+func like(s sql.NullString, pattern string) bool {
+	if !s.Valid {
+		return false
+	}
+
+	var re strings.Builder
+	re.WriteString("(?s)^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			re.WriteString(".*")
+		case '_':
+			re.WriteString(".")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	re.WriteString("$")
+
+	return regexp.MustCompile(re.String()).MatchString(s.String)
+}
+
+// The delete helpers cascade like the schema's ON DELETE CASCADE foreign
+// keys. Callers must hold the write lock.
+
+func (s *Store) deleteUsers(match func(database.User) bool) int64 {
+	removed := remove(&s.users, match)
+
+	for _, u := range removed {
+		s.deleteFeeds(func(f database.Feed) bool { return f.UserID == u.ID })
+		s.deleteFeedFollows(func(f database.FeedFollow) bool { return f.UserID == u.ID })
+		remove(&s.postStates, func(p database.PostState) bool { return p.UserID == u.ID })
+		remove(&s.postTags, func(p database.PostTag) bool { return p.UserID == u.ID })
+		remove(&s.filters, func(f database.Filter) bool { return f.UserID == u.ID })
+		remove(&s.apiTokens, func(t database.ApiToken) bool { return t.UserID == u.ID })
+		remove(&s.sessions, func(t database.Session) bool { return t.UserID == u.ID })
+		remove(&s.resets, func(r database.PasswordReset) bool { return r.UserID == u.ID })
+		s.deleteWebhooks(func(w database.Webhook) bool { return w.UserID == u.ID })
+		remove(&s.digestPrefs, func(d database.DigestPref) bool { return d.UserID == u.ID })
+	}
+
+	return int64(len(removed))
+}
+
+func (s *Store) deleteFeeds(match func(database.Feed) bool) int64 {
+	removed := remove(&s.feeds, match)
+
+	for _, f := range removed {
+		s.deleteFeedFollows(func(v database.FeedFollow) bool { return v.FeedID == f.ID })
+		s.deletePosts(func(p database.Post) bool { return p.FeedID == f.ID })
+		s.deleteWebhooks(func(w database.Webhook) bool { return w.FeedID.Valid && w.FeedID.UUID == f.ID })
+		remove(&s.websubs, func(w database.WebsubSubscription) bool { return w.FeedID == f.ID })
+		remove(&s.retentions, func(r database.FeedRetention) bool { return r.FeedID == f.ID })
+		remove(&s.feedFetches, func(v database.FeedFetch) bool { return v.FeedID == f.ID })
+		remove(&s.credentials, func(c database.FeedCredential) bool { return c.FeedID == f.ID })
+		remove(&s.prunedUrls, func(p database.PrunedUrl) bool { return p.FeedID == f.ID })
+	}
+
+	return int64(len(removed))
+}
+
+func (s *Store) deleteFeedFollows(match func(database.FeedFollow) bool) int64 {
+	removed := remove(&s.feedFollows, match)
+
+	for _, f := range removed {
+		remove(&s.followGroups, func(g database.FeedFollowGroup) bool { return g.FeedFollowID == f.ID })
+	}
+
+	return int64(len(removed))
+}
+
+func (s *Store) deletePosts(match func(database.Post) bool) int64 {
+	removed := remove(&s.posts, match)
+
+	for _, p := range removed {
+		remove(&s.postStates, func(v database.PostState) bool { return v.PostID == p.ID })
+		remove(&s.postTags, func(v database.PostTag) bool { return v.PostID == p.ID })
+	}
+
+	return int64(len(removed))
+}
+
+func (s *Store) deleteWebhooks(match func(database.Webhook) bool) int64 {
+	removed := remove(&s.webhooks, match)
+
+	for _, w := range removed {
+		remove(&s.deliveries, func(d database.WebhookDelivery) bool { return d.WebhookID == w.ID })
+	}
+
+	return int64(len(removed))
+}
+
+func (s *Store) userExists(id uuid.UUID) bool {
+	_, ok := find(s.users, func(u database.User) bool { return u.ID == id })
+	return ok
+}
+
+func (s *Store) feedExists(id uuid.UUID) bool {
+	_, ok := find(s.feeds, func(f database.Feed) bool { return f.ID == id })
+	return ok
+}
+
+func (s *Store) postExists(id uuid.UUID) bool {
+	_, ok := find(s.posts, func(p database.Post) bool { return p.ID == id })
+	return ok
+}
@@ -0,0 +1,144 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func createUser(ctx context.Context, db database.Store, name string) error {
+	_, err := db.CreateUser(ctx, database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      name,
+	})
+
+	return err
+}
+
+func TestInTxRollback(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	errFail := errors.New("fail")
+
+	err := s.InTx(ctx, func(tx database.Store) error {
+		if err := createUser(ctx, tx, "alice"); err != nil {
+			return err
+		}
+
+		return errFail
+	})
+
+	if !errors.Is(err, errFail) {
+		t.Fatalf("InTx returned %v, want %v", err, errFail)
+	}
+
+	if _, err := s.GetUser(ctx, "alice"); err == nil {
+		t.Error("a rolled back insert is still there")
+	}
+
+	err = s.InTx(ctx, func(tx database.Store) error {
+		return createUser(ctx, tx, "bob")
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetUser(ctx, "bob"); err != nil {
+		t.Errorf("a committed insert is missing: %v", err)
+	}
+}
+
+// Writes made while a transaction runs must survive its rollback.
+func TestInTxConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+
+	inside := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		<-inside
+
+		for i := range 10 {
+			if err := createUser(ctx, s, fmt.Sprintf("user%d", i)); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+
+	s.InTx(ctx, func(tx database.Store) error {
+		if err := createUser(ctx, tx, "alice"); err != nil {
+			return err
+		}
+
+		close(inside)
+
+		// give the writer time to try
+		time.Sleep(20 * time.Millisecond)
+
+		return errors.New("fail")
+	})
+
+	wg.Wait()
+
+	users, err := s.GetUsers(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users) != 10 {
+		t.Errorf("got %d users, want the 10 written outside the transaction", len(users))
+	}
+}
+
+// A transaction's writes aren't seen outside it before it commits.
+func TestInTxIsolation(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+
+	inside := make(chan struct{})
+	read := make(chan error)
+
+	go func() {
+		<-inside
+		_, err := s.GetUser(ctx, "alice")
+		read <- err
+	}()
+
+	err := s.InTx(ctx, func(tx database.Store) error {
+		if err := createUser(ctx, tx, "alice"); err != nil {
+			return err
+		}
+
+		close(inside)
+
+		select {
+		case err := <-read:
+			return fmt.Errorf("read during the transaction returned %v", err)
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-read; err != nil {
+		t.Errorf("read after the commit: %v", err)
+	}
+}
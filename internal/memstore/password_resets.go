@@ -0,0 +1,53 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func (s *Store) SetPasswordReset(ctx context.Context, arg database.SetPasswordResetParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return errForeignKey("password_resets", "password_resets_user_id_fkey")
+	}
+
+	remove(&s.resets, func(r database.PasswordReset) bool { return r.UserID == arg.UserID })
+	s.resets = append(s.resets, database.PasswordReset(arg))
+
+	return nil
+}
+
+func (s *Store) GetPasswordReset(ctx context.Context, arg database.GetPasswordResetParams) (database.PasswordReset, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return one(s.resets, func(r database.PasswordReset) bool {
+		return r.UserID == arg.UserID && r.ExpiresAt.After(arg.ExpiresAt)
+	})
+}
+
+func (s *Store) DeletePasswordReset(ctx context.Context, userID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(remove(&s.resets, func(r database.PasswordReset) bool { return r.UserID == userID }))), nil
+}
+
+func (s *Store) GetAdminsWithoutPassword(ctx context.Context) ([]database.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// users are kept in the order they were created
+	return where(s.users, func(u database.User) bool { return u.Role == "admin" && !u.PasswordHash.Valid }), nil
+}
+
+func (s *Store) GetAllPasswordResets(ctx context.Context) ([]database.PasswordReset, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.resets, func(database.PasswordReset) bool { return true }), nil
+}
@@ -0,0 +1,127 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+// setPostState upserts the user's state for a post and lets set change the
+// one column the query updates.
+func (s *Store) setPostState(id uuid.UUID, createdAt, updatedAt time.Time, userID, postID uuid.UUID, set func(*database.PostState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(userID) {
+		return errForeignKey("post_states", "post_states_user_id_fkey")
+	}
+
+	if !s.postExists(postID) {
+		return errForeignKey("post_states", "post_states_post_id_fkey")
+	}
+
+	match := func(p database.PostState) bool { return p.UserID == userID && p.PostID == postID }
+
+	n := update(s.postStates, match, func(p *database.PostState) {
+		set(p)
+		p.UpdatedAt = updatedAt
+	})
+
+	if n > 0 {
+		return nil
+	}
+
+	state := database.PostState{
+		ID:        id,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+		UserID:    userID,
+		PostID:    postID,
+	}
+	set(&state)
+	s.postStates = append(s.postStates, state)
+
+	return nil
+}
+
+func (s *Store) SetPostRead(ctx context.Context, arg database.SetPostReadParams) error {
+	return s.setPostState(arg.ID, arg.CreatedAt, arg.UpdatedAt, arg.UserID, arg.PostID, func(p *database.PostState) {
+		p.Read = arg.Read
+	})
+}
+
+func (s *Store) SetPostStarred(ctx context.Context, arg database.SetPostStarredParams) error {
+	return s.setPostState(arg.ID, arg.CreatedAt, arg.UpdatedAt, arg.UserID, arg.PostID, func(p *database.PostState) {
+		p.Starred = arg.Starred
+	})
+}
+
+func (s *Store) SetPostHidden(ctx context.Context, arg database.SetPostHiddenParams) error {
+	return s.setPostState(arg.ID, arg.CreatedAt, arg.UpdatedAt, arg.UserID, arg.PostID, func(p *database.PostState) {
+		p.Hidden = arg.Hidden
+	})
+}
+
+func (s *Store) AddPostTag(ctx context.Context, arg database.AddPostTagParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return errForeignKey("post_tags", "post_tags_user_id_fkey")
+	}
+
+	if !s.postExists(arg.PostID) {
+		return errForeignKey("post_tags", "post_tags_post_id_fkey")
+	}
+
+	// ON CONFLICT DO NOTHING
+	if _, ok := find(s.postTags, func(t database.PostTag) bool {
+		return t.UserID == arg.UserID && t.PostID == arg.PostID && t.Tag == arg.Tag
+	}); ok {
+		return nil
+	}
+
+	s.postTags = append(s.postTags, database.PostTag{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UserID:    arg.UserID,
+		PostID:    arg.PostID,
+		Tag:       arg.Tag,
+	})
+
+	return nil
+}
+
+func (s *Store) GetPostTags(ctx context.Context, arg database.GetPostTagsParams) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var res []string
+	for _, t := range s.postTags {
+		if t.UserID == arg.UserID && t.PostID == arg.PostID {
+			res = append(res, t.Tag)
+		}
+	}
+
+	slices.SortFunc(res, strings.Compare)
+
+	return res, nil
+}
+
+func (s *Store) GetAllPostStates(ctx context.Context) ([]database.PostState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.postStates, func(database.PostState) bool { return true }), nil
+}
+
+func (s *Store) GetAllPostTags(ctx context.Context) ([]database.PostTag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.postTags, func(database.PostTag) bool { return true }), nil
+}
@@ -0,0 +1,395 @@
+package memstore
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+// timelineRow is a post joined with its feed, the user's follow of that feed
+// and the user's state for the post, the shape every timeline query selects.
+type timelineRow struct {
+	database.ListPostsForUserRow
+	FeedSeq int64
+	follow  database.FeedFollow
+	hidden  bool
+}
+
+// timeline returns the posts of every feed the user follows. Callers must
+// hold the lock.
+func (s *Store) timeline(userID uuid.UUID) []timelineRow {
+	var res []timelineRow
+	for _, ff := range where(s.feedFollows, func(f database.FeedFollow) bool { return f.UserID == userID }) {
+		f, ok := find(s.feeds, func(f database.Feed) bool { return f.ID == ff.FeedID })
+		if !ok {
+			continue
+		}
+
+		for _, p := range where(s.posts, func(p database.Post) bool { return p.FeedID == f.ID }) {
+			state := s.postState(userID, p.ID)
+
+			res = append(res, timelineRow{
+				ListPostsForUserRow: database.ListPostsForUserRow{
+					ID:          p.ID,
+					CreatedAt:   p.CreatedAt,
+					UpdatedAt:   p.UpdatedAt,
+					Title:       p.Title,
+					Url:         p.Url,
+					Description: p.Description,
+					PublishedAt: p.PublishedAt,
+					FeedID:      p.FeedID,
+					Author:      p.Author,
+					Categories:  p.Categories,
+					Seq:         p.Seq,
+					FeedName:    f.Name,
+					FeedUrl:     f.Url,
+					Read:        state.Read,
+					Starred:     state.Starred,
+				},
+				FeedSeq: f.Seq,
+				follow:  ff,
+				hidden:  state.Hidden,
+			})
+		}
+	}
+
+	return res
+}
+
+// postState returns the user's state for a post, or the zero state when
+// there is none. Callers must hold the lock.
+func (s *Store) postState(userID, postID uuid.UUID) database.PostState {
+	state, _ := find(s.postStates, func(p database.PostState) bool {
+		return p.UserID == userID && p.PostID == postID
+	})
+	return state
+}
+
+func (s *Store) inGroup(followID uuid.UUID, name string) bool {
+	_, ok := find(s.followGroups, func(g database.FeedFollowGroup) bool {
+		return g.FeedFollowID == followID && g.Name == name
+	})
+	return ok
+}
+
+// byPublishedDesc orders like ORDER BY published_at DESC, with undated posts
+// first as Postgres sorts NULLs.
+func byPublishedDesc(a, b timelineRow) int {
+	return -compareNullTime(a.PublishedAt, b.PublishedAt)
+}
+
+func bySeq(a, b timelineRow) int {
+	switch {
+	case a.Seq < b.Seq:
+		return -1
+	case a.Seq > b.Seq:
+		return 1
+	}
+	return 0
+}
+
+func (s *Store) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.feedExists(arg.FeedID) {
+		return database.Post{}, errForeignKey("posts", "posts_feed_id_fkey")
+	}
+
+	p, ok := s.createPost(arg)
+	if !ok {
+		return database.Post{}, sql.ErrNoRows
+	}
+
+	return p, nil
+}
+
+func (s *Store) CreatePosts(ctx context.Context, args []database.CreatePostParams) ([]database.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// a statement either inserts all of its rows or none
+	for _, arg := range args {
+		if !s.feedExists(arg.FeedID) {
+			return nil, errForeignKey("posts", "posts_feed_id_fkey")
+		}
+	}
+
+	var res []database.Post
+	for _, arg := range args {
+		if p, ok := s.createPost(arg); ok {
+			res = append(res, p)
+		}
+	}
+
+	return res, nil
+}
+
+// createPost inserts the post unless its url is saved already.
+func (s *Store) createPost(arg database.CreatePostParams) (database.Post, bool) {
+	// ON CONFLICT (url) DO NOTHING
+	if _, ok := find(s.posts, func(p database.Post) bool { return p.Url == arg.Url }); ok {
+		return database.Post{}, false
+	}
+
+	s.postSeq++
+	p := database.Post{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		UpdatedAt:   arg.UpdatedAt,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedID:      arg.FeedID,
+		Author:      arg.Author,
+		Categories:  arg.Categories,
+		Seq:         s.postSeq,
+	}
+	s.posts = append(s.posts, p)
+
+	return p, true
+}
+
+func (s *Store) GetAllPosts(ctx context.Context) ([]database.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.posts, func(database.Post) bool { return true }), nil
+}
+
+func (s *Store) DeletePosts(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deletePosts(func(database.Post) bool { return true }), nil
+}
+
+func (s *Store) DeletePost(ctx context.Context, id uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deletePosts(func(p database.Post) bool { return p.ID == id }), nil
+}
+
+func (s *Store) GetPostTimesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.GetPostTimesForFeedRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var res []database.GetPostTimesForFeedRow
+	for _, p := range s.posts {
+		if p.FeedID == feedID {
+			res = append(res, database.GetPostTimesForFeedRow{PublishedAt: p.PublishedAt, CreatedAt: p.CreatedAt})
+		}
+	}
+
+	return res, nil
+}
+
+func (s *Store) GetPostForUser(ctx context.Context, arg database.GetPostForUserParams) (database.GetPostForUserRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, err := one(s.timeline(arg.UserID), func(r timelineRow) bool { return r.ID == arg.ID })
+
+	if err != nil {
+		return database.GetPostForUserRow{}, err
+	}
+
+	return database.GetPostForUserRow(r.ListPostsForUserRow), nil
+}
+
+func (s *Store) ListPostsForUser(ctx context.Context, arg database.ListPostsForUserParams) ([]database.ListPostsForUserRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows := where(s.timeline(arg.UserID), func(r timelineRow) bool {
+		switch {
+		case r.hidden:
+			return false
+		case arg.FeedID.Valid && r.FeedID != arg.FeedID.UUID:
+			return false
+		case arg.GroupName.Valid && !s.inGroup(r.follow.ID, arg.GroupName.String):
+			return false
+		case arg.UnreadOnly && r.Read:
+			return false
+		case arg.StarredOnly && !r.Starred:
+			return false
+		case arg.Keyword.Valid:
+			title := sql.NullString{String: strings.ToLower(r.Title), Valid: true}
+			description := r.Description
+			description.String = strings.ToLower(description.String)
+			return like(title, arg.Keyword.String) || like(description, arg.Keyword.String)
+		}
+		return true
+	})
+
+	slices.SortStableFunc(rows, func(a, b timelineRow) int {
+		if c := byPublishedDesc(a, b); c != 0 {
+			return c
+		}
+		return compareUUID(a.ID, b.ID)
+	})
+
+	var res []database.ListPostsForUserRow
+	for _, r := range limit(offset(rows, arg.Offset), arg.Limit) {
+		res = append(res, r.ListPostsForUserRow)
+	}
+
+	return res, nil
+}
+
+func (s *Store) GetPostsForUserAfterSeq(ctx context.Context, arg database.GetPostsForUserAfterSeqParams) ([]database.GetPostsForUserAfterSeqRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows := where(s.timeline(arg.UserID), func(r timelineRow) bool { return !r.hidden && r.Seq > arg.Seq })
+	slices.SortStableFunc(rows, bySeq)
+
+	var res []database.GetPostsForUserAfterSeqRow
+	for _, r := range limit(rows, arg.Limit) {
+		res = append(res, database.GetPostsForUserAfterSeqRow(r.withFeedSeq()))
+	}
+
+	return res, nil
+}
+
+func (s *Store) GetPostsForUserBeforeSeq(ctx context.Context, arg database.GetPostsForUserBeforeSeqParams) ([]database.GetPostsForUserBeforeSeqRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows := where(s.timeline(arg.UserID), func(r timelineRow) bool { return !r.hidden && r.Seq < arg.Seq })
+	slices.SortStableFunc(rows, func(a, b timelineRow) int { return bySeq(b, a) })
+
+	var res []database.GetPostsForUserBeforeSeqRow
+	for _, r := range limit(rows, arg.Limit) {
+		res = append(res, database.GetPostsForUserBeforeSeqRow(r.withFeedSeq()))
+	}
+
+	return res, nil
+}
+
+func (s *Store) GetPostForUserBySeq(ctx context.Context, arg database.GetPostForUserBySeqParams) (database.GetPostForUserBySeqRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, err := one(s.timeline(arg.UserID), func(r timelineRow) bool { return r.Seq == arg.Seq })
+
+	if err != nil {
+		return database.GetPostForUserBySeqRow{}, err
+	}
+
+	return r.withFeedSeq(), nil
+}
+
+// withFeedSeq returns the row in the shape of the queries that also select
+// feeds.seq.
+func (r timelineRow) withFeedSeq() database.GetPostForUserBySeqRow {
+	return database.GetPostForUserBySeqRow{
+		ID:          r.ID,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+		Title:       r.Title,
+		Url:         r.Url,
+		Description: r.Description,
+		PublishedAt: r.PublishedAt,
+		FeedID:      r.FeedID,
+		Author:      r.Author,
+		Categories:  r.Categories,
+		Seq:         r.Seq,
+		FeedName:    r.FeedName,
+		FeedUrl:     r.FeedUrl,
+		FeedSeq:     r.FeedSeq,
+		Read:        r.Read,
+		Starred:     r.Starred,
+	}
+}
+
+func (s *Store) CountPostsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(where(s.timeline(userID), func(r timelineRow) bool { return !r.hidden }))), nil
+}
+
+func (s *Store) GetUnreadPostSeqsForUser(ctx context.Context, userID uuid.UUID) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows := where(s.timeline(userID), func(r timelineRow) bool { return !r.Read && !r.hidden })
+	slices.SortStableFunc(rows, bySeq)
+
+	var res []int64
+	for _, r := range rows {
+		res = append(res, r.Seq)
+	}
+
+	return res, nil
+}
+
+func (s *Store) GetStarredPostSeqsForUser(ctx context.Context, userID uuid.UUID) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows := where(s.timeline(userID), func(r timelineRow) bool { return r.Starred })
+	slices.SortStableFunc(rows, bySeq)
+
+	var res []int64
+	for _, r := range rows {
+		res = append(res, r.Seq)
+	}
+
+	return res, nil
+}
+
+func (s *Store) CountUnreadPostsSince(ctx context.Context, arg database.CountUnreadPostsSinceParams) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows := where(s.timeline(arg.UserID), func(r timelineRow) bool {
+		return r.CreatedAt.After(arg.CreatedAt) && !r.Read && !r.hidden
+	})
+
+	return int64(len(rows)), nil
+}
+
+func (s *Store) GetUnreadPostsSince(ctx context.Context, arg database.GetUnreadPostsSinceParams) ([]database.GetUnreadPostsSinceRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows := where(s.timeline(arg.UserID), func(r timelineRow) bool {
+		return r.CreatedAt.After(arg.CreatedAt) && !r.Read && !r.hidden
+	})
+
+	slices.SortStableFunc(rows, func(a, b timelineRow) int {
+		if c := strings.Compare(a.FeedName, b.FeedName); c != 0 {
+			return c
+		}
+		return byPublishedDesc(a, b)
+	})
+
+	var res []database.GetUnreadPostsSinceRow
+	for _, r := range limit(rows, arg.Limit) {
+		res = append(res, database.GetUnreadPostsSinceRow{
+			ID:          r.ID,
+			CreatedAt:   r.CreatedAt,
+			UpdatedAt:   r.UpdatedAt,
+			Title:       r.Title,
+			Url:         r.Url,
+			Description: r.Description,
+			PublishedAt: r.PublishedAt,
+			FeedID:      r.FeedID,
+			Author:      r.Author,
+			Categories:  r.Categories,
+			Seq:         r.Seq,
+			FeedName:    r.FeedName,
+			FeedUrl:     r.FeedUrl,
+		})
+	}
+
+	return res, nil
+}
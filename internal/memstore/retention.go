@@ -0,0 +1,149 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func (s *Store) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) (database.FeedRetention, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.feedExists(arg.FeedID) {
+		return database.FeedRetention{}, errForeignKey("feed_retention", "feed_retention_feed_id_fkey")
+	}
+
+	match := func(r database.FeedRetention) bool { return r.FeedID == arg.FeedID }
+
+	n := update(s.retentions, match, func(r *database.FeedRetention) {
+		r.UpdatedAt = arg.UpdatedAt
+		r.MaxAgeDays = arg.MaxAgeDays
+		r.MaxPosts = arg.MaxPosts
+		r.KeepUnreadOrStarred = arg.KeepUnreadOrStarred
+	})
+
+	if n == 0 {
+		s.retentions = append(s.retentions, database.FeedRetention(arg))
+	}
+
+	return one(s.retentions, match)
+}
+
+func (s *Store) GetFeedRetention(ctx context.Context, feedID uuid.UUID) (database.FeedRetention, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return one(s.retentions, func(r database.FeedRetention) bool { return r.FeedID == feedID })
+}
+
+func (s *Store) DeleteFeedRetention(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(remove(&s.retentions, func(r database.FeedRetention) bool { return r.FeedID == feedID }))), nil
+}
+
+func (s *Store) GetPostsForPruning(ctx context.Context, feedID uuid.UUID) ([]database.GetPostsForPruningRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	posts := where(s.posts, func(p database.Post) bool { return p.FeedID == feedID })
+
+	// newest first, by when the post was published or else saved
+	slices.SortStableFunc(posts, func(a, b database.Post) int {
+		at, bt := a.CreatedAt, b.CreatedAt
+		if a.PublishedAt.Valid {
+			at = a.PublishedAt.Time
+		}
+		if b.PublishedAt.Valid {
+			bt = b.PublishedAt.Time
+		}
+		if c := compareTime(bt, at); c != 0 {
+			return c
+		}
+		return int(b.Seq - a.Seq)
+	})
+
+	followers := where(s.feedFollows, func(f database.FeedFollow) bool { return f.FeedID == feedID })
+
+	var res []database.GetPostsForPruningRow
+	for _, p := range posts {
+		row := database.GetPostsForPruningRow{
+			ID:          p.ID,
+			CreatedAt:   p.CreatedAt,
+			Title:       p.Title,
+			Url:         p.Url,
+			PublishedAt: p.PublishedAt,
+		}
+
+		_, row.Starred = find(s.postStates, func(v database.PostState) bool { return v.PostID == p.ID && v.Starred })
+
+		for _, f := range followers {
+			state := s.postState(f.UserID, p.ID)
+			if !state.Read && !state.Hidden {
+				row.Unread = true
+			}
+		}
+
+		res = append(res, row)
+	}
+
+	return res, nil
+}
+
+func (s *Store) CreatePrunedUrl(ctx context.Context, arg database.CreatePrunedUrlParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.feedExists(arg.FeedID) {
+		return errForeignKey("pruned_urls", "pruned_urls_feed_id_fkey")
+	}
+
+	if _, ok := find(s.prunedUrls, func(p database.PrunedUrl) bool { return p.FeedID == arg.FeedID && p.Url == arg.Url }); ok {
+		return nil
+	}
+
+	s.prunedUrls = append(s.prunedUrls, database.PrunedUrl(arg))
+
+	return nil
+}
+
+func (s *Store) GetPrunedUrlsForFeed(ctx context.Context, feedID uuid.UUID) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var urls []string
+	for _, p := range s.prunedUrls {
+		if p.FeedID == feedID {
+			urls = append(urls, p.Url)
+		}
+	}
+
+	return urls, nil
+}
+
+func (s *Store) DeletePrunedUrl(ctx context.Context, arg database.DeletePrunedUrlParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	remove(&s.prunedUrls, func(p database.PrunedUrl) bool { return p.FeedID == arg.FeedID && p.Url == arg.Url })
+
+	return nil
+}
+
+func (s *Store) GetAllFeedRetention(ctx context.Context) ([]database.FeedRetention, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.retentions, func(database.FeedRetention) bool { return true }), nil
+}
+
+func (s *Store) GetAllPrunedUrls(ctx context.Context) ([]database.PrunedUrl, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.prunedUrls, func(database.PrunedUrl) bool { return true }), nil
+}
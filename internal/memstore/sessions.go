@@ -0,0 +1,91 @@
+package memstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func (s *Store) CreateSession(ctx context.Context, arg database.CreateSessionParams) (database.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return database.Session{}, errForeignKey("sessions", "sessions_user_id_fkey")
+	}
+
+	if _, ok := find(s.sessions, func(v database.Session) bool { return v.TokenHash == arg.TokenHash }); ok {
+		return database.Session{}, errUnique("sessions_token_hash_key")
+	}
+
+	v := database.Session{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UserID:    arg.UserID,
+		TokenHash: arg.TokenHash,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	s.sessions = append(s.sessions, v)
+
+	return v, nil
+}
+
+func (s *Store) GetUserBySession(ctx context.Context, arg database.GetUserBySessionParams) (database.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, err := one(s.sessions, func(v database.Session) bool {
+		return v.TokenHash == arg.TokenHash && v.ExpiresAt.After(arg.ExpiresAt)
+	})
+
+	if err != nil {
+		return database.User{}, err
+	}
+
+	return one(s.users, func(u database.User) bool { return u.ID == session.UserID })
+}
+
+func (s *Store) TouchSession(ctx context.Context, arg database.TouchSessionParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update(s.sessions, func(v database.Session) bool { return v.TokenHash == arg.TokenHash }, func(v *database.Session) {
+		v.LastUsedAt = arg.LastUsedAt
+	})
+
+	return nil
+}
+
+func (s *Store) DeleteSession(ctx context.Context, tokenHash string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(remove(&s.sessions, func(v database.Session) bool { return v.TokenHash == tokenHash }))), nil
+}
+
+func (s *Store) DeleteSessionsForUser(ctx context.Context, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	remove(&s.sessions, func(v database.Session) bool { return v.UserID == userID })
+
+	return nil
+}
+
+func (s *Store) DeleteExpiredSessions(ctx context.Context, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	remove(&s.sessions, func(v database.Session) bool { return !v.ExpiresAt.After(expiresAt) })
+
+	return nil
+}
+
+func (s *Store) GetAllSessions(ctx context.Context) ([]database.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.sessions, func(database.Session) bool { return true }), nil
+}
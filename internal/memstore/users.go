@@ -0,0 +1,119 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func (s *Store) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := find(s.users, func(u database.User) bool { return u.Name == arg.Name }); ok {
+		return database.User{}, errUnique("users_name_key")
+	}
+
+	// the first user to register administers the instance
+	role := "member"
+	if len(s.users) == 0 {
+		role = "admin"
+	}
+
+	u := database.User{
+		ID:           arg.ID,
+		CreatedAt:    arg.CreatedAt,
+		UpdatedAt:    arg.UpdatedAt,
+		Name:         arg.Name,
+		PasswordHash: arg.PasswordHash,
+		Role:         role,
+	}
+	s.users = append(s.users, u)
+
+	return u, nil
+}
+
+func (s *Store) GetUser(ctx context.Context, name string) (database.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return one(s.users, func(u database.User) bool { return u.Name == name })
+}
+
+func (s *Store) GetUserById(ctx context.Context, id uuid.UUID) (database.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return one(s.users, func(u database.User) bool { return u.ID == id })
+}
+
+func (s *Store) GetUsers(ctx context.Context) ([]database.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.users, func(database.User) bool { return true }), nil
+}
+
+func (s *Store) DeleteUsers(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteUsers(func(database.User) bool { return true })
+
+	return nil
+}
+
+func (s *Store) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteUsers(func(u database.User) bool { return u.ID == id }), nil
+}
+
+func (s *Store) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update(s.users, func(u database.User) bool { return u.ID == arg.ID }, func(u *database.User) {
+		u.PasswordHash = arg.PasswordHash
+		u.UpdatedAt = arg.UpdatedAt
+	})
+
+	return nil
+}
+
+func (s *Store) SetUserRole(ctx context.Context, arg database.SetUserRoleParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update(s.users, func(u database.User) bool { return u.ID == arg.ID }, func(u *database.User) {
+		u.Role = arg.Role
+		u.UpdatedAt = arg.UpdatedAt
+	})
+
+	return nil
+}
+
+func (s *Store) CountAdmins(ctx context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(where(s.users, func(u database.User) bool { return u.Role == "admin" }))), nil
+}
+
+func (s *Store) RenameUser(ctx context.Context, arg database.RenameUserParams) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := find(s.users, func(u database.User) bool { return u.Name == arg.Name && u.ID != arg.ID }); ok {
+		return database.User{}, errUnique("users_name_key")
+	}
+
+	update(s.users, func(u database.User) bool { return u.ID == arg.ID }, func(u *database.User) {
+		u.Name = arg.Name
+		u.UpdatedAt = arg.UpdatedAt
+	})
+
+	return one(s.users, func(u database.User) bool { return u.ID == arg.ID })
+}
@@ -0,0 +1,144 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func (s *Store) CreateWebhook(ctx context.Context, arg database.CreateWebhookParams) (database.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(arg.UserID) {
+		return database.Webhook{}, errForeignKey("webhooks", "webhooks_user_id_fkey")
+	}
+
+	if arg.FeedID.Valid && !s.feedExists(arg.FeedID.UUID) {
+		return database.Webhook{}, errForeignKey("webhooks", "webhooks_feed_id_fkey")
+	}
+
+	w := database.Webhook{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		Url:       arg.Url,
+		Secret:    arg.Secret,
+		FeedID:    arg.FeedID,
+		GroupName: arg.GroupName,
+		Tag:       arg.Tag,
+		Template:  arg.Template,
+		Batch:     arg.Batch,
+	}
+	s.webhooks = append(s.webhooks, w)
+
+	return w, nil
+}
+
+func (s *Store) GetWebhooksForUser(ctx context.Context, userID uuid.UUID) ([]database.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := where(s.webhooks, func(w database.Webhook) bool { return w.UserID == userID })
+	slices.SortStableFunc(res, func(a, b database.Webhook) int {
+		return compareTime(a.CreatedAt, b.CreatedAt)
+	})
+
+	return res, nil
+}
+
+func (s *Store) GetWebhookForUser(ctx context.Context, arg database.GetWebhookForUserParams) (database.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return one(s.webhooks, func(w database.Webhook) bool {
+		return w.ID == arg.ID && w.UserID == arg.UserID
+	})
+}
+
+func (s *Store) GetWebhooksForFeed(ctx context.Context, feedID uuid.UUID) ([]database.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var res []database.Webhook
+	for _, ff := range where(s.feedFollows, func(f database.FeedFollow) bool { return f.FeedID == feedID }) {
+		res = append(res, where(s.webhooks, func(w database.Webhook) bool {
+			return w.UserID == ff.UserID &&
+				(!w.FeedID.Valid || w.FeedID.UUID == ff.FeedID) &&
+				(!w.GroupName.Valid || s.inGroup(ff.ID, w.GroupName.String))
+		})...)
+	}
+
+	return res, nil
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, arg database.DeleteWebhookParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteWebhooks(func(w database.Webhook) bool {
+		return w.ID == arg.ID && w.UserID == arg.UserID
+	}), nil
+}
+
+func (s *Store) CreateWebhookDelivery(ctx context.Context, arg database.CreateWebhookDeliveryParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := find(s.webhooks, func(w database.Webhook) bool { return w.ID == arg.WebhookID }); !ok {
+		return errForeignKey("webhook_deliveries", "webhook_deliveries_webhook_id_fkey")
+	}
+
+	s.deliveries = append(s.deliveries, database.WebhookDelivery(arg))
+
+	return nil
+}
+
+func (s *Store) GetWebhookDeliveriesForUser(ctx context.Context, arg database.GetWebhookDeliveriesForUserParams) ([]database.GetWebhookDeliveriesForUserRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var res []database.GetWebhookDeliveriesForUserRow
+	for _, d := range s.deliveries {
+		w, ok := find(s.webhooks, func(w database.Webhook) bool { return w.ID == d.WebhookID })
+		if !ok || w.UserID != arg.UserID {
+			continue
+		}
+
+		res = append(res, database.GetWebhookDeliveriesForUserRow{
+			ID:         d.ID,
+			CreatedAt:  d.CreatedAt,
+			WebhookID:  d.WebhookID,
+			Event:      d.Event,
+			Posts:      d.Posts,
+			Attempts:   d.Attempts,
+			StatusCode: d.StatusCode,
+			Error:      d.Error,
+			Succeeded:  d.Succeeded,
+			Url:        w.Url,
+		})
+	}
+
+	slices.SortStableFunc(res, func(a, b database.GetWebhookDeliveriesForUserRow) int {
+		return compareTime(b.CreatedAt, a.CreatedAt)
+	})
+
+	return limit(res, arg.Limit), nil
+}
+
+func (s *Store) GetAllWebhooks(ctx context.Context) ([]database.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.webhooks, func(database.Webhook) bool { return true }), nil
+}
+
+func (s *Store) GetAllWebhookDeliveries(ctx context.Context) ([]database.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.deliveries, func(database.WebhookDelivery) bool { return true }), nil
+}
@@ -0,0 +1,69 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func (s *Store) CreateWebsubSubscription(ctx context.Context, arg database.CreateWebsubSubscriptionParams) (database.WebsubSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.feedExists(arg.FeedID) {
+		return database.WebsubSubscription{}, errForeignKey("websub_subscriptions", "websub_subscriptions_feed_id_fkey")
+	}
+
+	match := func(w database.WebsubSubscription) bool { return w.FeedID == arg.FeedID }
+
+	// The lease is kept until the hub verifies the new subscription.
+	n := update(s.websubs, match, func(w *database.WebsubSubscription) {
+		w.UpdatedAt = arg.UpdatedAt
+		w.Hub = arg.Hub
+		w.Topic = arg.Topic
+		w.Secret = arg.Secret
+		w.State = arg.State
+	})
+
+	if n == 0 {
+		s.websubs = append(s.websubs, database.WebsubSubscription{
+			FeedID:    arg.FeedID,
+			CreatedAt: arg.CreatedAt,
+			UpdatedAt: arg.UpdatedAt,
+			Hub:       arg.Hub,
+			Topic:     arg.Topic,
+			Secret:    arg.Secret,
+			State:     arg.State,
+		})
+	}
+
+	return one(s.websubs, match)
+}
+
+func (s *Store) GetWebsubSubscription(ctx context.Context, feedID uuid.UUID) (database.WebsubSubscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return one(s.websubs, func(w database.WebsubSubscription) bool { return w.FeedID == feedID })
+}
+
+func (s *Store) SetWebsubState(ctx context.Context, arg database.SetWebsubStateParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update(s.websubs, func(w database.WebsubSubscription) bool { return w.FeedID == arg.FeedID }, func(w *database.WebsubSubscription) {
+		w.State = arg.State
+		w.LeaseExpiresAt = arg.LeaseExpiresAt
+		w.UpdatedAt = arg.UpdatedAt
+	})
+
+	return nil
+}
+
+func (s *Store) GetAllWebsubSubscriptions(ctx context.Context) ([]database.WebsubSubscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return where(s.websubs, func(database.WebsubSubscription) bool { return true }), nil
+}
@@ -0,0 +1,236 @@
+// Package metrics keeps counters, histograms and gauges and serves them in
+// the Prometheus text format. It covers what the aggregator exports, not the
+// whole client library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds metrics in the order they were added.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []*metric
+}
+
+type metric struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+	gauge   func() (float64, error)
+	series  map[string]*series
+}
+
+// series is one combination of label values of a metric. Counters use
+// value; histograms count observations per bucket, not cumulatively.
+type series struct {
+	labels []string
+	value  float64
+	counts []uint64
+	count  uint64
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) add(m *metric) *metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m.series = map[string]*series{}
+	r.metrics = append(r.metrics, m)
+
+	// a metric without labels has its one series from the start, so it
+	// reads zero rather than missing
+	if len(m.labels) == 0 && m.gauge == nil {
+		m.get(nil).counts = make([]uint64, len(m.buckets))
+	}
+
+	return m
+}
+
+// Counter only goes up.
+type Counter struct {
+	r *Registry
+	m *metric
+}
+
+// Counter adds a counter with the given label names.
+func (r *Registry) Counter(name, help string, labels ...string) Counter {
+	return Counter{r, r.add(&metric{name: name, help: help, kind: "counter", labels: labels})}
+}
+
+// Add adds v to the series with the given label values.
+func (c Counter) Add(v float64, labels ...string) {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+
+	c.m.get(labels).value += v
+}
+
+func (c Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	r *Registry
+	m *metric
+}
+
+// Histogram adds a histogram with the given upper bounds, in increasing
+// order, and label names.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) Histogram {
+	return Histogram{r, r.add(&metric{name: name, help: help, kind: "histogram", labels: labels, buckets: buckets})}
+}
+
+func (h Histogram) Observe(v float64, labels ...string) {
+	h.r.mu.Lock()
+	defer h.r.mu.Unlock()
+
+	s := h.m.get(labels)
+	if len(s.counts) < len(h.m.buckets) {
+		s.counts = make([]uint64, len(h.m.buckets))
+	}
+
+	if i, _ := slices.BinarySearch(h.m.buckets, v); i < len(s.counts) {
+		s.counts[i]++
+	}
+	s.value += v
+	s.count++
+}
+
+// GaugeFunc adds a gauge whose value fn works out each time the metrics are
+// read. The gauge is left out when fn fails.
+func (r *Registry) GaugeFunc(name, help string, fn func() (float64, error)) {
+	r.add(&metric{name: name, help: help, kind: "gauge", gauge: fn})
+}
+
+func (m *metric) get(labels []string) *series {
+	if len(labels) != len(m.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", m.name, len(m.labels), len(labels)))
+	}
+
+	key := strings.Join(labels, "\xff")
+
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{labels: slices.Clone(labels)}
+		m.series[key] = s
+	}
+
+	return s
+}
+
+// WriteTo writes every metric in the Prometheus text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	metrics := slices.Clone(r.metrics)
+	r.mu.Unlock()
+
+	var b strings.Builder
+
+	for _, m := range metrics {
+		if m.gauge != nil {
+			// fn may be slow, so it runs without holding the lock
+			v, err := m.gauge()
+			if err != nil {
+				continue
+			}
+
+			writeHeader(&b, m)
+			fmt.Fprintf(&b, "%s %s\n", m.name, formatValue(v))
+			continue
+		}
+
+		r.mu.Lock()
+		writeHeader(&b, m)
+		m.write(&b)
+		r.mu.Unlock()
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func writeHeader(b *strings.Builder, m *metric) {
+	fmt.Fprintf(b, "# HELP %s %s\n", m.name, helpEscaper.Replace(m.help))
+	fmt.Fprintf(b, "# TYPE %s %s\n", m.name, m.kind)
+}
+
+func (m *metric) write(b *strings.Builder) {
+	keys := make([]string, 0, len(m.series))
+	for k := range m.series {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		s := m.series[k]
+
+		if m.kind == "counter" {
+			fmt.Fprintf(b, "%s%s %s\n", m.name, formatLabels(m.labels, s.labels, "", ""), formatValue(s.value))
+			continue
+		}
+
+		var cumulative uint64
+		for i, upper := range m.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(b, "%s_bucket%s %d\n", m.name, formatLabels(m.labels, s.labels, "le", formatValue(upper)), cumulative)
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", m.name, formatLabels(m.labels, s.labels, "le", "+Inf"), s.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", m.name, formatLabels(m.labels, s.labels, "", ""), formatValue(s.value))
+		fmt.Fprintf(b, "%s_count%s %d\n", m.name, formatLabels(m.labels, s.labels, "", ""), s.count)
+	}
+}
+
+// formatLabels writes names and values as {name="value",...}, with the
+// extra label after them when it isn't empty.
+func formatLabels(names, values []string, extra, extraValue string) string {
+	var pairs []string
+
+	for i, name := range names {
+		pairs = append(pairs, name+`="`+labelEscaper.Replace(values[i])+`"`)
+	}
+
+	if extra != "" {
+		pairs = append(pairs, extra+`="`+extraValue+`"`)
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// ServeHTTP serves the metrics to a Prometheus scrape.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
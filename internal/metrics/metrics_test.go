@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"errors"
+	"math"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeHTTP(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.Counter("test_requests_total", "Requests by path.", "path")
+	plain := r.Counter("test_plain_total", "Help with a \\ and a\nnewline.")
+	duration := r.Histogram("test_duration_seconds", "How long it took.", []float64{0.125, 1})
+	size := r.Histogram("test_size_bytes", "Sizes by kind.", []float64{10}, "kind")
+	r.Histogram("test_unused_bytes", "Never observed.", []float64{10}, "kind")
+	r.GaugeFunc("test_up", "Always up.", func() (float64, error) { return 1, nil })
+	r.GaugeFunc("test_broken", "Always fails.", func() (float64, error) { return 0, errors.New("database is down") })
+	r.GaugeFunc("test_inf", "Infinite.", func() (float64, error) { return math.Inf(1), nil })
+
+	requests.Inc(`/a"b\c` + "\n")
+	requests.Add(2.5, "/")
+	plain.Inc()
+
+	for _, v := range []float64{0.0625, 0.125, 0.5, 3} {
+		duration.Observe(v)
+	}
+
+	size.Observe(5, "b")
+	size.Observe(20, "a")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	if got := w.Header().Get("Content-Type"); got != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Content-Type is %q", got)
+	}
+
+	// series are sorted by label values, buckets count every observation up
+	// to their bound, and the broken gauge is left out
+	want := `# HELP test_requests_total Requests by path.
+# TYPE test_requests_total counter
+test_requests_total{path="/"} 2.5
+test_requests_total{path="/a\"b\\c\n"} 1
+# HELP test_plain_total Help with a \\ and a\nnewline.
+# TYPE test_plain_total counter
+test_plain_total 1
+# HELP test_duration_seconds How long it took.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="0.125"} 2
+test_duration_seconds_bucket{le="1"} 3
+test_duration_seconds_bucket{le="+Inf"} 4
+test_duration_seconds_sum 3.6875
+test_duration_seconds_count 4
+# HELP test_size_bytes Sizes by kind.
+# TYPE test_size_bytes histogram
+test_size_bytes_bucket{kind="a",le="10"} 0
+test_size_bytes_bucket{kind="a",le="+Inf"} 1
+test_size_bytes_sum{kind="a"} 20
+test_size_bytes_count{kind="a"} 1
+test_size_bytes_bucket{kind="b",le="10"} 1
+test_size_bytes_bucket{kind="b",le="+Inf"} 1
+test_size_bytes_sum{kind="b"} 5
+test_size_bytes_count{kind="b"} 1
+# HELP test_unused_bytes Never observed.
+# TYPE test_unused_bytes histogram
+# HELP test_up Always up.
+# TYPE test_up gauge
+test_up 1
+# HELP test_inf Infinite.
+# TYPE test_inf gauge
+test_inf +Inf
+`
+
+	if got := w.Body.String(); got != want {
+		t.Errorf("exposition is\n%s\nwant\n%s", got, want)
+	}
+}
+
+// A metric without labels reads zero before anything is counted.
+func TestUnlabelledStartAtZero(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("test_total", "A counter.")
+	r.Histogram("test_seconds", "A histogram.", []float64{1})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	want := `# HELP test_total A counter.
+# TYPE test_total counter
+test_total 0
+# HELP test_seconds A histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{le="1"} 0
+test_seconds_bucket{le="+Inf"} 0
+test_seconds_sum 0
+test_seconds_count 0
+`
+
+	if got := w.Body.String(); got != want {
+		t.Errorf("exposition is\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWrongLabelCountPanics(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("test_total", "A counter.", "result")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("counting without the label value didn't panic")
+		}
+	}()
+
+	c.Inc()
+}
@@ -0,0 +1,404 @@
+// Package migrate applies goose style migrations from an fs.FS and keeps
+// track of them in goose's goose_db_version table, so databases migrated
+// with either tool stay compatible.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const versionTable = "goose_db_version"
+
+// Dialect holds the SQL the version table needs on one kind of database.
+type Dialect struct {
+	Name string
+
+	// createVersionTable creates the same table goose does.
+	createVersionTable string
+	// tableExists counts the tables named $1.
+	tableExists string
+}
+
+var (
+	Postgres = Dialect{
+		Name: "postgres",
+		createVersionTable: `CREATE TABLE ` + versionTable + ` (
+    id SERIAL PRIMARY KEY,
+    version_id BIGINT NOT NULL,
+    is_applied BOOLEAN NOT NULL,
+    tstamp TIMESTAMP DEFAULT NOW()
+)`,
+		tableExists: "SELECT COUNT(*) FROM information_schema.tables WHERE table_name = $1",
+	}
+
+	SQLite = Dialect{
+		Name: "sqlite",
+		createVersionTable: `CREATE TABLE ` + versionTable + ` (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version_id INTEGER NOT NULL,
+    is_applied INTEGER NOT NULL,
+    tstamp TIMESTAMP DEFAULT (datetime('now'))
+)`,
+		tableExists: "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = $1",
+	}
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// NoTx is set by -- +goose NO TRANSACTION, for migrations that manage
+	// their own transactions.
+	NoTx bool
+}
+
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Load reads every NNN_name.sql migration in fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+
+	for _, f := range files {
+		prefix, _, ok := strings.Cut(f, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s has no version prefix", f)
+		}
+
+		version, err := strconv.ParseInt(prefix, 10, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has an invalid version: %w", f, err)
+		}
+
+		b, err := fs.ReadFile(fsys, f)
+
+		if err != nil {
+			return nil, err
+		}
+
+		up, down, noTx, err := parse(string(b))
+
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", f, err)
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    path.Base(f),
+			Up:      up,
+			Down:    down,
+			NoTx:    noTx,
+		})
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return int(a.Version - b.Version)
+	})
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", migrations[i-1].Name, migrations[i].Name, migrations[i].Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+// parse splits a migration into its -- +goose Up and -- +goose Down sections
+// and reports whether it is marked -- +goose NO TRANSACTION.
+func parse(src string) (up, down string, noTx bool, err error) {
+	var section *strings.Builder
+	var upSQL, downSQL strings.Builder
+
+	for _, line := range strings.SplitAfter(src, "\n") {
+		switch strings.TrimSpace(line) {
+		case "-- +goose Up":
+			section = &upSQL
+			continue
+		case "-- +goose Down":
+			section = &downSQL
+			continue
+		case "-- +goose StatementBegin", "-- +goose StatementEnd":
+			continue
+		case "-- +goose NO TRANSACTION":
+			noTx = true
+			continue
+		}
+
+		if section != nil {
+			section.WriteString(line)
+		}
+	}
+
+	if section == nil {
+		return "", "", false, errors.New("missing -- +goose Up annotation")
+	}
+
+	return strings.TrimSpace(upSQL.String()), strings.TrimSpace(downSQL.String()), noTx, nil
+}
+
+// Latest returns the highest version in migrations.
+func Latest(migrations []Migration) int64 {
+	if len(migrations) == 0 {
+		return 0
+	}
+
+	return migrations[len(migrations)-1].Version
+}
+
+// Version returns the version the database is migrated to, 0 when it has
+// never been migrated.
+func Version(ctx context.Context, db *sql.DB, d Dialect) (int64, error) {
+	applied, err := appliedVersions(ctx, db, d)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var version int64
+	for v := range applied {
+		version = max(version, v)
+	}
+
+	return version, nil
+}
+
+// Up applies every pending migration up to and including target.
+func Up(ctx context.Context, db *sql.DB, d Dialect, migrations []Migration, target int64, applied func(Migration)) error {
+	err := ensureVersionTable(ctx, db, d)
+
+	if err != nil {
+		return err
+	}
+
+	done, err := appliedVersions(ctx, db, d)
+
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.Version > target {
+			break
+		}
+
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+
+		err = run(ctx, db, m.NoTx, m.Up,
+			fmt.Sprintf("INSERT INTO %s (version_id, is_applied) VALUES ($1, TRUE)", versionTable),
+			m.Version)
+
+		if err != nil {
+			return fmt.Errorf("migration %s failed: %w", m.Name, err)
+		}
+
+		applied(m)
+	}
+
+	return nil
+}
+
+// Down rolls back applied migrations, newest first, until only those up to
+// and including target remain.
+func Down(ctx context.Context, db *sql.DB, d Dialect, migrations []Migration, target int64, rolledBack func(Migration)) error {
+	done, err := appliedVersions(ctx, db, d)
+
+	if err != nil {
+		return err
+	}
+
+	for _, m := range slices.Backward(migrations) {
+		if m.Version <= target {
+			break
+		}
+
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+
+		err = run(ctx, db, m.NoTx, m.Down,
+			fmt.Sprintf("DELETE FROM %s WHERE version_id = $1", versionTable),
+			m.Version)
+
+		if err != nil {
+			return fmt.Errorf("rolling back %s failed: %w", m.Name, err)
+		}
+
+		rolledBack(m)
+	}
+
+	return nil
+}
+
+// Statuses reports when each migration was applied, if it was.
+func Statuses(ctx context.Context, db *sql.DB, d Dialect, migrations []Migration) ([]Status, error) {
+	done, err := appliedVersions(ctx, db, d)
+
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		st := Status{Migration: m}
+		if t, ok := done[m.Version]; ok {
+			st.AppliedAt = &t
+		}
+		res = append(res, st)
+	}
+
+	return res, nil
+}
+
+// run executes a migration and records it in the version table within one
+// transaction, unless noTx is set.
+func run(ctx context.Context, db *sql.DB, noTx bool, statements, record string, version int64) error {
+	if noTx {
+		return runNoTx(ctx, db, statements, record, version)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if statements != "" {
+		_, err = tx.ExecContext(ctx, statements)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, record, version)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// runNoTx runs the statements on a connection of their own, as they may
+// change settings of the connection they run on. A connection they fail on
+// is closed rather than reused, since they may have left a transaction open
+// or a setting changed.
+func runNoTx(ctx context.Context, db *sql.DB, statements, record string, version int64) error {
+	conn, err := db.Conn(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	if statements != "" {
+		_, err = conn.ExecContext(ctx, statements)
+
+		if err != nil {
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+			return err
+		}
+	}
+
+	_, err = conn.ExecContext(ctx, record, version)
+
+	return err
+}
+
+func ensureVersionTable(ctx context.Context, db *sql.DB, d Dialect) error {
+	exists, err := versionTableExists(ctx, db, d)
+
+	if err != nil || exists {
+		return err
+	}
+
+	// the same table, and initial row, goose creates
+	return run(ctx, db, false, d.createVersionTable,
+		fmt.Sprintf("INSERT INTO %s (version_id, is_applied) VALUES ($1, TRUE)", versionTable),
+		0)
+}
+
+func versionTableExists(ctx context.Context, db *sql.DB, d Dialect) (bool, error) {
+	var n int
+
+	err := db.QueryRowContext(ctx, d.tableExists, versionTable).Scan(&n)
+
+	if err != nil {
+		return false, fmt.Errorf("failed checking for %s: %w", versionTable, err)
+	}
+
+	return n > 0, nil
+}
+
+// appliedVersions returns the applied migrations and when they were
+// applied. Like goose, a version counts as applied when its newest row
+// says so; older goose versions recorded rollbacks as is_applied false
+// rather than deleting the row.
+func appliedVersions(ctx context.Context, db *sql.DB, d Dialect) (map[int64]time.Time, error) {
+	exists, err := versionTableExists(ctx, db, d)
+
+	if err != nil || !exists {
+		return map[int64]time.Time{}, err
+	}
+
+	rows, err := db.QueryContext(ctx,
+		fmt.Sprintf("SELECT version_id, is_applied, tstamp FROM %s ORDER BY id DESC", versionTable))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed reading %s: %w", versionTable, err)
+	}
+
+	defer rows.Close()
+
+	seen := make(map[int64]bool)
+	applied := make(map[int64]time.Time)
+
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		var tstamp sql.NullTime
+
+		err = rows.Scan(&version, &isApplied, &tstamp)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if seen[version] {
+			continue
+		}
+		seen[version] = true
+
+		if isApplied && version > 0 {
+			applied[version] = tstamp.Time
+		}
+	}
+
+	return applied, rows.Err()
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/w0/aggregator/internal/database"
+	"github.com/w0/aggregator/internal/migrate"
+	"github.com/w0/aggregator/sql/schema"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteOptions are applied to every connection. Foreign keys are off by
+// default in SQLite and the schema relies on them cascading deletes; WAL,
+// the busy timeout and immediate transactions let agg, serve and other
+// commands use the file at the same time.
+const sqliteOptions = "_pragma=foreign_keys(1)&_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+
+// sqliteTimeFormat stores times like a PostgreSQL TIMESTAMP column does, as
+// the wall clock without a zone, and sorts correctly as text.
+const sqliteTimeFormat = "2006-01-02 15:04:05.999999999"
+
+func openSQLite(path string) (*DB, error) {
+	if path == "" {
+		return nil, errors.New("sqlite:// url needs the path of the database file")
+	}
+
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+
+		if err != nil {
+			return nil, err
+		}
+
+		path = filepath.Join(home, rest)
+	}
+
+	db, err := sql.Open("sqlite", path+"?"+sqliteOptions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DB{
+		DB:         db,
+		Dialect:    migrate.SQLite,
+		Migrations: schema.SQLite(),
+		Store: store{
+			Queries: database.New(sqliteDB{db}),
+			db:      db,
+			wrap:    func(tx *sql.Tx) database.DBTX { return sqliteDB{tx} },
+		},
+	}, nil
+}
+
+// sqliteDB formats time arguments with sqliteTimeFormat. The driver's own
+// format is time.Time.String, which includes the zone and so doesn't
+// compare correctly with the times the queries filter and sort by.
+type sqliteDB struct {
+	database.DBTX
+}
+
+func (db sqliteDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.DBTX.ExecContext(ctx, query, sqliteArgs(args)...)
+}
+
+func (db sqliteDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return db.DBTX.QueryContext(ctx, query, sqliteArgs(args)...)
+}
+
+func (db sqliteDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.DBTX.QueryRowContext(ctx, query, sqliteArgs(args)...)
+}
+
+func sqliteArgs(args []any) []any {
+	res := make([]any, len(args))
+
+	for i, v := range args {
+		switch v := v.(type) {
+		case time.Time:
+			res[i] = v.Format(sqliteTimeFormat)
+		case sql.NullTime:
+			if v.Valid {
+				res[i] = v.Time.Format(sqliteTimeFormat)
+			}
+		default:
+			res[i] = v
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,309 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/w0/aggregator/internal/migrate"
+	"github.com/w0/aggregator/sql/schema"
+)
+
+func openTestSQLite(t *testing.T) (*DB, []migrate.Migration) {
+	t.Helper()
+
+	db, err := Open("sqlite://" + filepath.Join(t.TempDir(), "gator.db"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := migrate.Load(db.Migrations)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db, migrations
+}
+
+func migrateTo(t *testing.T, db *DB, migrations []migrate.Migration, version int64) {
+	t.Helper()
+
+	ctx := context.Background()
+	current, err := migrate.Version(ctx, db.DB, db.Dialect)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version >= current {
+		err = migrate.Up(ctx, db.DB, db.Dialect, migrations, version, func(migrate.Migration) {})
+	} else {
+		err = migrate.Down(ctx, db.DB, db.Dialect, migrations, version, func(migrate.Migration) {})
+	}
+
+	if err != nil {
+		t.Fatalf("migrating to %d: %v", version, err)
+	}
+}
+
+// describeSchema lists every table's columns, foreign keys and unique
+// indexes in a form that doesn't depend on how the table came to be.
+func describeSchema(t *testing.T, db *DB) string {
+	t.Helper()
+
+	var tables []string
+
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'goose_db_version' ORDER BY name`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, name)
+	}
+
+	rows.Close()
+
+	var b strings.Builder
+
+	for _, table := range tables {
+		fmt.Fprintf(&b, "%s\n", table)
+
+		for _, q := range []string{
+			"SELECT name, type, \"notnull\", COALESCE(dflt_value, ''), pk FROM pragma_table_info(?) ORDER BY cid",
+			"SELECT \"table\", \"from\", \"to\", on_delete, '', '' FROM pragma_foreign_key_list(?) ORDER BY \"from\"",
+			"SELECT (SELECT group_concat(name) FROM pragma_index_info(il.name)), \"unique\", '', '', '' FROM pragma_index_list(?) il WHERE \"unique\"",
+		} {
+			rows, err := db.Query(q, table)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var lines []string
+
+			for rows.Next() {
+				cols := make([]any, 5)
+				vals := make([]string, 5)
+				for i := range cols {
+					cols[i] = &vals[i]
+				}
+
+				// foreign keys have one more column than the others
+				if strings.Contains(q, "foreign_key_list") {
+					var extra string
+					cols = append(cols, &extra)
+				}
+
+				if err := rows.Scan(cols...); err != nil {
+					t.Fatal(err)
+				}
+
+				lines = append(lines, "  "+strings.Join(vals, " "))
+			}
+
+			rows.Close()
+
+			slices.Sort(lines)
+			b.WriteString(strings.Join(lines, "\n") + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+// columns lists every table.column in the database.
+func columns(t *testing.T, db *DB) map[string]bool {
+	t.Helper()
+
+	rows, err := db.Query(`SELECT m.name || '.' || c.name FROM sqlite_master m, pragma_table_info(m.name) c WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%' AND m.name != 'goose_db_version'`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rows.Close()
+
+	res := map[string]bool{}
+
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			t.Fatal(err)
+		}
+		res[col] = true
+	}
+
+	return res
+}
+
+// Every column a SQLite migration adds is one the PostgreSQL migration of
+// the same version adds, so both databases have the same columns at every
+// version.
+func TestSQLiteMigrationsMirrorPostgres(t *testing.T) {
+	db, migrations := openTestSQLite(t)
+
+	pg, err := migrate.Load(schema.FS)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pg) != len(migrations) {
+		t.Fatalf("%d PostgreSQL migrations but %d SQLite ones", len(pg), len(migrations))
+	}
+
+	before := columns(t, db)
+
+	for i, m := range migrations {
+		if pg[i].Version != m.Version {
+			t.Fatalf("PostgreSQL has %s where SQLite has %s", pg[i].Name, m.Name)
+		}
+
+		migrateTo(t, db, migrations, m.Version)
+		after := columns(t, db)
+		words := regexp.MustCompile(`\w+`).FindAllString(pg[i].Up, -1)
+
+		for col := range after {
+			if before[col] {
+				continue
+			}
+
+			table, name, _ := strings.Cut(col, ".")
+
+			if !slices.Contains(words, table) || !slices.Contains(words, name) {
+				t.Errorf("%s adds %s, which the PostgreSQL %s doesn't", m.Name, col, pg[i].Name)
+			}
+		}
+
+		before = after
+	}
+}
+
+// Rolling back to a version gives the same schema as migrating a new
+// database up to it, so every SQLite migration's down undoes its up.
+func TestSQLiteMigrationsRollBack(t *testing.T) {
+	db, migrations := openTestSQLite(t)
+	latest := migrate.Latest(migrations)
+
+	want := map[int64]string{}
+
+	for _, m := range migrations {
+		migrateTo(t, db, migrations, m.Version)
+		want[m.Version] = describeSchema(t, db)
+	}
+
+	for _, m := range slices.Backward(migrations[:len(migrations)-1]) {
+		migrateTo(t, db, migrations, m.Version)
+
+		if got := describeSchema(t, db); got != want[m.Version] {
+			t.Errorf("rolling back from %d to %d gives\n%s\nwant\n%s", latest, m.Version, got, want[m.Version])
+		}
+	}
+
+	migrateTo(t, db, migrations, 0)
+
+	if got := describeSchema(t, db); got != "" {
+		t.Errorf("rolling back everything leaves\n%s", got)
+	}
+}
+
+// 011 recreates feeds and posts to add seq; what references them has to
+// survive that.
+func TestSQLiteSeqMigration(t *testing.T) {
+	db, migrations := openTestSQLite(t)
+	ctx := context.Background()
+
+	// foreign_keys is a setting of each connection
+	db.SetMaxOpenConns(1)
+
+	migrateTo(t, db, migrations, 10)
+
+	for _, q := range []string{
+		`INSERT INTO users (id, created_at, updated_at, name) VALUES ('u1', '2024-01-01 00:00:00', '2024-01-01 00:00:00', 'alice')`,
+		`INSERT INTO feeds (id, created_at, updated_at, name, url, user_id) VALUES ('f2', '2024-01-02 00:00:00', '2024-01-02 00:00:00', 'two', 'https://example.com/2', 'u1')`,
+		`INSERT INTO feeds (id, created_at, updated_at, name, url, user_id) VALUES ('f1', '2024-01-01 00:00:00', '2024-01-01 00:00:00', 'one', 'https://example.com/1', 'u1')`,
+		`INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id) VALUES ('ff1', '2024-01-01 00:00:00', '2024-01-01 00:00:00', 'u1', 'f1')`,
+		`INSERT INTO posts (id, created_at, updated_at, title, url, feed_id) VALUES ('p1', '2024-01-01 00:00:00', '2024-01-01 00:00:00', 'post', 'https://example.com/1/p', 'f1')`,
+		`INSERT INTO post_states (id, created_at, updated_at, user_id, post_id, read) VALUES ('ps1', '2024-01-01 00:00:00', '2024-01-01 00:00:00', 'u1', 'p1', TRUE)`,
+	} {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	migrateTo(t, db, migrations, migrate.Latest(migrations))
+
+	var seqs []string
+
+	rows, err := db.QueryContext(ctx, `SELECT id || ':' || seq FROM feeds ORDER BY seq`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		seqs = append(seqs, s)
+	}
+
+	rows.Close()
+
+	// numbered in the order the feeds were added
+	if got := strings.Join(seqs, " "); got != "f1:1 f2:2" {
+		t.Errorf("feeds are numbered %s, want f1:1 f2:2", got)
+	}
+
+	for _, table := range []string{"posts", "feed_follows", "post_states"} {
+		var n int
+
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+
+		if n != 1 {
+			t.Errorf("%s has %d rows after migrating, want 1", table, n)
+		}
+	}
+
+	var fk int
+
+	if err := db.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&fk); err != nil {
+		t.Fatal(err)
+	}
+
+	if fk != 1 {
+		t.Error("foreign keys are still off after migrating")
+	}
+
+	// and deletes cascade through the new tables
+	if _, err := db.ExecContext(ctx, `DELETE FROM feeds WHERE id = 'f1'`); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM post_states").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 0 {
+		t.Error("deleting a feed didn't cascade to the states of its posts")
+	}
+}
@@ -0,0 +1,87 @@
+// Package storage opens the database a db_url names. postgres:// and
+// postgresql:// urls, or any other connection string lib/pq accepts,
+// connect to PostgreSQL. sqlite:// urls open a SQLite file, which needs no
+// server and suits single user installs.
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"strings"
+
+	_ "github.com/lib/pq"
+	"github.com/w0/aggregator/internal/database"
+	"github.com/w0/aggregator/internal/migrate"
+	"github.com/w0/aggregator/sql/schema"
+)
+
+const sqliteScheme = "sqlite://"
+
+// DB is an open database along with the store and migrations for it.
+type DB struct {
+	*sql.DB
+	Dialect    migrate.Dialect
+	Migrations fs.FS
+	Store      database.Store
+}
+
+func Open(dbURL string) (*DB, error) {
+	if path, ok := strings.CutPrefix(dbURL, sqliteScheme); ok {
+		return openSQLite(path)
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DB{
+		DB:         db,
+		Dialect:    migrate.Postgres,
+		Migrations: schema.FS,
+		Store:      store{Queries: database.New(db), db: db},
+	}, nil
+}
+
+// store is a database.Store on top of db. Transactions use wrap, when it is
+// set, to put the same layer between the queries and the transaction as
+// Queries has between them and db.
+type store struct {
+	*database.Queries
+	db   *sql.DB
+	wrap func(*sql.Tx) database.DBTX
+}
+
+func (s store) InTx(ctx context.Context, fn func(database.Store) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	q := s.Queries.WithTx(tx)
+	if s.wrap != nil {
+		q = database.New(s.wrap(tx))
+	}
+
+	err = fn(txStore{q})
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// txStore is a store inside a transaction.
+type txStore struct {
+	*database.Queries
+}
+
+func (s txStore) InTx(ctx context.Context, fn func(database.Store) error) error {
+	return fn(s)
+}
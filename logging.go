@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/w0/aggregator/internal/config"
+	"github.com/w0/aggregator/internal/database"
+)
+
+// newLogger builds the logger for the log settings in the config, with
+// --quiet raising the level to warn and --verbose lowering it to debug.
+// Logs go to stderr, so they stay apart from the output of commands.
+func newLogger(cfg *config.Log, quiet, verbose bool) (*slog.Logger, error) {
+	level := slog.LevelInfo
+	format := "text"
+
+	if cfg != nil {
+		if cfg.Level != "" {
+			if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+				return nil, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", cfg.Level)
+			}
+		}
+
+		if cfg.Format != "" {
+			format = strings.ToLower(cfg.Format)
+		}
+	}
+
+	switch {
+	case quiet:
+		level = slog.LevelWarn
+	case verbose:
+		level = slog.LevelDebug
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected text or json", cfg.Format)
+	}
+}
+
+// feedLogger returns the logger for fetch and save events of feed.
+// creator_id is the id of the user who added the feed, which need not be
+// anyone the event concerns.
+func feedLogger(feed database.Feed) *slog.Logger {
+	return slog.With("feed_id", feed.ID, "feed_url", feed.Url, "creator_id", feed.UserID)
+}
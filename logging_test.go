@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func TestFeedLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	saved := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(saved) })
+
+	feed := database.Feed{ID: uuid.New(), Url: "https://example.com/feed", UserID: uuid.New()}
+	feedLogger(feed).Info("fetched feed")
+
+	var got map[string]any
+
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("%v\n%s", err, buf.String())
+	}
+
+	if got["feed_id"] != feed.ID.String() || got["feed_url"] != feed.Url || got["creator_id"] != feed.UserID.String() {
+		t.Errorf("logged %v", got)
+	}
+
+	if _, ok := got["user"]; ok {
+		t.Errorf("logged the feed's creator as the user: %v", got)
+	}
+}
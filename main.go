@@ -1,67 +1,135 @@
 package main
 
 import (
-	"database/sql"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 
-	_ "github.com/lib/pq"
 	"github.com/w0/aggregator/internal/config"
-	"github.com/w0/aggregator/internal/database"
+	"github.com/w0/aggregator/internal/storage"
 )
 
+const mainUsage = `usage: aggregator [--quiet||--verbose] command <arguments>`
+
 func main() {
+	fs := flag.NewFlagSet("aggregator", flag.ContinueOnError)
+	quiet := fs.Bool("quiet", false, "only log warnings and errors")
+	verbose := fs.Bool("verbose", false, "log debug messages as well")
+
+	if err := fs.Parse(os.Args[1:]); err != nil || (*quiet && *verbose) {
+		fmt.Fprintln(os.Stderr, mainUsage)
+		os.Exit(2)
+	}
+
 	cfg, err := config.Read()
 	if err != nil {
-		log.Fatalf("Error reading config: %v", err)
+		fatal("failed reading config", err)
 	}
 
-	db, err := sql.Open("postgres", cfg.DbURL)
+	logger, err := newLogger(cfg.Log, *quiet, *verbose)
 	if err != nil {
-		log.Fatalf("Error opening database: %v", err)
+		fatal("invalid log settings in .gatorconfig.json", err)
 	}
 
-	dbQueries := database.New(db)
+	slog.SetDefault(logger)
 
-	s := state{
-		cfg: &cfg,
-		db:  dbQueries,
+	hosts, err := newHostLimiter(cfg.Fetch)
+	if err != nil {
+		fatal("invalid fetch settings in .gatorconfig.json", err)
 	}
 
-	cmds := commands{
-		cmds: make(map[string]func(*state, command) error),
+	client, err := newFeedClient(cfg.Fetch)
+	if err != nil {
+		fatal("invalid fetch settings in .gatorconfig.json", err)
 	}
 
-	cmds.register("login", handlerLogin)
-	cmds.register("register", handlerRegister)
-	cmds.register("reset", handlerReset)
-	cmds.register("users", handlerUsers)
-	cmds.register("agg", handlerAgg)
-	cmds.register("addfeed", middlewareLoggedIn(handlerAddFeed))
-	cmds.register("feeds", handlerFeeds)
-	cmds.register("follow", middlewareLoggedIn(handlerFollow))
-	cmds.register("following", middlewareLoggedIn(handlerFollowing))
-	cmds.register("unfollow", middlewareLoggedIn(handlerUnfollow))
-	cmds.register("browse", middlewareLoggedIn(handlerBrowse))
+	db, err := storage.Open(cfg.DbURL)
+	if err != nil {
+		fatal("failed opening database", err)
+	}
+
+	s := state{
+		cfg:      &cfg,
+		db:       db.Store,
+		conn:     db,
+		hosts:    hosts,
+		client:   client,
+		webhooks: newWebhookQueue(),
+	}
 
-	if len(os.Args) < 2 {
+	cmds := newCommands()
+
+	if fs.NArg() == 0 {
 		fmt.Println(usage(cmds.cmds))
 		return
 	}
 
 	cmd := command{
-		name:      os.Args[1],
-		arguments: os.Args[2:],
+		name:      fs.Arg(0),
+		arguments: fs.Args()[1:],
 	}
 
 	if _, ok := cmds.cmds[cmd.name]; !ok {
-		log.Fatal(usage(cmds.cmds))
+		fmt.Fprint(os.Stderr, usage(cmds.cmds))
+		os.Exit(1)
+	}
+
+	if cmd.name != "migrate" {
+		err = checkSchema(db)
+
+		if err != nil {
+			fatal("failed checking database schema", err)
+		}
 	}
 
 	err = cmds.run(&s, cmd)
 
 	if err != nil {
-		log.Fatalf("Command %s failed. %v", os.Args[1], err)
+		slog.Error("command failed", "command", cmd.name, "error", err)
+		os.Exit(1)
+	}
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// newCommands registers every command the CLI knows.
+func newCommands() commands {
+	cmds := commands{
+		cmds: make(map[string]func(*state, command) error),
 	}
+
+	cmds.register("login", handlerLogin)
+	cmds.register("register", handlerRegister)
+	cmds.register("reset", middlewareAdmin(handlerReset))
+	cmds.register("users", middlewareAdmin(handlerUsers))
+	cmds.register("agg", handlerAgg)
+	cmds.register("addfeed", middlewareLoggedIn(handlerAddFeed))
+	cmds.register("feeds", handlerFeeds)
+	cmds.register("feed", middlewareLoggedIn(handlerFeed))
+	cmds.register("follow", middlewareLoggedIn(handlerFollow))
+	cmds.register("following", middlewareLoggedIn(handlerFollowing))
+	cmds.register("unfollow", middlewareLoggedIn(handlerUnfollow))
+	cmds.register("browse", middlewareLoggedIn(handlerBrowse))
+	cmds.register("group", middlewareLoggedIn(handlerGroup))
+	cmds.register("opml", middlewareLoggedIn(handlerOPML))
+	cmds.register("filter", middlewareLoggedIn(handlerFilter))
+	cmds.register("token", middlewareLoggedIn(handlerToken))
+	cmds.register("webhook", middlewareLoggedIn(handlerWebhook))
+	cmds.register("digest", handlerDigest)
+	cmds.register("logout", handlerLogout)
+	cmds.register("user", middlewareLoggedIn(handlerUser))
+	cmds.register("passwd", middlewareLoggedIn(handlerPasswd))
+	cmds.register("promote", middlewareAdmin(handlerPromote))
+	cmds.register("demote", middlewareAdmin(handlerDemote))
+	cmds.register("serve", handlerServe)
+	cmds.register("publish", middlewareLoggedIn(handlerPublish))
+	cmds.register("migrate", handlerMigrate)
+	cmds.register("prune", middlewareAdmin(handlerPrune))
+	cmds.register("health", middlewareAdmin(handlerHealth))
+
+	return cmds
 }
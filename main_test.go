@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/w0/aggregator/internal/config"
+	"github.com/w0/aggregator/internal/database"
+	"github.com/w0/aggregator/internal/memstore"
+	"github.com/w0/aggregator/internal/migrate"
+	"github.com/w0/aggregator/internal/storage"
+)
+
+// testPassword is what the tests register and log in with.
+const testPassword = "correct horse"
+
+// testDBEnv names a PostgreSQL database the handler tests run against as
+// well. Every test empties it first.
+const testDBEnv = "GATOR_TEST_DB_URL"
+
+type testStore struct {
+	name string
+	// open returns an empty store, and the database under it if there is
+	// one.
+	open func(t *testing.T) (database.Store, *storage.DB)
+}
+
+// testStores are the stores the handler tests run against. Every test that
+// goes through eachStore passes on all of them.
+var testStores = []testStore{
+	{"memory", func(t *testing.T) (database.Store, *storage.DB) {
+		return memstore.New(), nil
+	}},
+	{"sqlite", func(t *testing.T) (database.Store, *storage.DB) {
+		return openTestDB(t, "sqlite://"+filepath.Join(t.TempDir(), "gator.db"))
+	}},
+}
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if url := os.Getenv(testDBEnv); url != "" {
+		testStores = append(testStores, testStore{"postgres", func(t *testing.T) (database.Store, *storage.DB) {
+			return openTestDB(t, url)
+		}})
+	}
+
+	os.Exit(m.Run())
+}
+
+// openTestDB rolls back every migration of the database at url, removing
+// what earlier tests left, and applies them again.
+func openTestDB(t *testing.T, url string) (database.Store, *storage.DB) {
+	t.Helper()
+
+	db, err := storage.Open(url)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := migrate.Load(db.Migrations)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	done := func(migrate.Migration) {}
+
+	err = migrate.Down(ctx, db.DB, db.Dialect, migrations, 0, done)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = migrate.Up(ctx, db.DB, db.Dialect, migrations, migrate.Latest(migrations), done)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db.Store, db
+}
+
+// testEnv runs commands the way main does, against a fresh store and a
+// config in a temporary home directory.
+type testEnv struct {
+	t    *testing.T
+	s    *state
+	cmds commands
+}
+
+// eachStore runs fn once against every store in testStores.
+func eachStore(t *testing.T, fn func(t *testing.T, e *testEnv)) {
+	for _, v := range testStores {
+		t.Run(v.name, func(t *testing.T) {
+			db, conn := v.open(t)
+			e := newTestEnv(t, db)
+			e.s.conn = conn
+
+			fn(t, e)
+		})
+	}
+}
+
+func newTestEnv(t *testing.T, db database.Store) *testEnv {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	err := os.WriteFile(filepath.Join(home, ".gatorconfig.json"), []byte("{}"), 0600)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Fetch: &config.Fetch{
+			HostDelay: "0s",
+			// 32 zero bytes
+			CredentialsKey: "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+		},
+	}
+
+	hosts, err := newHostLimiter(cfg.Fetch)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := newFeedClient(cfg.Fetch)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testEnv{
+		t:    t,
+		s:    &state{db: db, cfg: cfg, hosts: hosts, client: client, webhooks: newWebhookQueue()},
+		cmds: newCommands(),
+	}
+}
+
+// run runs a command line with input as what is typed at its prompts, and
+// returns what it printed.
+func (e *testEnv) run(input string, args ...string) (string, error) {
+	e.t.Helper()
+
+	dir := e.t.TempDir()
+	in := filepath.Join(dir, "stdin")
+
+	if err := os.WriteFile(in, []byte(input), 0600); err != nil {
+		e.t.Fatal(err)
+	}
+
+	inFile, err := os.Open(in)
+
+	if err != nil {
+		e.t.Fatal(err)
+	}
+
+	defer inFile.Close()
+
+	out, err := os.Create(filepath.Join(dir, "stdout"))
+
+	if err != nil {
+		e.t.Fatal(err)
+	}
+
+	defer out.Close()
+
+	savedIn, savedOut, savedReader := os.Stdin, os.Stdout, stdin
+	os.Stdin, os.Stdout, stdin = inFile, out, bufio.NewReader(inFile)
+
+	defer func() {
+		os.Stdin, os.Stdout, stdin = savedIn, savedOut, savedReader
+	}()
+
+	err = e.cmds.run(e.s, command{name: args[0], arguments: args[1:]})
+
+	printed, readErr := os.ReadFile(out.Name())
+
+	if readErr != nil {
+		e.t.Fatal(readErr)
+	}
+
+	return string(printed), err
+}
+
+// must runs a command line that has to succeed.
+func (e *testEnv) must(input string, args ...string) string {
+	e.t.Helper()
+
+	out, err := e.run(input, args...)
+
+	if err != nil {
+		e.t.Fatalf("%s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+
+	return out
+}
+
+// fails runs a command line that has to fail and returns its error.
+func (e *testEnv) fails(input string, args ...string) error {
+	e.t.Helper()
+
+	out, err := e.run(input, args...)
+
+	if err == nil {
+		e.t.Fatalf("%s succeeded\n%s", strings.Join(args, " "), out)
+	}
+
+	return err
+}
+
+// register creates a user with testPassword and leaves them logged in.
+func (e *testEnv) register(name string) {
+	e.t.Helper()
+	e.must(testPassword+"\n"+testPassword+"\n", "register", name)
+}
+
+func (e *testEnv) login(name string) {
+	e.t.Helper()
+	e.must(testPassword+"\n", "login", name)
+}
+
+// user returns the stored user called name.
+func (e *testEnv) user(name string) database.User {
+	e.t.Helper()
+
+	user, err := e.s.db.GetUser(context.Background(), name)
+
+	if err != nil {
+		e.t.Fatalf("getting %s: %v", name, err)
+	}
+
+	return user
+}
+
+// feed returns the stored feed at url.
+func (e *testEnv) feed(url string) database.Feed {
+	e.t.Helper()
+
+	feed, err := e.s.db.GetFeedByUrl(context.Background(), url)
+
+	if err != nil {
+		e.t.Fatalf("getting %s: %v", url, err)
+	}
+
+	return feed
+}
+
+// fetches returns the fetch history of feed, newest first.
+func (e *testEnv) fetches(feed database.Feed) []database.FeedFetch {
+	e.t.Helper()
+
+	fetches, err := e.s.db.GetFeedFetchesSince(context.Background(),
+		database.GetFeedFetchesSinceParams{FeedID: feed.ID})
+
+	if err != nil {
+		e.t.Fatal(err)
+	}
+
+	return fetches
+}
+
+// testFeed is an RSS feed served over HTTP whose items tests can change
+// between fetches.
+type testFeed struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	items    []RSSItem
+	requests []*http.Request
+}
+
+func newTestFeed(t *testing.T, items ...RSSItem) *testFeed {
+	f := &testFeed{items: items}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *testFeed) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, r.Clone(r.Context()))
+
+	w.Header().Set("Content-Type", "application/rss+xml")
+	fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Test feed</title>`)
+
+	for _, v := range f.items {
+		fmt.Fprintf(w, "<item><title>%s</title><link>%s</link><description>%s</description><pubDate>%s</pubDate>",
+			html.EscapeString(v.Title), html.EscapeString(v.Link), html.EscapeString(v.Description), v.PubDate)
+
+		if v.Author != "" {
+			fmt.Fprintf(w, "<author>%s</author>", html.EscapeString(v.Author))
+		}
+
+		for _, c := range v.Categories {
+			fmt.Fprintf(w, "<category>%s</category>", html.EscapeString(c))
+		}
+
+		fmt.Fprint(w, "</item>")
+	}
+
+	fmt.Fprint(w, "</channel></rss>")
+}
+
+func (f *testFeed) set(items ...RSSItem) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.items = items
+}
+
+// lastRequest returns the last request the feed got.
+func (f *testFeed) lastRequest(t *testing.T) *http.Request {
+	t.Helper()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.requests) == 0 {
+		t.Fatal("feed was never fetched")
+	}
+
+	return f.requests[len(f.requests)-1]
+}
+
+// testItem is an RSS item published n days into 2024.
+func testItem(n int) RSSItem {
+	return RSSItem{
+		Title:       fmt.Sprintf("Post %d", n),
+		Link:        fmt.Sprintf("https://example.com/posts/%d", n),
+		Description: fmt.Sprintf("Body of post %d", n),
+		PubDate:     time.Date(2024, 1, n, 12, 0, 0, 0, time.UTC).Format(time.RFC1123Z),
+	}
+}
+
+// fetchAll runs agg's fetch loop once for every feed, which fetches each of
+// them once.
+func (e *testEnv) fetchAll() {
+	e.t.Helper()
+
+	feeds, err := e.s.db.GetFeeds(context.Background())
+
+	if err != nil {
+		e.t.Fatal(err)
+	}
+
+	for range feeds {
+		if err := scrapeFeeds(e.s, false, 1); err != nil {
+			e.t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/w0/aggregator/internal/database"
+	"github.com/w0/aggregator/internal/metrics"
+)
+
+// registry holds the metrics agg serves with --metrics. They are counted
+// whether or not anything reads them.
+var registry = metrics.NewRegistry()
+
+var (
+	fetchesTotal = registry.Counter("gator_feed_fetches_total",
+		"Feed fetches by result: ok, fetch_error or save_error.", "result")
+	fetchDuration = registry.Histogram("gator_feed_fetch_duration_seconds",
+		"Time taken to download a feed.", []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30})
+	fetchBytes = registry.Counter("gator_feed_fetch_bytes_total",
+		"Bytes downloaded fetching feeds.")
+	postsInserted = registry.Counter("gator_posts_inserted_total",
+		"Posts saved from feeds.")
+	postsSkipped = registry.Counter("gator_posts_skipped_total",
+		"Feed items not saved, by reason: duplicate, too_old or pruned.", "reason")
+	dbErrors = registry.Counter("gator_db_errors_total",
+		"Database errors while fetching and saving feeds.")
+)
+
+// registerQueueLag adds a gauge of how long ago the feed agg fetches next was
+// last fetched, or added if it never was.
+func registerQueueLag(s *state) {
+	registry.GaugeFunc("gator_feed_queue_lag_seconds",
+		"Time since the feed next in line was last fetched.",
+		func() (float64, error) { return queueLag(s, time.Now()) })
+}
+
+// queueLag returns how many seconds before now the feed next in line was last
+// fetched, or 0 when no feed is due.
+func queueLag(s *state, now time.Time) (float64, error) {
+	feed, err := s.db.GetNextFeedToFetch(context.Background(),
+		database.GetNextFeedToFetchParams{
+			LastFetchedAt:  sql.NullTime{Time: now.Add(-pushedPollInterval), Valid: true},
+			LeaseExpiresAt: sql.NullTime{Time: now, Valid: true},
+		})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, countDBError(err)
+	}
+
+	since := feed.CreatedAt
+	if feed.LastFetchedAt.Valid {
+		since = feed.LastFetchedAt.Time
+	}
+
+	return now.Sub(since).Seconds(), nil
+}
+
+// countDBError counts err, unless it is nil, in gator_db_errors_total and
+// returns it.
+func countDBError(err error) error {
+	if err != nil {
+		dbErrors.Inc()
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/w0/aggregator/internal/database"
+)
+
+// metricValue reads one series, e.g. `gator_feed_fetches_total{result="ok"}`,
+// from what the registry serves. The registry is shared by every test, so
+// tests compare values from before and after.
+func metricValue(t *testing.T, series string) float64 {
+	t.Helper()
+
+	var b strings.Builder
+	registry.WriteTo(&b)
+
+	for _, line := range strings.Split(b.String(), "\n") {
+		if v, ok := strings.CutPrefix(line, series+" "); ok {
+			f, err := strconv.ParseFloat(v, 64)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return f
+		}
+	}
+
+	return 0
+}
+
+func TestFetchMetrics(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		series := []string{
+			`gator_feed_fetches_total{result="ok"}`,
+			`gator_feed_fetches_total{result="fetch_error"}`,
+			`gator_feed_fetch_duration_seconds_count`,
+			`gator_feed_fetch_bytes_total`,
+			`gator_posts_inserted_total`,
+			`gator_posts_skipped_total{reason="duplicate"}`,
+		}
+
+		before := map[string]float64{}
+		for _, v := range series {
+			before[v] = metricValue(t, v)
+		}
+
+		delta := func(series string) float64 {
+			return metricValue(t, series) - before[series]
+		}
+
+		feed := newTestFeed(t, testItem(1), testItem(2))
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.fetchAll()
+
+		if d := delta(`gator_feed_fetches_total{result="ok"}`); d != 1 {
+			t.Errorf("ok fetches went up by %v, want 1", d)
+		}
+
+		if d := delta(`gator_feed_fetch_duration_seconds_count`); d != 1 {
+			t.Errorf("fetch durations went up by %v, want 1", d)
+		}
+
+		if d := delta(`gator_feed_fetch_bytes_total`); d <= 0 {
+			t.Errorf("fetched bytes went up by %v", d)
+		}
+
+		if d := delta(`gator_posts_inserted_total`); d != 2 {
+			t.Errorf("inserted posts went up by %v, want 2", d)
+		}
+
+		// fetching again finds the same items
+		e.fetchAll()
+
+		if d := delta(`gator_posts_skipped_total{reason="duplicate"}`); d != 2 {
+			t.Errorf("duplicate posts went up by %v, want 2", d)
+		}
+
+		if d := delta(`gator_posts_inserted_total`); d != 2 {
+			t.Errorf("inserted posts went up by %v after fetching again, want 2", d)
+		}
+
+		broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "down", http.StatusInternalServerError)
+		}))
+		t.Cleanup(broken.Close)
+
+		e.must("", "addfeed", "Broken feed", broken.URL)
+		e.fetchAll()
+
+		if d := delta(`gator_feed_fetches_total{result="fetch_error"}`); d != 1 {
+			t.Errorf("failed fetches went up by %v, want 1", d)
+		}
+	})
+}
+
+func TestQueueLag(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		now := time.Now()
+
+		if lag, err := queueLag(e.s, now); err != nil || lag != 0 {
+			t.Errorf("lag without feeds is %v, %v, want 0", lag, err)
+		}
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", "https://example.com/feed")
+
+		err := e.s.db.MarkFeedFetched(context.Background(), database.MarkFeedFetchedParams{
+			UpdatedAt:     now,
+			LastFetchedAt: sql.NullTime{Time: now.Add(-time.Hour), Valid: true},
+			ID:            e.feed("https://example.com/feed").ID,
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if lag, err := queueLag(e.s, now); err != nil || lag < 3599 || lag > 3601 {
+			t.Errorf("lag of a feed fetched an hour ago is %v, %v", lag, err)
+		}
+	})
+}
@@ -10,11 +10,42 @@ import (
 func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
 
 	return func(s *state, cmd command) error {
-		user, err := s.db.GetUser(context.Background(), s.cfg.CurrentUserName)
+		if s.cfg.SessionToken == "" {
+			return fmt.Errorf("not logged in, run aggregator login <username>")
+		}
+
+		user, err := userFromSession(context.Background(), s, s.cfg.SessionToken)
 		if err != nil {
-			return fmt.Errorf("user %s not in database: %w", s.cfg.CurrentUserName, err)
+			return fmt.Errorf("session expired or revoked, log in again: %w", err)
 		}
 
 		return handler(s, cmd, user)
 	}
 }
+
+// sessionUser returns the logged in user, or nil when nobody is, for commands
+// that work either way.
+func sessionUser(s *state) *database.User {
+	if s.cfg.SessionToken == "" {
+		return nil
+	}
+
+	user, err := userFromSession(context.Background(), s, s.cfg.SessionToken)
+
+	if err != nil {
+		return nil
+	}
+
+	return &user
+}
+
+func middlewareAdmin(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
+
+	return middlewareLoggedIn(func(s *state, cmd command, user database.User) error {
+		if user.Role != roleAdmin {
+			return fmt.Errorf("%s requires an admin, %s is a %s", cmd.name, user.Name, user.Role)
+		}
+
+		return handler(s, cmd, user)
+	})
+}
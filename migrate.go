@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/w0/aggregator/internal/migrate"
+	"github.com/w0/aggregator/internal/storage"
+)
+
+const migrateUsage = `usage: aggregator migrate up [version]
+       aggregator migrate down [version]
+       aggregator migrate status`
+
+// passwordResetsVersion is the migration adding reset codes. Applying it is
+// when admins from before passwords existed get a code to choose one with.
+const passwordResetsVersion = 20
+
+func handlerMigrate(s *state, cmd command) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf(migrateUsage)
+	}
+
+	ctx := context.Background()
+
+	migrations, err := migrate.Load(s.conn.Migrations)
+
+	if err != nil {
+		return err
+	}
+
+	current, err := migrate.Version(ctx, s.conn.DB, s.conn.Dialect)
+
+	if err != nil {
+		return err
+	}
+
+	switch cmd.arguments[0] {
+	case "up":
+		target := migrate.Latest(migrations)
+		if len(cmd.arguments) > 1 {
+			target, err = strconv.ParseInt(cmd.arguments[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid version: %w", err)
+			}
+		}
+
+		var addedResets bool
+
+		err = migrate.Up(ctx, s.conn.DB, s.conn.Dialect, migrations, target, func(m migrate.Migration) {
+			fmt.Printf("Applied %s\n", m.Name)
+			addedResets = addedResets || m.Version == passwordResetsVersion
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if addedResets {
+			return printBootstrapCodes(s)
+		}
+	case "down":
+		// without a version, roll back just the newest migration
+		var target int64
+		for _, m := range migrations {
+			if m.Version < current {
+				target = m.Version
+			}
+		}
+
+		if len(cmd.arguments) > 1 {
+			target, err = strconv.ParseInt(cmd.arguments[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid version: %w", err)
+			}
+		}
+
+		err = migrate.Down(ctx, s.conn.DB, s.conn.Dialect, migrations, target, func(m migrate.Migration) {
+			fmt.Printf("Rolled back %s\n", m.Name)
+		})
+
+		if err != nil {
+			return err
+		}
+	case "status":
+		statuses, err := migrate.Statuses(ctx, s.conn.DB, s.conn.Dialect, migrations)
+
+		if err != nil {
+			return err
+		}
+
+		for _, v := range statuses {
+			applied := "pending"
+			if v.AppliedAt != nil {
+				applied = v.AppliedAt.Format(time.DateTime)
+			}
+			fmt.Printf("%-20s %s\n", applied, v.Name)
+		}
+
+		fmt.Printf("Database version %d, latest %d\n", current, migrate.Latest(migrations))
+		return nil
+	default:
+		return fmt.Errorf(migrateUsage)
+	}
+
+	current, err = migrate.Version(ctx, s.conn.DB, s.conn.Dialect)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Database is at version %d\n", current)
+
+	return nil
+}
+
+// checkSchema refuses to run against a database whose schema doesn't match
+// the migrations this binary was built with.
+func checkSchema(db *storage.DB) error {
+	ctx := context.Background()
+
+	migrations, err := migrate.Load(db.Migrations)
+
+	if err != nil {
+		return err
+	}
+
+	current, err := migrate.Version(ctx, db.DB, db.Dialect)
+
+	if err != nil {
+		return err
+	}
+
+	latest := migrate.Latest(migrations)
+
+	switch {
+	case current < latest:
+		return fmt.Errorf("database schema is at version %d but this binary needs %d, run aggregator migrate up", current, latest)
+	case current > latest:
+		return fmt.Errorf("database schema is at version %d, newer than this binary's %d, upgrade aggregator", current, latest)
+	}
+
+	return nil
+}
+
+// printBootstrapCodes prints a reset code for every admin without a password.
+// Those accounts can't log in otherwise, and the codes are only ever shown to
+// whoever upgrades the database.
+func printBootstrapCodes(s *state) error {
+	ctx := context.Background()
+
+	admins, err := s.db.GetAdminsWithoutPassword(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed getting admins: %w", err)
+	}
+
+	for _, admin := range admins {
+		code, expires, err := issueResetCode(ctx, s, admin)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n%s has no password yet. Reset code, valid until %s:\n\n\t%s\n\n", admin.Name, expires.Format(time.DateTime), code)
+		fmt.Printf("Choose a password with aggregator login %s --reset-code\n", admin.Name)
+	}
+
+	return nil
+}
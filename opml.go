@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    struct {
+		Title string `xml:"title"`
+	} `xml:"head"`
+	Body struct {
+		Outlines []OPMLOutline `xml:"outline"`
+	} `xml:"body"`
+}
+
+type OPMLOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+func handlerOPML(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf("usage: aggregator opml export [file] || aggregator opml import <file>")
+	}
+
+	switch cmd.arguments[0] {
+	case "export":
+		doc, err := exportOPML(s, user)
+
+		if err != nil {
+			return err
+		}
+
+		b, err := xml.MarshalIndent(doc, "", "  ")
+
+		if err != nil {
+			return err
+		}
+
+		b = append([]byte(xml.Header), b...)
+
+		if len(cmd.arguments) < 2 {
+			fmt.Println(string(b))
+			return nil
+		}
+
+		err = os.WriteFile(cmd.arguments[1], b, 0644)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Exported %s's feeds to %s\n", user.Name, cmd.arguments[1])
+	case "import":
+		if len(cmd.arguments) < 2 {
+			return fmt.Errorf("usage: aggregator opml import <file>")
+		}
+
+		b, err := os.ReadFile(cmd.arguments[1])
+
+		if err != nil {
+			return err
+		}
+
+		var doc OPML
+
+		err = xml.Unmarshal(b, &doc)
+
+		if err != nil {
+			return fmt.Errorf("failed to parse opml: %w", err)
+		}
+
+		count, err := importOutlines(s, user, doc.Body.Outlines, "")
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Imported %d feeds for %s\n", count, user.Name)
+	default:
+		return fmt.Errorf("unknown opml command: %s", cmd.arguments[0])
+	}
+
+	return nil
+}
+
+// exportOPML builds an OPML document of the user's follows. Grouped feeds are
+// nested under one folder outline per group, ungrouped feeds sit at the top.
+func exportOPML(s *state, user database.User) (OPML, error) {
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+
+	if err != nil {
+		return OPML{}, err
+	}
+
+	groups, err := s.db.GetGroupsForUser(context.Background(), user.ID)
+
+	if err != nil {
+		return OPML{}, err
+	}
+
+	doc := OPML{Version: "2.0"}
+	doc.Head.Title = fmt.Sprintf("%s's feeds", user.Name)
+
+	grouped := make(map[string]bool)
+	folders := make(map[string]int)
+
+	for _, v := range groups {
+		grouped[v.FeedUrl] = true
+
+		i, ok := folders[v.Name]
+		if !ok {
+			doc.Body.Outlines = append(doc.Body.Outlines, OPMLOutline{Text: v.Name, Title: v.Name})
+			i = len(doc.Body.Outlines) - 1
+			folders[v.Name] = i
+		}
+
+		doc.Body.Outlines[i].Outlines = append(doc.Body.Outlines[i].Outlines, feedOutline(v.FeedName, v.FeedUrl))
+	}
+
+	for _, v := range follows {
+		if grouped[v.Url] {
+			continue
+		}
+		doc.Body.Outlines = append(doc.Body.Outlines, feedOutline(v.FeedName, v.Url))
+	}
+
+	return doc, nil
+}
+
+func feedOutline(name, url string) OPMLOutline {
+	return OPMLOutline{
+		Text:   name,
+		Title:  name,
+		Type:   "rss",
+		XMLURL: url,
+	}
+}
+
+// importOutlines follows every feed found in outlines, creating feeds that are
+// not yet known. Feeds nested in a folder outline are added to a group named
+// after the folder.
+func importOutlines(s *state, user database.User, outlines []OPMLOutline, group string) (int, error) {
+	count := 0
+
+	for _, v := range outlines {
+		if v.XMLURL == "" {
+			name := v.Text
+			if name == "" {
+				name = v.Title
+			}
+
+			n, err := importOutlines(s, user, v.Outlines, name)
+
+			if err != nil {
+				return count, err
+			}
+
+			count += n
+			continue
+		}
+
+		feed, err := ensureFollow(s, user, v.Text, v.XMLURL)
+
+		if err != nil {
+			return count, err
+		}
+
+		if group != "" {
+			err = addFeedToGroup(s, user, feed, group)
+
+			if err != nil {
+				return count, err
+			}
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+// ensureFollow makes sure the user follows the feed at url, adding the feed
+// under name if it does not exist yet.
+func ensureFollow(s *state, user database.User, name, url string) (database.Feed, error) {
+	now := time.Now()
+
+	feed, err := s.db.GetFeedByUrl(context.Background(), url)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		if name == "" {
+			name = url
+		}
+
+		feed, err = s.db.CreateFeed(context.Background(),
+			database.CreateFeedParams{
+				ID:        uuid.New(),
+				CreatedAt: now,
+				UpdatedAt: now,
+				Name:      name,
+				Url:       url,
+				UserID:    user.ID,
+			})
+	}
+
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("failed adding feed %s: %w", url, err)
+	}
+
+	_, err = s.db.GetFeedFollow(context.Background(),
+		database.GetFeedFollowParams{
+			UserID: user.ID,
+			FeedID: feed.ID,
+		})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		err = canFollowFeed(context.Background(), s, user, feed)
+
+		if err != nil {
+			return database.Feed{}, err
+		}
+
+		_, err = s.db.CreateFeedFollow(context.Background(),
+			database.CreateFeedFollowParams{
+				ID:        uuid.New(),
+				CreatedAt: now,
+				UpdatedAt: now,
+				FeedID:    feed.ID,
+				UserID:    user.ID,
+			})
+	}
+
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("failed following feed %s: %w", url, err)
+	}
+
+	return feed, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readOPML(t *testing.T, file string) OPML {
+	t.Helper()
+
+	b, err := os.ReadFile(file)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc OPML
+
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	return doc
+}
+
+// What one user exports another imports into the same follows and groups.
+func TestOPMLRoundTrip(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		goBlog, goNews, misc := "https://go.example.com/blog", "https://go.example.com/news", "https://example.com/misc"
+
+		e.register("alice")
+		e.must("", "addfeed", "Go blog", goBlog)
+		e.must("", "addfeed", "Go news", goNews)
+		e.must("", "addfeed", "Misc", misc)
+		e.must("", "group", "add", "go", goBlog)
+		e.must("", "group", "add", "go", goNews)
+		e.must("", "group", "add", "news", goNews)
+
+		dir := t.TempDir()
+		exported := filepath.Join(dir, "alice.opml")
+
+		if out := e.must("", "opml", "export", exported); out != "Exported alice's feeds to "+exported+"\n" {
+			t.Errorf("opml export printed %q", out)
+		}
+
+		doc := readOPML(t, exported)
+
+		want := []OPMLOutline{
+			{Text: "go", Title: "go", Outlines: []OPMLOutline{feedOutline("Go blog", goBlog), feedOutline("Go news", goNews)}},
+			{Text: "news", Title: "news", Outlines: []OPMLOutline{feedOutline("Go news", goNews)}},
+			feedOutline("Misc", misc),
+		}
+
+		if doc.Version != "2.0" || doc.Head.Title != "alice's feeds" || !reflect.DeepEqual(doc.Body.Outlines, want) {
+			t.Errorf("exported %+v", doc)
+		}
+
+		e.register("bob")
+
+		if out := e.must("", "opml", "import", exported); out != "Imported 4 feeds for bob\n" {
+			t.Errorf("opml import printed %q", out)
+		}
+
+		again := filepath.Join(dir, "bob.opml")
+		e.must("", "opml", "export", again)
+
+		if got := readOPML(t, again).Body.Outlines; !reflect.DeepEqual(got, want) {
+			t.Errorf("bob's export after importing alice's is\n%+v\nwant\n%+v", got, want)
+		}
+
+		// importing again changes nothing
+		e.must("", "opml", "import", exported)
+		e.must("", "opml", "export", again)
+
+		if got := readOPML(t, again).Body.Outlines; !reflect.DeepEqual(got, want) {
+			t.Errorf("importing twice left\n%+v", got)
+		}
+
+		if out := e.must("", "following"); strings.Count(out, "* ") != 3 {
+			t.Errorf("bob follows\n%s", out)
+		}
+	})
+}
+
+// Feeds that aren't known yet are added, named after their outline.
+func TestOPMLImportNewFeeds(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		file := filepath.Join(t.TempDir(), "feeds.opml")
+		opml := `<?xml version="1.0"?>
+<opml version="1.0"><head><title>feeds</title></head><body>
+<outline text="Reading">
+<outline text="Example" type="rss" xmlUrl="https://example.com/feed"/>
+</outline>
+<outline title="Untitled" type="rss" xmlUrl="https://example.com/other"/>
+</body></opml>`
+
+		if err := os.WriteFile(file, []byte(opml), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		e.register("alice")
+		e.must("", "opml", "import", file)
+
+		if got := e.feed("https://example.com/feed"); got.Name != "Example" || got.UserID != e.user("alice").ID {
+			t.Errorf("imported feed is %+v", got)
+		}
+
+		// without a text the url names the feed
+		if got := e.feed("https://example.com/other"); got.Name != "https://example.com/other" {
+			t.Errorf("imported feed without a text is named %q", got.Name)
+		}
+
+		if out := e.must("", "group", "list"); !strings.Contains(out, "Reading") {
+			t.Errorf("the folder didn't become a group:\n%s", out)
+		}
+	})
+}
+
+func TestFollowGroup(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", "https://example.com/feed")
+		e.register("bob")
+
+		// flags after the url would be ignored, so they are refused
+		e.fails("", "follow", "https://example.com/feed", "--group", "news")
+
+		if out := e.must("", "following"); strings.Contains(out, "Test feed") {
+			t.Errorf("a refused follow followed the feed:\n%s", out)
+		}
+
+		if out := e.must("", "follow", "--group", "news", "https://example.com/feed"); out != "bob followed Test feed in group news\n" {
+			t.Errorf("follow --group printed %q", out)
+		}
+
+		if out := e.must("", "group", "list", "news"); !strings.Contains(out, "Test feed") {
+			t.Errorf("group list news printed\n%s", out)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/w0/aggregator/internal/config"
+)
+
+const (
+	defaultUserAgent       = "gator"
+	defaultContact         = "https://github.com/w0/aggregator"
+	defaultHostConcurrency = 2
+	defaultHostDelay       = time.Second
+
+	// maxRetryAfter caps how long a host can ask us to stay away.
+	maxRetryAfter = 24 * time.Hour
+)
+
+// errBackOff is returned for requests to a host that asked us to wait.
+var errBackOff = errors.New("host asked us to wait")
+
+// userAgent is sent with every feed fetch and webhook delivery, so the
+// people running a host can tell who is fetching and how to reach them.
+func userAgent(cfg *config.Config) string {
+	ua, contact := defaultUserAgent, defaultContact
+
+	if f := cfg.Fetch; f != nil {
+		if f.UserAgent != "" {
+			ua = f.UserAgent
+		}
+		if f.Contact != "" {
+			contact = f.Contact
+		}
+	}
+
+	return fmt.Sprintf("%s (+%s)", ua, contact)
+}
+
+// hostLimiter keeps agg polite to the hosts it fetches from. It allows at
+// most concurrency requests to a host at a time, starts them at least delay
+// apart, and sends none while a Retry-After the host answered with runs.
+type hostLimiter struct {
+	concurrency int
+	delay       time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	slots chan struct{}
+	// next is the earliest a request to the host may start.
+	next       time.Time
+	retryAfter time.Time
+}
+
+// newHostLimiter reads the limits from the fetch settings in the config,
+// using the defaults for those left out.
+func newHostLimiter(cfg *config.Fetch) (*hostLimiter, error) {
+	l := &hostLimiter{
+		concurrency: defaultHostConcurrency,
+		delay:       defaultHostDelay,
+		hosts:       map[string]*hostState{},
+	}
+
+	if cfg == nil {
+		return l, nil
+	}
+
+	if cfg.HostConcurrency < 0 {
+		return nil, fmt.Errorf("host_concurrency can't be negative")
+	}
+
+	if cfg.HostConcurrency > 0 {
+		l.concurrency = cfg.HostConcurrency
+	}
+
+	if cfg.HostDelay != "" {
+		delay, err := time.ParseDuration(cfg.HostDelay)
+
+		if err != nil || delay < 0 {
+			return nil, fmt.Errorf("host_delay must be a duration such as 2s")
+		}
+
+		l.delay = delay
+	}
+
+	return l, nil
+}
+
+func (l *hostLimiter) host(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	host = strings.ToLower(host)
+
+	h, ok := l.hosts[host]
+	if !ok {
+		h = &hostState{slots: make(chan struct{}, l.concurrency)}
+		l.hosts[host] = h
+	}
+
+	return h
+}
+
+// acquire waits until a request to host may start and returns the func that
+// ends it. It fails right away while the host has asked us to back off.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	h := l.host(host)
+
+	l.mu.Lock()
+	until := h.retryAfter
+	l.mu.Unlock()
+
+	if time.Now().Before(until) {
+		return nil, fmt.Errorf("%w: %s until %s", errBackOff, host, until.Format(time.DateTime))
+	}
+
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	release := func() { <-h.slots }
+
+	l.mu.Lock()
+	start := time.Now()
+	if h.next.After(start) {
+		start = h.next
+	}
+	h.next = start.Add(l.delay)
+	l.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+
+	return release, nil
+}
+
+// backOff keeps requests away from host until the time a 429 or 503
+// response's Retry-After names. It returns that time, or the zero time when
+// the response has no usable Retry-After.
+func (l *hostLimiter) backOff(host string, res *http.Response) time.Time {
+	until, ok := parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+
+	if !ok {
+		return time.Time{}
+	}
+
+	h := l.host(host)
+
+	l.mu.Lock()
+	if until.After(h.retryAfter) {
+		h.retryAfter = until
+	}
+	l.mu.Unlock()
+
+	return until
+}
+
+// parseRetryAfter reads a Retry-After header, which holds either a number
+// of seconds or an HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Time, bool) {
+	v = strings.TrimSpace(v)
+
+	if v == "" {
+		return time.Time{}, false
+	}
+
+	var until time.Time
+
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return time.Time{}, false
+		}
+		until = now.Add(time.Duration(secs) * time.Second)
+	} else if t, err := http.ParseTime(v); err == nil {
+		until = t
+	} else {
+		return time.Time{}, false
+	}
+
+	if limit := now.Add(maxRetryAfter); until.After(limit) {
+		until = limit
+	}
+
+	return until, true
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// confirm asks a yes/no question on stdout and reads the answer from stdin.
+// Anything but y or yes counts as no.
+func confirm(question string) (bool, error) {
+	answer, err := prompt(fmt.Sprintf("%s [y/N]: ", question))
+
+	if err != nil {
+		return false, err
+	}
+
+	answer = strings.ToLower(answer)
+
+	return answer == "y" || answer == "yes", nil
+}
+
+// prompt prints msg and returns the next line read from stdin without the
+// line ending.
+func prompt(msg string) (string, error) {
+	fmt.Print(msg)
+
+	line, err := stdin.ReadString('\n')
+
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed reading answer: %w", err)
+	}
+
+	return strings.TrimSpace(line), nil
+}
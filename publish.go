@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+const (
+	defaultPublishLimit = 50
+	maxPublishLimit     = 500
+)
+
+// timelineOptions selects the posts of a published timeline.
+type timelineOptions struct {
+	group   string
+	keyword string
+	limit   int
+	link    string
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published,omitempty"`
+	Links      []atomLink     `xml:"link"`
+	Author     *atomPerson    `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    atomText       `xml:"summary"`
+	Source     struct {
+		Title string `xml:"title"`
+	} `xml:"source"`
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string       `xml:"title"`
+	Link          string       `xml:"link"`
+	Description   string       `xml:"description"`
+	LastBuildDate string       `xml:"lastBuildDate"`
+	Items         []rssOutItem `xml:"item"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssOutItem struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	GUID        rssGUID   `xml:"guid"`
+	PubDate     string    `xml:"pubDate,omitempty"`
+	Categories  []string  `xml:"category"`
+	Source      rssSource `xml:"source"`
+}
+
+type rssSource struct {
+	URL   string `xml:"url,attr"`
+	Value string `xml:",chardata"`
+}
+
+func handlerPublish(s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	format := fs.String("format", "atom", "atom or rss")
+	opts := timelineOptions{}
+	fs.StringVar(&opts.group, "group", "", "only publish posts from feeds in group")
+	fs.StringVar(&opts.keyword, "keyword", "", "only publish posts mentioning keyword")
+	fs.IntVar(&opts.limit, "limit", defaultPublishLimit, "number of posts to publish")
+	fs.StringVar(&opts.link, "link", "", "url the published feed will be available at")
+
+	if err := fs.Parse(cmd.arguments); err != nil || fs.NArg() != 1 {
+		return fmt.Errorf("usage: aggregator publish [--format atom||rss] [--group <group>] [--keyword <keyword>] [--limit <n>] [--link <url>] <file>")
+	}
+
+	// without a url to be served at, the feed links to the file itself
+	if opts.link == "" {
+		path, err := filepath.Abs(fs.Arg(0))
+
+		if err != nil {
+			return err
+		}
+
+		opts.link = (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+	}
+
+	posts, err := timelinePosts(s, user, opts)
+
+	if err != nil {
+		return err
+	}
+
+	b, _, err := renderTimeline(*format, user, posts, opts)
+
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(fs.Arg(0), b, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Published %d posts for %s to %s\n", len(posts), user.Name, fs.Arg(0))
+
+	return nil
+}
+
+// handleTimeline serves the user's timeline as an atom or rss feed. Feed
+// readers rarely send custom headers, so the token may also be passed as the
+// token query parameter.
+func (srv *server) handleTimeline(format string) authedHandler {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		query := r.URL.Query()
+
+		limit, err := queryInt(query.Get("limit"), defaultPublishLimit)
+
+		if err != nil || limit < 1 || limit > maxPublishLimit {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+
+		opts := timelineOptions{
+			group:   query.Get("group"),
+			keyword: query.Get("q"),
+			limit:   limit,
+			link:    fmt.Sprintf("%s://%s%s", scheme, r.Host, r.URL.Path),
+		}
+
+		posts, err := timelinePosts(srv.s, user, opts)
+
+		if err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+
+		b, modified, err := renderTimeline(format, user, posts, opts)
+
+		if err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+
+		sum := sha256.Sum256(b)
+
+		contentType := "application/atom+xml; charset=utf-8"
+		if format == "rss" {
+			contentType = "application/rss+xml; charset=utf-8"
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("ETag", fmt.Sprintf("%q", hex.EncodeToString(sum[:16])))
+
+		// ServeContent answers If-None-Match and If-Modified-Since with 304.
+		http.ServeContent(w, r, "", modified, bytes.NewReader(b))
+	}
+}
+
+func timelinePosts(s *state, user database.User, opts timelineOptions) ([]database.ListPostsForUserRow, error) {
+	if opts.limit < 1 || opts.limit > maxPublishLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxPublishLimit)
+	}
+
+	params := database.ListPostsForUserParams{
+		UserID:  user.ID,
+		Keyword: keywordPattern(opts.keyword),
+		Limit:   int32(opts.limit),
+	}
+
+	if opts.group != "" {
+		params.GroupName = sql.NullString{String: opts.group, Valid: true}
+	}
+
+	posts, err := s.db.ListPostsForUser(context.Background(), params)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch posts for user %w", err)
+	}
+
+	return posts, nil
+}
+
+// keywordPattern turns a keyword into the LIKE pattern ListPostsForUser
+// matches against lower cased titles and descriptions.
+func keywordPattern(keyword string) sql.NullString {
+	if keyword == "" {
+		return sql.NullString{}
+	}
+
+	return sql.NullString{
+		String: "%" + strings.ToLower(keyword) + "%",
+		Valid:  true,
+	}
+}
+
+// renderTimeline encodes posts as an atom or rss document. It also returns
+// the time the newest post was saved, used as the feed's modification time.
+func renderTimeline(format string, user database.User, posts []database.ListPostsForUserRow, opts timelineOptions) ([]byte, time.Time, error) {
+	modified := user.CreatedAt
+	for _, v := range posts {
+		if v.CreatedAt.After(modified) {
+			modified = v.CreatedAt
+		}
+	}
+
+	title := fmt.Sprintf("%s's timeline", user.Name)
+	if opts.group != "" {
+		title = fmt.Sprintf("%s: %s", title, opts.group)
+	}
+	if opts.keyword != "" {
+		title = fmt.Sprintf("%s matching %q", title, opts.keyword)
+	}
+
+	var doc any
+
+	switch format {
+	case "atom":
+		doc = atomTimeline(user, posts, opts, title, modified)
+	case "rss":
+		doc = rssTimeline(posts, opts, title, modified)
+	default:
+		return nil, time.Time{}, fmt.Errorf("unknown format %s, expected atom or rss", format)
+	}
+
+	b, err := xml.MarshalIndent(doc, "", "  ")
+
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return append([]byte(xml.Header), b...), modified, nil
+}
+
+func atomTimeline(user database.User, posts []database.ListPostsForUserRow, opts timelineOptions, title string, modified time.Time) atomFeed {
+	// The feed id only depends on the user and the selection, so it is
+	// stable between renders.
+	selection := fmt.Sprintf("group=%s&keyword=%s", opts.group, opts.keyword)
+
+	feed := atomFeed{
+		ID:      "urn:uuid:" + uuid.NewSHA1(user.ID, []byte(selection)).String(),
+		Title:   title,
+		Updated: modified.UTC().Format(time.RFC3339),
+		Author:  atomPerson{Name: user.Name},
+	}
+
+	if opts.link != "" {
+		feed.Links = append(feed.Links, atomLink{Href: opts.link, Rel: "self"})
+	}
+
+	for _, v := range posts {
+		entry := atomEntry{
+			ID:      "urn:uuid:" + v.ID.String(),
+			Title:   v.Title,
+			Updated: v.UpdatedAt.UTC().Format(time.RFC3339),
+			Links:   []atomLink{{Href: v.Url, Rel: "alternate"}},
+			Summary: atomText{Type: "html", Body: v.Description.String},
+		}
+
+		if v.PublishedAt.Valid {
+			entry.Published = v.PublishedAt.Time.UTC().Format(time.RFC3339)
+		}
+
+		if v.Author.Valid {
+			entry.Author = &atomPerson{Name: v.Author.String}
+		}
+
+		for _, c := range splitCategories(v.Categories) {
+			entry.Categories = append(entry.Categories, atomCategory{Term: c})
+		}
+
+		entry.Source.Title = v.FeedName
+
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return feed
+}
+
+func rssTimeline(posts []database.ListPostsForUserRow, opts timelineOptions, title string, modified time.Time) rssDocument {
+	doc := rssDocument{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         title,
+			Link:          opts.link,
+			Description:   title,
+			LastBuildDate: modified.UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	for _, v := range posts {
+		item := rssOutItem{
+			Title:       v.Title,
+			Link:        v.Url,
+			Description: v.Description.String,
+			GUID:        rssGUID{Value: "urn:uuid:" + v.ID.String()},
+			Categories:  splitCategories(v.Categories),
+			Source:      rssSource{URL: v.FeedUrl, Value: v.FeedName},
+		}
+
+		if v.PublishedAt.Valid {
+			item.PubDate = v.PublishedAt.Time.UTC().Format(time.RFC1123Z)
+		}
+
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+
+	return doc
+}
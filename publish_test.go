@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// apiToken creates an api token for the logged in user and returns it.
+func (e *testEnv) apiToken() string {
+	e.t.Helper()
+
+	out := e.must("", "token", "create", "test")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+
+	return lines[len(lines)-1]
+}
+
+// get serves a GET of target, with header set on the request.
+func (e *testEnv) get(target string, header http.Header) *httptest.ResponseRecorder {
+	e.t.Helper()
+
+	req := httptest.NewRequest("GET", target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	w := httptest.NewRecorder()
+	newServer(e.s).ServeHTTP(w, req)
+
+	return w
+}
+
+func bearer(token string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + token}}
+}
+
+// publishedFeed serves a timeline feed with two posts, the newer one with
+// an author and categories.
+func publishedFeed(t *testing.T, e *testEnv) (*testFeed, string) {
+	t.Helper()
+
+	item := testItem(2)
+	item.Author = "Rob Pike"
+	item.Categories = []string{"go", "news"}
+
+	feed := newTestFeed(t, testItem(1), item)
+
+	e.register("alice")
+	e.must("", "addfeed", "Test feed", feed.URL)
+	e.fetchAll()
+
+	return feed, e.apiToken()
+}
+
+func TestTimelineAtom(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed, token := publishedFeed(t, e)
+
+		w := e.get("/timeline.atom?token="+token, nil)
+
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/atom+xml; charset=utf-8" {
+			t.Fatalf("timeline.atom answered %d %q\n%s", w.Code, w.Header().Get("Content-Type"), w.Body)
+		}
+
+		var doc atomFeed
+
+		if err := xml.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+			t.Fatal(err)
+		}
+
+		if doc.Title != "alice's timeline" || doc.Author.Name != "alice" || !strings.HasPrefix(doc.ID, "urn:uuid:") {
+			t.Errorf("feed is %+v", doc)
+		}
+
+		if len(doc.Links) != 1 || doc.Links[0] != (atomLink{Href: "http://example.com/timeline.atom", Rel: "self"}) {
+			t.Errorf("feed links are %+v", doc.Links)
+		}
+
+		if len(doc.Entries) != 2 {
+			t.Fatalf("feed has %d entries, want 2", len(doc.Entries))
+		}
+
+		entry := doc.Entries[0]
+
+		if entry.ID != "urn:uuid:"+e.postID(feed.URL, postUrl(2)).String() || entry.Title != "Post 2" ||
+			entry.Published != "2024-01-02T12:00:00Z" || entry.Source.Title != "Test feed" {
+			t.Errorf("newest entry is %+v", entry)
+		}
+
+		if len(entry.Links) != 1 || entry.Links[0].Href != postUrl(2) || entry.Links[0].Rel != "alternate" {
+			t.Errorf("entry links are %+v", entry.Links)
+		}
+
+		if entry.Author == nil || entry.Author.Name != "Rob Pike" {
+			t.Errorf("entry author is %+v", entry.Author)
+		}
+
+		if len(entry.Categories) != 2 || entry.Categories[0].Term != "go" || entry.Categories[1].Term != "news" {
+			t.Errorf("entry categories are %+v", entry.Categories)
+		}
+
+		if doc.Entries[1].Author != nil {
+			t.Errorf("entry without an author has %+v", doc.Entries[1].Author)
+		}
+
+		// ids don't change between requests, and neither does the content
+		again := e.get("/timeline.atom", bearer(token))
+
+		var doc2 atomFeed
+
+		if err := xml.Unmarshal(again.Body.Bytes(), &doc2); err != nil {
+			t.Fatal(err)
+		}
+
+		if doc2.ID != doc.ID || doc2.Entries[0].ID != entry.ID {
+			t.Errorf("ids changed between requests: %s %s, then %s %s", doc.ID, entry.ID, doc2.ID, doc2.Entries[0].ID)
+		}
+
+		etag := w.Header().Get("ETag")
+
+		if etag == "" || again.Header().Get("ETag") != etag {
+			t.Errorf("ETags are %q and %q", etag, again.Header().Get("ETag"))
+		}
+
+		if w := e.get("/timeline.atom", http.Header{"Authorization": {"Bearer " + token}, "If-None-Match": {etag}}); w.Code != http.StatusNotModified {
+			t.Errorf("conditional request answered %d, want 304", w.Code)
+		}
+
+		modified := w.Header().Get("Last-Modified")
+
+		if w := e.get("/timeline.atom", http.Header{"Authorization": {"Bearer " + token}, "If-Modified-Since": {modified}}); w.Code != http.StatusNotModified {
+			t.Errorf("request if modified since %q answered %d, want 304", modified, w.Code)
+		}
+	})
+}
+
+func TestTimelineRSS(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed, token := publishedFeed(t, e)
+
+		w := e.get("/timeline.rss?q=post+1", bearer(token))
+
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/rss+xml; charset=utf-8" {
+			t.Fatalf("timeline.rss answered %d %q\n%s", w.Code, w.Header().Get("Content-Type"), w.Body)
+		}
+
+		var doc rssDocument
+
+		if err := xml.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+			t.Fatal(err)
+		}
+
+		if doc.Version != "2.0" || doc.Channel.Title != `alice's timeline matching "post 1"` || doc.Channel.Link != "http://example.com/timeline.rss" {
+			t.Errorf("channel is %+v", doc.Channel)
+		}
+
+		if len(doc.Channel.Items) != 1 {
+			t.Fatalf("channel has %d items, want the one matching the keyword", len(doc.Channel.Items))
+		}
+
+		item := doc.Channel.Items[0]
+		want := rssOutItem{
+			Title:       "Post 1",
+			Link:        postUrl(1),
+			Description: "Body of post 1",
+			GUID:        rssGUID{Value: "urn:uuid:" + e.postID(feed.URL, postUrl(1)).String()},
+			PubDate:     "Mon, 01 Jan 2024 12:00:00 +0000",
+			Source:      rssSource{URL: feed.URL, Value: "Test feed"},
+		}
+
+		if !reflect.DeepEqual(item, want) {
+			t.Errorf("item is %+v, want %+v", item, want)
+		}
+
+		if w := e.get("/timeline.rss?limit=0", bearer(token)); w.Code != http.StatusBadRequest {
+			t.Errorf("limit=0 answered %d, want 400", w.Code)
+		}
+	})
+}
+
+// Only the feeds take the token in the url.
+func TestTokenInQuery(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		e.register("alice")
+		token := e.apiToken()
+
+		if w := e.get("/timeline.atom", nil); w.Code != http.StatusUnauthorized {
+			t.Errorf("timeline without a token answered %d, want 401", w.Code)
+		}
+
+		if w := e.get("/timeline.rss?token=wrong", nil); w.Code != http.StatusUnauthorized {
+			t.Errorf("timeline with a wrong token answered %d, want 401", w.Code)
+		}
+
+		if w := e.get("/api/v1/me?token="+token, nil); w.Code != http.StatusUnauthorized {
+			t.Errorf("api with the token in the url answered %d, want 401", w.Code)
+		}
+
+		if w := e.get("/api/v1/me", bearer(token)); w.Code != http.StatusOK {
+			t.Errorf("api with the token in the header answered %d, want 200", w.Code)
+		}
+	})
+}
+
+func TestPublish(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		publishedFeed(t, e)
+
+		file := filepath.Join(t.TempDir(), "timeline.xml")
+
+		if out := e.must("", "publish", "--format", "rss", file); out != "Published 2 posts for alice to "+file+"\n" {
+			t.Errorf("publish printed %q", out)
+		}
+
+		b, err := os.ReadFile(file)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var doc rssDocument
+
+		if err := xml.Unmarshal(b, &doc); err != nil {
+			t.Fatal(err)
+		}
+
+		if doc.Channel.Link != "file://"+filepath.ToSlash(file) || len(doc.Channel.Items) != 2 {
+			t.Errorf("channel links to %q and has %d items", doc.Channel.Link, len(doc.Channel.Items))
+		}
+
+		e.must("", "publish", "--link", "https://example.com/alice.atom", file)
+
+		b, err = os.ReadFile(file)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(string(b), `<link href="https://example.com/alice.atom" rel="self"></link>`) {
+			t.Errorf("atom feed doesn't link to itself:\n%s", b)
+		}
+	})
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/w0/aggregator/internal/database"
+)
+
+const pruneUsage = `usage: aggregator prune [--dry-run] [--feed <url>]`
+
+const retentionUsage = `usage: aggregator feed retention <url> [--max-age <days>] [--max-posts <n>] [--keep-unread-or-starred true||false]
+       aggregator feed retention <url> --clear`
+
+// retentionPolicy decides which posts of a feed are kept. Zero values mean
+// no limit.
+type retentionPolicy struct {
+	maxAgeDays int
+	maxPosts   int
+	keep       bool
+}
+
+func (p retentionPolicy) limited() bool {
+	return p.maxAgeDays > 0 || p.maxPosts > 0
+}
+
+// tooOld reports whether a post published or saved at t is past the max age.
+func (p retentionPolicy) tooOld(t, now time.Time) bool {
+	return p.maxAgeDays > 0 && t.Before(now.AddDate(0, 0, -p.maxAgeDays))
+}
+
+// feedRetention returns the global retention policy with the feed's
+// overrides applied.
+func feedRetention(s *state, feed database.Feed) (retentionPolicy, error) {
+	var p retentionPolicy
+
+	if g := s.cfg.Retention; g != nil {
+		p = retentionPolicy{
+			maxAgeDays: g.MaxAgeDays,
+			maxPosts:   g.MaxPosts,
+			keep:       g.KeepUnreadOrStarred,
+		}
+	}
+
+	r, err := s.db.GetFeedRetention(context.Background(), feed.ID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, nil
+	}
+
+	if err != nil {
+		return p, fmt.Errorf("failed getting retention for %s: %w", feed.Url, err)
+	}
+
+	if r.MaxAgeDays.Valid {
+		p.maxAgeDays = int(r.MaxAgeDays.Int32)
+	}
+
+	if r.MaxPosts.Valid {
+		p.maxPosts = int(r.MaxPosts.Int32)
+	}
+
+	if r.KeepUnreadOrStarred.Valid {
+		p.keep = r.KeepUnreadOrStarred.Bool
+	}
+
+	return p, nil
+}
+
+// pastMaxPosts returns the urls of the new posts that would rank past the
+// max posts of the feed among its saved posts, newest first, so would be
+// pruned right away. With keep the new posts, unread as they are, stay.
+func (p retentionPolicy) pastMaxPosts(saved []database.GetPostsForPruningRow, posts []database.CreatePostParams) map[string]bool {
+	past := map[string]bool{}
+
+	if p.maxPosts <= 0 || p.keep {
+		return past
+	}
+
+	exists := map[string]bool{}
+	var fresh []database.CreatePostParams
+
+	for _, v := range saved {
+		exists[v.Url] = true
+	}
+
+	for _, v := range posts {
+		if !exists[v.Url] {
+			fresh = append(fresh, v)
+		}
+	}
+
+	for i, v := range fresh {
+		t := newPostedAt(v)
+
+		var newer int
+		for _, other := range saved {
+			if postedAt(other).After(t) {
+				newer++
+			}
+		}
+
+		// posts saved later get a higher seq, which breaks ties
+		for j, other := range fresh {
+			if o := newPostedAt(other); o.After(t) || o.Equal(t) && j > i {
+				newer++
+			}
+		}
+
+		if newer >= p.maxPosts {
+			past[v.Url] = true
+		}
+	}
+
+	return past
+}
+
+func newPostedAt(post database.CreatePostParams) time.Time {
+	if post.PublishedAt.Valid {
+		return post.PublishedAt.Time
+	}
+
+	return post.CreatedAt
+}
+
+func handlerPrune(s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only list the posts that would be deleted")
+	feedURL := fs.String("feed", "", "only prune this feed")
+
+	if err := fs.Parse(cmd.arguments); err != nil || fs.NArg() > 0 {
+		return fmt.Errorf(pruneUsage)
+	}
+
+	ctx := context.Background()
+
+	var feeds []database.Feed
+
+	if *feedURL != "" {
+		feed, err := s.db.GetFeedByUrl(ctx, *feedURL)
+
+		if err != nil {
+			return fmt.Errorf("feed %s not found: %w", *feedURL, err)
+		}
+
+		feeds = append(feeds, feed)
+	} else {
+		all, err := s.db.GetAllFeeds(ctx)
+
+		if err != nil {
+			return fmt.Errorf("failed getting feeds: %w", err)
+		}
+
+		feeds = all
+	}
+
+	var total, pruned int
+
+	for _, feed := range feeds {
+		posts, err := pruneFeed(s, feed, *dryRun)
+
+		if err != nil {
+			return err
+		}
+
+		if len(posts) == 0 {
+			continue
+		}
+
+		fmt.Printf("%s: %d posts\n", feed.Name, len(posts))
+
+		if *dryRun {
+			for _, v := range posts {
+				fmt.Printf("\t%s  %s\n", postedAt(v).Format(time.DateOnly), v.Title)
+			}
+		}
+
+		total += len(posts)
+		pruned++
+	}
+
+	if *dryRun {
+		fmt.Printf("Would delete %d posts from %d feeds\n", total, pruned)
+	} else {
+		fmt.Printf("Deleted %d posts from %d feeds\n", total, pruned)
+	}
+
+	return nil
+}
+
+// pruneFeed deletes the posts of feed its retention policy no longer keeps
+// and returns them. With dryRun nothing is deleted.
+func pruneFeed(s *state, feed database.Feed, dryRun bool) ([]database.GetPostsForPruningRow, error) {
+	policy, err := feedRetention(s, feed)
+
+	if err != nil || !policy.limited() {
+		return nil, err
+	}
+
+	ctx := context.Background()
+
+	// newest first, so the position is the post's rank in the feed
+	posts, err := s.db.GetPostsForPruning(ctx, feed.ID)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed getting posts of %s: %w", feed.Url, err)
+	}
+
+	now := time.Now()
+
+	var prunable []database.GetPostsForPruningRow
+
+	for i, v := range posts {
+		tooMany := policy.maxPosts > 0 && i >= policy.maxPosts
+
+		if !tooMany && !policy.tooOld(postedAt(v), now) {
+			continue
+		}
+
+		if policy.keep && (v.Unread || v.Starred) {
+			continue
+		}
+
+		prunable = append(prunable, v)
+	}
+
+	if dryRun {
+		return prunable, nil
+	}
+
+	err = s.db.InTx(ctx, func(tx database.Store) error {
+		for _, v := range prunable {
+			// states and tags of the post are removed by the cascade.
+			_, err := tx.DeletePost(ctx, v.ID)
+
+			if err != nil {
+				return fmt.Errorf("failed deleting post %s: %w", v.Url, err)
+			}
+
+			// the feed likely still lists the post, which mustn't be saved
+			// again on the next fetch
+			err = tx.CreatePrunedUrl(ctx,
+				database.CreatePrunedUrlParams{
+					FeedID:   feed.ID,
+					Url:      v.Url,
+					PrunedAt: now,
+				})
+
+			if err != nil {
+				return fmt.Errorf("failed recording pruned post %s: %w", v.Url, err)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return prunable, nil
+}
+
+func postedAt(post database.GetPostsForPruningRow) time.Time {
+	if post.PublishedAt.Valid {
+		return post.PublishedAt.Time
+	}
+
+	return post.CreatedAt
+}
+
+func handlerFeedRetention(s *state, feed database.Feed, arguments []string) error {
+	fs := flag.NewFlagSet("feed retention", flag.ContinueOnError)
+	maxAge := fs.Int("max-age", 0, "delete posts older than this many days, 0 for no limit")
+	maxPosts := fs.Int("max-posts", 0, "keep at most this many posts, 0 for no limit")
+	keep := fs.String("keep-unread-or-starred", "", "never delete posts someone hasn't read or has starred")
+	clearPolicy := fs.Bool("clear", false, "go back to the global policy")
+
+	if err := fs.Parse(arguments); err != nil || fs.NArg() > 0 {
+		return fmt.Errorf(retentionUsage)
+	}
+
+	ctx := context.Background()
+
+	if *clearPolicy {
+		_, err := s.db.DeleteFeedRetention(ctx, feed.ID)
+
+		if err != nil {
+			return fmt.Errorf("failed clearing retention: %w", err)
+		}
+
+		return printRetention(s, feed)
+	}
+
+	if fs.NFlag() == 0 {
+		return printRetention(s, feed)
+	}
+
+	current, err := s.db.GetFeedRetention(ctx, feed.ID)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed getting retention: %w", err)
+	}
+
+	now := time.Now()
+	params := database.SetFeedRetentionParams{
+		FeedID:              feed.ID,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+		MaxAgeDays:          current.MaxAgeDays,
+		MaxPosts:            current.MaxPosts,
+		KeepUnreadOrStarred: current.KeepUnreadOrStarred,
+	}
+
+	var parseErr error
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "max-age":
+			if *maxAge < 0 {
+				parseErr = fmt.Errorf("max age can't be negative")
+			}
+			params.MaxAgeDays = sql.NullInt32{Int32: int32(*maxAge), Valid: true}
+		case "max-posts":
+			if *maxPosts < 0 {
+				parseErr = fmt.Errorf("max posts can't be negative")
+			}
+			params.MaxPosts = sql.NullInt32{Int32: int32(*maxPosts), Valid: true}
+		case "keep-unread-or-starred":
+			b, err := strconv.ParseBool(*keep)
+			if err != nil {
+				parseErr = fmt.Errorf("keep-unread-or-starred must be true or false")
+			}
+			params.KeepUnreadOrStarred = sql.NullBool{Bool: b, Valid: true}
+		}
+	})
+
+	if parseErr != nil {
+		return parseErr
+	}
+
+	_, err = s.db.SetFeedRetention(ctx, params)
+
+	if err != nil {
+		return fmt.Errorf("failed setting retention: %w", err)
+	}
+
+	return printRetention(s, feed)
+}
+
+func printRetention(s *state, feed database.Feed) error {
+	policy, err := feedRetention(s, feed)
+
+	if err != nil {
+		return err
+	}
+
+	maxAge, maxPosts := "no limit", "no limit"
+	if policy.maxAgeDays > 0 {
+		maxAge = fmt.Sprintf("%d days", policy.maxAgeDays)
+	}
+	if policy.maxPosts > 0 {
+		maxPosts = strconv.Itoa(policy.maxPosts)
+	}
+
+	keep := "no"
+	if policy.keep {
+		keep = "yes"
+	}
+
+	fmt.Printf("Retention for %s (%s)\n", feed.Name, feed.Url)
+	fmt.Printf("Max age:                %s\n", maxAge)
+	fmt.Printf("Max posts:              %s\n", maxPosts)
+	fmt.Printf("Keep unread or starred: %s\n", keep)
+
+	return nil
+}
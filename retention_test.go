@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+// recentItem is an RSS item published days ago.
+func recentItem(n, days int) RSSItem {
+	v := testItem(n)
+	v.PubDate = time.Now().AddDate(0, 0, -days).Format(time.RFC1123Z)
+	return v
+}
+
+// postUrls lists the urls of the saved posts of the feed at url, newest
+// first.
+func (e *testEnv) postUrls(url string) []string {
+	e.t.Helper()
+
+	posts, err := e.s.db.GetPostsForPruning(context.Background(), e.feed(url).ID)
+
+	if err != nil {
+		e.t.Fatal(err)
+	}
+
+	var urls []string
+	for _, v := range posts {
+		urls = append(urls, v.Url)
+	}
+
+	return urls
+}
+
+// postID returns the id of the post of the feed at feedURL saved from url.
+func (e *testEnv) postID(feedURL, url string) uuid.UUID {
+	e.t.Helper()
+
+	posts, err := e.s.db.GetPostsForPruning(context.Background(), e.feed(feedURL).ID)
+
+	if err != nil {
+		e.t.Fatal(err)
+	}
+
+	for _, v := range posts {
+		if v.Url == url {
+			return v.ID
+		}
+	}
+
+	e.t.Fatalf("%s has no post %s", feedURL, url)
+	return uuid.Nil
+}
+
+func (e *testEnv) markRead(user string, postID uuid.UUID) {
+	e.t.Helper()
+
+	err := e.s.db.SetPostRead(context.Background(),
+		database.SetPostReadParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    e.user(user).ID,
+			PostID:    postID,
+			Read:      true,
+		})
+
+	if err != nil {
+		e.t.Fatal(err)
+	}
+}
+
+func postUrl(n int) string {
+	return fmt.Sprintf("https://example.com/posts/%d", n)
+}
+
+func TestSaveFeedsMaxAge(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed := newTestFeed(t, recentItem(1, 10), recentItem(2, 100))
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.must("", "feed", "retention", feed.URL, "--max-age", "30")
+		e.fetchAll()
+
+		// the old item would only be pruned again
+		if got := e.postUrls(feed.URL); !slices.Equal(got, []string{postUrl(1)}) {
+			t.Errorf("saved %v, want only the recent post", got)
+		}
+
+		// but not when unread posts are kept, which new posts are
+		e.must("", "feed", "retention", feed.URL, "--keep-unread-or-starred", "true")
+		feed.set(recentItem(1, 10), recentItem(2, 100), recentItem(3, 200))
+		e.fetchAll()
+
+		if got := e.postUrls(feed.URL); !slices.Equal(got, []string{postUrl(1), postUrl(2), postUrl(3)}) {
+			t.Errorf("saved %v under a keep policy, want every post", got)
+		}
+	})
+}
+
+func TestPruneMaxPosts(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed := newTestFeed(t, testItem(1), testItem(2), testItem(3), testItem(4), testItem(5))
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.fetchAll()
+		e.must("", "feed", "retention", feed.URL, "--max-posts", "2")
+
+		out := e.must("", "prune", "--dry-run")
+
+		if !strings.Contains(out, "Test feed: 3 posts") || !strings.Contains(out, "Post 1") || strings.Contains(out, "Post 5") ||
+			!strings.Contains(out, "Would delete 3 posts from 1 feeds") {
+			t.Errorf("prune --dry-run printed\n%s", out)
+		}
+
+		if n := len(e.postUrls(feed.URL)); n != 5 {
+			t.Fatalf("dry run left %d posts, want 5", n)
+		}
+
+		if out := e.must("", "prune"); !strings.Contains(out, "Deleted 3 posts from 1 feeds") {
+			t.Errorf("prune printed\n%s", out)
+		}
+
+		want := []string{postUrl(5), postUrl(4)}
+
+		if got := e.postUrls(feed.URL); !slices.Equal(got, want) {
+			t.Fatalf("prune kept %v, want %v", got, want)
+		}
+
+		// the feed still lists the pruned posts, they don't come back
+		e.fetchAll()
+
+		if got := e.postUrls(feed.URL); !slices.Equal(got, want) {
+			t.Errorf("fetching again saved %v, want %v", got, want)
+		}
+
+		// and a new post pushes the oldest one out as it is saved
+		feed.set(testItem(1), testItem(2), testItem(3), testItem(4), testItem(5), testItem(6))
+		e.fetchAll()
+		e.must("", "prune")
+
+		if got := e.postUrls(feed.URL); !slices.Equal(got, []string{postUrl(6), postUrl(5)}) {
+			t.Errorf("after a new post the feed has %v", got)
+		}
+	})
+}
+
+func TestPruneMaxAge(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed := newTestFeed(t, recentItem(1, 10), recentItem(2, 40), recentItem(3, 100))
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.fetchAll()
+		e.must("", "feed", "retention", feed.URL, "--max-age", "30")
+
+		if out := e.must("", "prune"); !strings.Contains(out, "Deleted 2 posts from 1 feeds") {
+			t.Errorf("prune printed\n%s", out)
+		}
+
+		if got := e.postUrls(feed.URL); !slices.Equal(got, []string{postUrl(1)}) {
+			t.Errorf("prune kept %v, want only the recent post", got)
+		}
+	})
+}
+
+// With keep_unread_or_starred only posts someone read and nobody starred go.
+func TestPruneKeepsUnreadOrStarred(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed := newTestFeed(t, testItem(1), testItem(2), testItem(3), testItem(4))
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.fetchAll()
+		e.must("", "feed", "retention", feed.URL, "--max-posts", "1", "--keep-unread-or-starred", "true")
+
+		e.markRead("alice", e.postID(feed.URL, postUrl(1)))
+		e.markRead("alice", e.postID(feed.URL, postUrl(2)))
+
+		err := e.s.db.SetPostStarred(context.Background(),
+			database.SetPostStarredParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				UserID:    e.user("alice").ID,
+				PostID:    e.postID(feed.URL, postUrl(2)),
+				Starred:   true,
+			})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if out := e.must("", "prune"); !strings.Contains(out, "Deleted 1 posts from 1 feeds") {
+			t.Errorf("prune printed\n%s", out)
+		}
+
+		// 1 was read and isn't starred; 2 is starred, 3 unread, 4 the newest
+		want := []string{postUrl(4), postUrl(3), postUrl(2)}
+
+		if got := e.postUrls(feed.URL); !slices.Equal(got, want) {
+			t.Errorf("prune kept %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/w0/aggregator/internal/database"
+)
+
+const (
+	roleAdmin  = "admin"
+	roleMember = "member"
+)
+
+func handlerPromote(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf("usage: aggregator promote <username>")
+	}
+
+	return setRole(s, cmd.arguments[0], roleAdmin)
+}
+
+func handlerDemote(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf("usage: aggregator demote <username>")
+	}
+
+	target, err := s.db.GetUser(context.Background(), cmd.arguments[0])
+
+	if err != nil {
+		return fmt.Errorf("user %s not found: %w", cmd.arguments[0], err)
+	}
+
+	if target.Role == roleAdmin {
+		admins, err := s.db.CountAdmins(context.Background())
+
+		if err != nil {
+			return fmt.Errorf("failed counting admins: %w", err)
+		}
+
+		if admins <= 1 {
+			return fmt.Errorf("%s is the last admin, promote someone else first", target.Name)
+		}
+	}
+
+	return setRole(s, target.Name, roleMember)
+}
+
+func setRole(s *state, username, role string) error {
+	target, err := s.db.GetUser(context.Background(), username)
+
+	if err != nil {
+		return fmt.Errorf("user %s not found: %w", username, err)
+	}
+
+	if target.Role == role {
+		fmt.Printf("%s is already a %s\n", target.Name, role)
+		return nil
+	}
+
+	err = s.db.SetUserRole(context.Background(),
+		database.SetUserRoleParams{
+			Role:      role,
+			UpdatedAt: time.Now(),
+			ID:        target.ID,
+		})
+
+	if err != nil {
+		return fmt.Errorf("failed changing role: %w", err)
+	}
+
+	fmt.Printf("%s is now a %s\n", target.Name, role)
+
+	return nil
+}
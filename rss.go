@@ -4,11 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"html"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,56 +20,112 @@ import (
 
 type RSSFeed struct {
 	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
+		Title string `xml:"title"`
+		// Links comes before Link so atom:link elements don't
+		// overwrite the channel's own link.
+		Links       []atomLink `xml:"http://www.w3.org/2005/Atom link"`
+		Link        string     `xml:"link"`
+		Description string     `xml:"description"`
+		Item        []RSSItem  `xml:"item"`
 	} `xml:"channel"`
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories  []string `xml:"category"`
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+// fetchInfo describes the response a fetch got, for the fetch history.
+type fetchInfo struct {
+	status   int
+	bytes    int64
+	duration time.Duration
+}
+
+func fetchFeed(ctx context.Context, s *state, feed database.Feed) (_ *RSSFeed, info fetchInfo, _ error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", feed.Url, nil)
+
+	if err != nil {
+		return &RSSFeed{}, info, err
+	}
+
+	creds, err := s.db.GetFeedCredentials(ctx, feed.ID)
+
+	if err != nil {
+		return &RSSFeed{}, info, fmt.Errorf("failed getting credentials: %w", countDBError(err))
+	}
+
+	creds, err = openCredentials(s.cfg, feed, creds)
 
 	if err != nil {
-		return &RSSFeed{}, err
+		return &RSSFeed{}, info, fmt.Errorf("failed opening credentials: %w", err)
 	}
 
-	req.Header.Add("User-Agent", "gator")
+	req.Header.Add("User-Agent", userAgent(s.cfg))
+	setCredentials(req, creds)
 
-	client := http.Client{}
+	host := req.URL.Host
 
-	res, err := client.Do(req)
+	release, err := s.hosts.acquire(ctx, host)
 
 	if err != nil {
-		return &RSSFeed{}, err
+		return &RSSFeed{}, info, err
+	}
+
+	defer release()
+
+	// time spent waiting for the host's turn doesn't count
+	start := time.Now()
+	defer func() {
+		info.duration = time.Since(start)
+		fetchDuration.Observe(info.duration.Seconds())
+	}()
+
+	res, err := s.client.Do(req)
+
+	if err != nil {
+		return &RSSFeed{}, info, err
 	}
 
 	defer res.Body.Close()
 
+	info.status = res.StatusCode
+
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+		if until := s.hosts.backOff(host, res); !until.IsZero() {
+			return &RSSFeed{}, info, fmt.Errorf("unexpected status %s, retrying %s after %s", res.Status, host, until.Format(time.DateTime))
+		}
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &RSSFeed{}, info, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
 	body, err := io.ReadAll(res.Body)
 
+	info.bytes = int64(len(body))
+	fetchBytes.Add(float64(info.bytes))
+
 	if err != nil {
-		return &RSSFeed{}, err
+		return &RSSFeed{}, info, err
 	}
 
-	var feed RSSFeed
+	var rssFeed RSSFeed
 
-	err = xml.Unmarshal(body, &feed)
+	err = xml.Unmarshal(body, &rssFeed)
 
 	if err != nil {
-		return &RSSFeed{}, err
+		return &RSSFeed{}, info, err
 	}
 
-	unescapeHTML(&feed)
+	unescapeHTML(&rssFeed)
 
-	return &feed, nil
+	return &rssFeed, info, nil
 
 }
 
@@ -80,16 +139,147 @@ func unescapeHTML(feed *RSSFeed) {
 	}
 }
 
-func scrapeFeeds(s *state) error {
-	feed, err := s.db.GetNextFeedToFetch(context.Background())
+// defaultFetchWorkers is how many feeds agg fetches on each tick.
+const defaultFetchWorkers = 4
+
+// scrapeFeeds fetches and saves the next n feeds in line at the same time,
+// and returns once they are all done. Feeds on the same host still wait
+// their turn with the host limiter. What happens to each feed is logged by
+// scrapeFeed; the error is for failing to get the feeds at all.
+func scrapeFeeds(s *state, prune bool, n int) error {
+	now := time.Now()
+
+	// feeds a hub pushes to only need the occasional poll
+	feeds, err := s.db.GetNextFeedsToFetch(context.Background(),
+		database.GetNextFeedsToFetchParams{
+			LastFetchedAt:  sql.NullTime{Time: now.Add(-pushedPollInterval), Valid: true},
+			LeaseExpiresAt: sql.NullTime{Time: now, Valid: true},
+			Limit:          int32(n),
+		})
 
 	if err != nil {
-		return fmt.Errorf("failed getting next feed. %w", err)
+		return fmt.Errorf("failed getting next feeds. %w", countDBError(err))
 	}
 
+	var wg sync.WaitGroup
+
+	for _, feed := range feeds {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			scrapeFeed(s, feed, prune)
+		}()
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+func scrapeFeed(s *state, feed database.Feed, prune bool) {
 	now := time.Now()
 
-	err = s.db.MarkFeedFetched(context.Background(),
+	log := feedLogger(feed)
+	log.Debug("fetching feed")
+
+	rssFeed, info, err := fetchFeed(context.Background(), s, feed)
+	duration := info.duration
+
+	// nothing was sent, so it isn't a failed fetch, but the feed still
+	// goes to the back of the line
+	if errors.Is(err, errBackOff) {
+		log.Warn("skipping feed", "error", err)
+
+		if markErr := markFetched(s.db, feed, now); markErr != nil {
+			log.Error("failed marking feed fetched", "error", countDBError(markErr))
+		}
+
+		return
+	}
+
+	fetch := database.CreateFeedFetchParams{
+		ID:         uuid.New(),
+		FeedID:     feed.ID,
+		StartedAt:  now,
+		DurationMs: int32(duration.Milliseconds()),
+		StatusCode: sql.NullInt32{Int32: int32(info.status), Valid: info.status != 0},
+		Bytes:      info.bytes,
+	}
+
+	result := "ok"
+
+	if err != nil {
+		err = fmt.Errorf("failed to fetch feed. %w", err)
+		result = "fetch_error"
+
+		log.Error("fetch failed", "duration", duration, "status", info.status, "error", err)
+	} else {
+		fetch.Items = int32(len(rssFeed.Channel.Item))
+
+		log.Info("fetched feed", "duration", duration, "status", info.status, "bytes", info.bytes, "items", fetch.Items)
+
+		var saved int
+		saved, _, err = saveFeeds(s, rssFeed, feed, now)
+		fetch.NewPosts = int32(saved)
+
+		if err != nil {
+			result = "save_error"
+		}
+	}
+
+	fetchesTotal.Inc(result)
+
+	if err != nil {
+		fetch.Error = sql.NullString{String: err.Error(), Valid: true}
+
+		// a feed that couldn't be fetched or saved is still marked as
+		// fetched, so it waits its turn like the others instead of being
+		// retried, and failing, on every tick. Nothing of it was saved, so
+		// the next fetch picks up the same items.
+		if markErr := markFetched(s.db, feed, now); markErr != nil {
+			log.Error("failed marking feed fetched", "error", countDBError(markErr))
+		}
+	}
+
+	recordErr := s.db.CreateFeedFetch(context.Background(), fetch)
+
+	if recordErr == nil {
+		_, recordErr = s.db.DeleteFeedFetchesBefore(context.Background(),
+			database.DeleteFeedFetchesBeforeParams{
+				FeedID:    feed.ID,
+				StartedAt: now.AddDate(0, 0, -fetchHistoryDays),
+			})
+	}
+
+	// the history is only for stats, so it doesn't fail the fetch
+	if recordErr != nil {
+		log.Warn("failed recording fetch", "error", countDBError(recordErr))
+	}
+
+	if err != nil {
+		return
+	}
+
+	if prune {
+		pruned, err := pruneFeed(s, feed, false)
+
+		if err != nil {
+			log.Error("prune failed", "error", err)
+		} else if len(pruned) > 0 {
+			log.Info("pruned feed", "posts", len(pruned))
+		}
+	}
+
+	err = subscribeWebSub(s, feed, rssFeed)
+
+	if err != nil {
+		log.Error("websub subscription failed", "error", err)
+	}
+}
+
+func markFetched(db database.Store, feed database.Feed, now time.Time) error {
+	err := db.MarkFeedFetched(context.Background(),
 		database.MarkFeedFetchedParams{
 			ID:        feed.ID,
 			UpdatedAt: now,
@@ -103,18 +293,69 @@ func scrapeFeeds(s *state) error {
 		return fmt.Errorf("failed updating fetched time. %w", err)
 	}
 
-	rssFeed, err := fetchFeed(context.Background(), feed.Url)
+	return nil
+}
+
+// saveFeeds saves the items of a fetch as posts of feed in one transaction,
+// applying filters to the new posts, and notifies webhooks once it commits.
+// Unless fetchedAt is zero the feed is marked fetched at that time in the
+// same transaction. It returns how many items were saved and how many were
+// skipped, because they were saved before, are past the feed's retention or
+// were pruned already.
+func saveFeeds(s *state, rss *RSSFeed, feed database.Feed, fetchedAt time.Time) (saved, skipped int, err error) {
+	ctx := context.Background()
+	start := time.Now()
+
+	defer func() {
+		log := feedLogger(feed).With("duration", time.Since(start))
+
+		if err != nil {
+			log.Error("save failed", "error", err)
+			return
+		}
+
+		log.Info("saved posts", "saved", saved, "skipped", skipped)
+	}()
+
+	filters, err := s.db.GetFiltersForFeed(ctx, feed.ID)
 
 	if err != nil {
-		return fmt.Errorf("failed to fetch feed. %w", err)
+		return 0, 0, fmt.Errorf("failed getting filters. %w", countDBError(err))
 	}
 
-	return saveFeeds(s, rssFeed, feed)
-}
+	retention, err := feedRetention(s, feed)
 
-func saveFeeds(s *state, rss *RSSFeed, feed database.Feed) error {
+	if err != nil {
+		return 0, 0, countDBError(err)
+	}
+
+	prunedUrls, err := s.db.GetPrunedUrlsForFeed(ctx, feed.ID)
+
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed getting pruned posts. %w", countDBError(err))
+	}
+
+	pruned := map[string]bool{}
+	for _, url := range prunedUrls {
+		pruned[url] = true
+	}
+
+	now := time.Now()
+
+	var params []database.CreatePostParams
+	var tooOld, wasPruned int
+	fields := map[string]postFields{}
+	listed := map[string]bool{}
 
 	for _, v := range rss.Channel.Item {
+		listed[v.Link] = true
+
+		// deleted by prune, saving it again would bring it back unread
+		if pruned[v.Link] {
+			wasPruned++
+			continue
+		}
+
 		publishedAt := sql.NullTime{}
 
 		if time, err := time.Parse(time.RFC1123Z, v.PubDate); err == nil {
@@ -122,28 +363,144 @@ func saveFeeds(s *state, rss *RSSFeed, feed database.Feed) error {
 			publishedAt.Valid = true
 		}
 
-		now := time.Now()
-		_, err := s.db.CreatePost(context.Background(),
-			database.CreatePostParams{
-				ID:        uuid.New(),
-				CreatedAt: now,
-				UpdatedAt: now,
-				Title:     v.Title,
-				Url:       v.Link,
-				Description: sql.NullString{
-					String: v.Description,
-					Valid:  true,
-				},
-				PublishedAt: publishedAt,
-				FeedID:      feed.ID,
-			})
+		// posts past the max age would only be pruned again, unless the
+		// policy keeps them for being unread, which new posts are
+		if publishedAt.Valid && retention.tooOld(publishedAt.Time, now) && !retention.keep {
+			tooOld++
+			continue
+		}
+
+		// the same link twice in one feed
+		if _, ok := fields[v.Link]; ok {
+			continue
+		}
+
+		author := v.Author
+		if author == "" {
+			author = v.Creator
+		}
+
+		params = append(params, database.CreatePostParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			Title:     v.Title,
+			Url:       v.Link,
+			Description: sql.NullString{
+				String: v.Description,
+				Valid:  true,
+			},
+			PublishedAt: publishedAt,
+			FeedID:      feed.ID,
+			Author: sql.NullString{
+				String: author,
+				Valid:  author != "",
+			},
+			Categories: sql.NullString{
+				String: strings.Join(v.Categories, "\n"),
+				Valid:  len(v.Categories) > 0,
+			},
+		})
+
+		fields[v.Link] = postFields{
+			feed:        []string{feed.Name, feed.Url},
+			title:       v.Title,
+			description: v.Description,
+			author:      author,
+			categories:  v.Categories,
+		}
+	}
+
+	if retention.maxPosts > 0 && !retention.keep {
+		var existing []database.GetPostsForPruningRow
+
+		existing, err = s.db.GetPostsForPruning(ctx, feed.ID)
+
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed getting posts. %w", countDBError(err))
+		}
+
+		// posts past the max posts would only be pruned again
+		past := retention.pastMaxPosts(existing, params)
+
+		params = slices.DeleteFunc(params, func(v database.CreatePostParams) bool { return past[v.Url] })
+		wasPruned += len(past)
+	}
+
+	var created []newPost
+
+	err = s.db.InTx(ctx, func(tx database.Store) error {
+		// posts whose url is already saved aren't returned
+		posts, err := tx.CreatePosts(ctx, params)
+
 		if err != nil {
-			if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+			return fmt.Errorf("failed saving posts. %w", err)
+		}
+
+		inserted := map[string]database.Post{}
+		for _, post := range posts {
+			inserted[post.Url] = post
+		}
+
+		// in the order of the feed rather than the order rows came back in
+		for _, v := range params {
+			post, ok := inserted[v.Url]
+
+			if !ok {
 				continue
 			}
-			return err
+
+			_, err = applyFilters(tx, filters, post.ID, fields[post.Url])
+
+			if err != nil {
+				return err
+			}
+
+			created = append(created, newPost{post: post, fields: fields[post.Url]})
 		}
+
+		if fetchedAt.IsZero() {
+			return nil
+		}
+
+		// a fetch has the whole feed, so pruned posts it no longer lists
+		// can't come back and need no record
+		for url := range pruned {
+			if listed[url] {
+				continue
+			}
+
+			err = tx.DeletePrunedUrl(ctx,
+				database.DeletePrunedUrlParams{
+					FeedID: feed.ID,
+					Url:    url,
+				})
+
+			if err != nil {
+				return fmt.Errorf("failed forgetting pruned post. %w", err)
+			}
+		}
+
+		return markFetched(tx, feed, fetchedAt)
+	})
+
+	if err != nil {
+		return 0, 0, countDBError(err)
 	}
 
-	return nil
+	duplicates := len(rss.Channel.Item) - tooOld - wasPruned - len(created)
+
+	postsInserted.Add(float64(len(created)))
+	postsSkipped.Add(float64(duplicates), "duplicate")
+	postsSkipped.Add(float64(tooOld), "too_old")
+	postsSkipped.Add(float64(wasPruned), "pruned")
+
+	// the posts are saved by now, so webhooks failing doesn't fail the save
+	err = notifyWebhooks(s, feed, filters, created)
+
+	if err != nil {
+		feedLogger(feed).Error("failed notifying webhooks", "error", err)
+	}
+
+	return len(created), duplicates + tooOld + wasPruned, nil
 }
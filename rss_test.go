@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/config"
+	"github.com/w0/aggregator/internal/database"
+)
+
+func TestScrapeFeeds(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed := newTestFeed(t, testItem(1), testItem(2))
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		e.fetchAll()
+
+		if ua := feed.lastRequest(t).Header.Get("User-Agent"); ua != userAgent(e.s.cfg) {
+			t.Errorf("fetched with User-Agent %q, want %q", ua, userAgent(e.s.cfg))
+		}
+
+		// fetching the same items again doesn't save them twice, and a
+		// new item is added to those already saved
+		feed.set(testItem(1), testItem(2), testItem(3))
+		e.fetchAll()
+
+		out := e.must("", "browse", "10")
+
+		if n := strings.Count(out, "Link: "); n != 3 {
+			t.Fatalf("browse showed %d posts, want 3:\n%s", n, out)
+		}
+
+		for _, v := range []string{"Post 1", "Post 2", "Post 3"} {
+			if strings.Count(out, "--- "+v+" ---") != 1 {
+				t.Errorf("%s isn't shown once:\n%s", v, out)
+			}
+		}
+
+		got := e.feed(feed.URL)
+
+		if !got.LastFetchedAt.Valid {
+			t.Error("feed wasn't marked fetched")
+		}
+
+		if fetches := e.fetches(got); len(fetches) != 2 {
+			t.Errorf("recorded %d fetches, want 2", len(fetches))
+		}
+	})
+}
+
+func TestScrapeFeedsFailure(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "down", http.StatusInternalServerError)
+		}))
+		t.Cleanup(srv.Close)
+
+		e.register("alice")
+		e.must("", "addfeed", "Broken feed", srv.URL)
+		e.fetchAll()
+
+		feed := e.feed(srv.URL)
+
+		// a failed feed goes to the back of the line like the others
+		if !feed.LastFetchedAt.Valid {
+			t.Error("failed feed wasn't marked fetched")
+		}
+
+		fetches := e.fetches(feed)
+
+		if len(fetches) != 1 || !fetches[0].Error.Valid || fetches[0].StatusCode.Int32 != http.StatusInternalServerError {
+			t.Errorf("recorded %+v, want one failed fetch with status 500", fetches)
+		}
+	})
+}
+
+// Fetches older than the stats can look at are deleted as new ones come in.
+func TestScrapeFeedsDropsOldFetches(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		srv := newTestFeed(t, testItem(1))
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", srv.URL)
+		feed := e.feed(srv.URL)
+
+		now := time.Now()
+		old := uuid.New()
+		recent := uuid.New()
+
+		for id, days := range map[uuid.UUID]int{old: fetchHistoryDays + 1, recent: fetchHistoryDays - 1} {
+			err := e.s.db.CreateFeedFetch(context.Background(),
+				database.CreateFeedFetchParams{
+					ID:        id,
+					FeedID:    feed.ID,
+					StartedAt: now.AddDate(0, 0, -days),
+				})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		e.fetchAll()
+
+		var ids []uuid.UUID
+		for _, v := range e.fetches(feed) {
+			ids = append(ids, v.ID)
+		}
+
+		if len(ids) != 2 || slices.Contains(ids, old) || !slices.Contains(ids, recent) {
+			t.Errorf("fetch history is %v, want the new fetch and %s but not %s", ids, recent, old)
+		}
+
+		if err := e.fails("", "feed", "stats", srv.URL, "--days", strconv.Itoa(fetchHistoryDays+1)); !strings.Contains(err.Error(), "at most") {
+			t.Errorf("feed stats past the history returned %v", err)
+		}
+	})
+}
+
+// busyFeed serves empty feeds and records how many requests it had in
+// flight at once. Each request waits for want of them to be in flight, or
+// for a while when they never are.
+type busyFeed struct {
+	*httptest.Server
+
+	want int
+
+	mu       sync.Mutex
+	inFlight int
+	most     int
+}
+
+func newBusyFeed(t *testing.T, want int) *busyFeed {
+	f := &busyFeed{want: want}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.inFlight++
+		f.most = max(f.most, f.inFlight)
+		f.mu.Unlock()
+
+		for deadline := time.Now().Add(250 * time.Millisecond); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			f.mu.Lock()
+			n := f.inFlight
+			f.mu.Unlock()
+
+			if n >= f.want {
+				break
+			}
+		}
+
+		f.mu.Lock()
+		f.inFlight--
+		f.mu.Unlock()
+
+		fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Busy feed</title></channel></rss>`)
+	}))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *busyFeed) mostInFlight() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.most
+}
+
+// agg fetches several feeds at a time, but no more from one host than
+// host_concurrency allows.
+func TestScrapeFeedsConcurrently(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		for _, tc := range []struct {
+			hostConcurrency int
+			want            int
+		}{
+			{3, 3},
+			{1, 1},
+		} {
+			hosts, err := newHostLimiter(&config.Fetch{HostConcurrency: tc.hostConcurrency, HostDelay: "0s"})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			e.s.hosts = hosts
+			srv := newBusyFeed(t, 3)
+
+			e.register(fmt.Sprintf("user%d", tc.hostConcurrency))
+
+			for i := range 3 {
+				e.must("", "addfeed", fmt.Sprintf("Feed %d", i), fmt.Sprintf("%s/%d", srv.URL, i))
+			}
+
+			if err := scrapeFeeds(e.s, false, 3); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := srv.mostInFlight(); got != tc.want {
+				t.Errorf("with host_concurrency %d the host had %d fetches at once, want %d", tc.hostConcurrency, got, tc.want)
+			}
+
+			for i := range 3 {
+				if feed := e.feed(fmt.Sprintf("%s/%d", srv.URL, i)); !feed.LastFetchedAt.Valid {
+					t.Errorf("%s wasn't fetched", feed.Url)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"html/template"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// allowedTags lists the elements kept by sanitizeHTML and the attributes each
+// may carry. Everything else is dropped, keeping the text inside.
+var allowedTags = map[string][]string{
+	"a":          {"href", "title"},
+	"b":          nil,
+	"blockquote": nil,
+	"br":         nil,
+	"code":       nil,
+	"em":         nil,
+	"figcaption": nil,
+	"figure":     nil,
+	"h1":         nil,
+	"h2":         nil,
+	"h3":         nil,
+	"h4":         nil,
+	"h5":         nil,
+	"h6":         nil,
+	"hr":         nil,
+	"i":          nil,
+	"img":        {"src", "alt", "title"},
+	"li":         nil,
+	"ol":         nil,
+	"p":          nil,
+	"pre":        nil,
+	"strong":     nil,
+	"table":      nil,
+	"tbody":      nil,
+	"td":         nil,
+	"th":         nil,
+	"thead":      nil,
+	"tr":         nil,
+	"ul":         nil,
+}
+
+// droppedTags are removed together with their content.
+var droppedTags = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"iframe":   true,
+	"object":   true,
+	"embed":    true,
+	"noscript": true,
+	"template": true,
+}
+
+// sanitizeHTML strips feed supplied html down to allowedTags so it can be
+// rendered in the web ui.
+func sanitizeHTML(s string) template.HTML {
+	var out strings.Builder
+
+	z := html.NewTokenizer(strings.NewReader(s))
+	skip := 0
+
+	for {
+		tt := z.Next()
+
+		switch tt {
+		case html.ErrorToken:
+			return template.HTML(out.String())
+		case html.TextToken:
+			if skip == 0 {
+				out.WriteString(html.EscapeString(string(z.Text())))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+
+			if droppedTags[t.Data] {
+				// embed has no end tag to stop skipping at
+				if tt == html.StartTagToken && t.Data != "embed" {
+					skip++
+				}
+				continue
+			}
+
+			attrs, ok := allowedTags[t.Data]
+			if skip > 0 || !ok {
+				continue
+			}
+
+			out.WriteString("<" + t.Data)
+			for _, a := range t.Attr {
+				if !slices.Contains(attrs, a.Key) {
+					continue
+				}
+
+				if (a.Key == "href" || a.Key == "src") && !safeURL(a.Val) {
+					continue
+				}
+
+				out.WriteString(" " + a.Key + `="` + html.EscapeString(a.Val) + `"`)
+			}
+
+			if t.Data == "a" {
+				out.WriteString(` rel="noopener noreferrer nofollow"`)
+			}
+			out.WriteString(">")
+		case html.EndTagToken:
+			t := z.Token()
+
+			if droppedTags[t.Data] {
+				if skip > 0 {
+					skip--
+				}
+				continue
+			}
+
+			if _, ok := allowedTags[t.Data]; ok && skip == 0 {
+				out.WriteString("</" + t.Data + ">")
+			}
+		}
+	}
+}
+
+// safeURL only lets through http, https and relative links.
+func safeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https":
+		return true
+	}
+
+	return false
+}
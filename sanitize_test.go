@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestSanitizeHTML(t *testing.T) {
+	const rel = ` rel="noopener noreferrer nofollow"`
+
+	for _, tc := range []struct {
+		name, in, want string
+	}{
+		{"allowed tags", `<p>Hello <b>world</b><br></p>`, `<p>Hello <b>world</b><br></p>`},
+		{"other tags keep their text", `<div><span class="x">text</span></div>`, `text`},
+		{"text is escaped", `1 &lt; 2 &amp; "q"`, `1 &lt; 2 &amp; &#34;q&#34;`},
+
+		{"script", `<script>alert(1)</script><p>after</p>`, `<p>after</p>`},
+		{"script in capitals", `<SCRIPT>alert(1)</SCRIPT>ok`, `ok`},
+		{"unclosed script", `before<script>alert(1)`, `before`},
+		{"style", `<style>p { color: red }</style>text`, `text`},
+		{"iframe with content", `<iframe src="https://example.com"><p>inside</p></iframe>after`, `after`},
+		{"embed has no end tag", `<embed src="x.swf"><p>after</p>`, `<p>after</p>`},
+		{"nested dropped tags", `<object><embed src="x.swf"><p>inside</p></object>after`, `after`},
+
+		{"event handlers", `<p onclick="alert(1)" onMouseOver="alert(2)">hi</p>`, `<p>hi</p>`},
+		{"onerror on an image", `<img src="a.png" onerror="alert(1)" alt="a">`, `<img src="a.png" alt="a">`},
+		{"other attributes", `<p style="color: red" class="x" id="y">hi</p>`, `<p>hi</p>`},
+
+		{"http link", `<a href="https://example.com/?a=1&amp;b=2" title="t">x</a>`, `<a href="https://example.com/?a=1&amp;b=2" title="t"` + rel + `>x</a>`},
+		{"relative link", `<a href="/posts/1">x</a>`, `<a href="/posts/1"` + rel + `>x</a>`},
+		{"javascript href", `<a href="javascript:alert(1)">x</a>`, `<a` + rel + `>x</a>`},
+		{"javascript href in capitals", `<a href="JavaScript:alert(1)">x</a>`, `<a` + rel + `>x</a>`},
+		{"javascript href after spaces", `<a href="  javascript:alert(1)">x</a>`, `<a` + rel + `>x</a>`},
+		{"javascript href as entities", `<a href="&#106;avascript:alert(1)">x</a>`, `<a` + rel + `>x</a>`},
+		{"javascript href with a tab", `<a href="java&#x09;script:alert(1)">x</a>`, `<a` + rel + `>x</a>`},
+		{"vbscript href", `<a href="vbscript:msgbox(1)">x</a>`, `<a` + rel + `>x</a>`},
+		{"data href", `<a href="data:text/html,<script>alert(1)</script>">x</a>`, `<a` + rel + `>x</a>`},
+		{"javascript src", `<img src="javascript:alert(1)" alt="a">`, `<img alt="a">`},
+		{"data src", `<img src="data:image/svg+xml;base64,PHN2Zz4=">`, `<img>`},
+		{"http src", `<img src="http://example.com/a.png">`, `<img src="http://example.com/a.png">`},
+		{"quotes in attributes", `<img alt="&quot;><script>alert(1)</script>">`, `<img alt="&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;">`},
+	} {
+		if got := string(sanitizeHTML(tc.in)); got != tc.want {
+			t.Errorf("%s: sanitizeHTML(%q) = %q, want %q", tc.name, tc.in, got, tc.want)
+		}
+	}
+}
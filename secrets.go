@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/config"
+	"github.com/w0/aggregator/internal/database"
+)
+
+// sealedPrefix marks a secret sealed with AES-256-GCM, so the format can
+// change later.
+const sealedPrefix = "v1:"
+
+var errNoCredentialsKey = errors.New(`feed credentials need a key, add "credentials_key" to the fetch settings in .gatorconfig.json, for example the output of openssl rand -base64 32`)
+
+// credentialsKey returns the key feed credentials are encrypted with.
+func credentialsKey(cfg *config.Config) ([]byte, error) {
+	if cfg.Fetch == nil || cfg.Fetch.CredentialsKey == "" {
+		return nil, errNoCredentialsKey
+	}
+
+	key, err := base64.StdEncoding.DecodeString(cfg.Fetch.CredentialsKey)
+
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("credentials_key must be 32 bytes encoded in base64")
+	}
+
+	return key, nil
+}
+
+// sealSecret encrypts a credential of feed. The feed's id is authenticated
+// along with it, so a sealed secret copied to another feed doesn't open.
+func sealSecret(key []byte, feedID uuid.UUID, secret string) (string, error) {
+	gcm, err := newGCM(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+
+	_, err = rand.Read(nonce)
+
+	if err != nil {
+		return "", fmt.Errorf("failed generating nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), feedID[:])
+
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func openSecret(key []byte, feedID uuid.UUID, sealed string) (string, error) {
+	data, ok := strings.CutPrefix(sealed, sealedPrefix)
+
+	if !ok {
+		return "", fmt.Errorf("credential isn't encrypted, set it again with aggregator feed auth")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(data)
+
+	if err != nil {
+		return "", fmt.Errorf("failed decoding credential: %w", err)
+	}
+
+	gcm, err := newGCM(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(raw) < gcm.NonceSize() {
+		return "", fmt.Errorf("credential is too short")
+	}
+
+	nonce, ciphertext := raw[:gcm.NonceSize()], raw[gcm.NonceSize():]
+
+	secret, err := gcm.Open(nil, nonce, ciphertext, feedID[:])
+
+	if err != nil {
+		return "", fmt.Errorf("failed decrypting credential, was credentials_key changed?")
+	}
+
+	return string(secret), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials key: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// openCredentials decrypts the secrets of a feed's credentials.
+func openCredentials(cfg *config.Config, feed database.Feed, creds []database.FeedCredential) ([]database.FeedCredential, error) {
+	if len(creds) == 0 {
+		return creds, nil
+	}
+
+	key, err := credentialsKey(cfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i, c := range creds {
+		secret, err := openSecret(key, feed.ID, c.Secret)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", c.Kind, c.Name, err)
+		}
+
+		creds[i].Secret = secret
+	}
+
+	return creds, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/config"
+	"github.com/w0/aggregator/internal/database"
+)
+
+var testKey = bytes.Repeat([]byte{7}, 32)
+
+func TestSealSecret(t *testing.T) {
+	feedID := uuid.New()
+
+	sealed, err := sealSecret(testKey, feedID, "hunter2")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(sealed, sealedPrefix) || strings.Contains(sealed, "hunter2") {
+		t.Errorf("sealed secret is %q", sealed)
+	}
+
+	got, err := openSecret(testKey, feedID, sealed)
+
+	if err != nil || got != "hunter2" {
+		t.Errorf("opened %q, %v", got, err)
+	}
+
+	// every seal has its own nonce
+	if again, _ := sealSecret(testKey, feedID, "hunter2"); again == sealed {
+		t.Error("sealing the same secret twice gave the same result")
+	}
+}
+
+func TestOpenSecretFails(t *testing.T) {
+	feedID := uuid.New()
+
+	sealed, err := sealSecret(testKey, feedID, "hunter2")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedPrefix))
+	raw[len(raw)-1] ^= 1
+	tampered := sealedPrefix + base64.StdEncoding.EncodeToString(raw)
+
+	for _, tc := range []struct {
+		name   string
+		key    []byte
+		feedID uuid.UUID
+		sealed string
+	}{
+		// a secret copied to another feed's row doesn't open there
+		{"another feed", testKey, uuid.New(), sealed},
+		{"another key", bytes.Repeat([]byte{8}, 32), feedID, sealed},
+		{"tampered", testKey, feedID, tampered},
+		{"not sealed", testKey, feedID, "hunter2"},
+		{"not base64", testKey, feedID, sealedPrefix + "!!!"},
+		{"too short", testKey, feedID, sealedPrefix + base64.StdEncoding.EncodeToString([]byte("short"))},
+		{"bad key", []byte("short"), feedID, sealed},
+	} {
+		if got, err := openSecret(tc.key, tc.feedID, tc.sealed); err == nil {
+			t.Errorf("%s: opened %q", tc.name, got)
+		}
+	}
+}
+
+func TestCredentialsKey(t *testing.T) {
+	if _, err := credentialsKey(&config.Config{}); err != errNoCredentialsKey {
+		t.Errorf("no fetch settings: %v", err)
+	}
+
+	if _, err := credentialsKey(&config.Config{Fetch: &config.Fetch{CredentialsKey: base64.StdEncoding.EncodeToString(testKey[:16])}}); err == nil {
+		t.Error("a 16 byte key was accepted")
+	}
+
+	if _, err := credentialsKey(&config.Config{Fetch: &config.Fetch{CredentialsKey: "not base64"}}); err == nil {
+		t.Error("a key that isn't base64 was accepted")
+	}
+
+	cfg := &config.Config{Fetch: &config.Fetch{CredentialsKey: base64.StdEncoding.EncodeToString(testKey)}}
+
+	key, err := credentialsKey(cfg)
+
+	if err != nil || !bytes.Equal(key, testKey) {
+		t.Fatalf("key is %v, %v", key, err)
+	}
+
+	feed := database.Feed{ID: uuid.New()}
+	sealed, err := sealSecret(key, feed.ID, "hunter2")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := openCredentials(cfg, feed, []database.FeedCredential{{Kind: credBearer, Secret: sealed}})
+
+	if err != nil || creds[0].Secret != "hunter2" {
+		t.Errorf("opened credentials %+v, %v", creds, err)
+	}
+}
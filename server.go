@@ -0,0 +1,118 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/w0/aggregator/internal/database"
+)
+
+type server struct {
+	s *state
+}
+
+// authedHandler is an http handler that runs on behalf of an authenticated user.
+type authedHandler func(w http.ResponseWriter, r *http.Request, user database.User)
+
+func handlerServe(s *state, cmd command) error {
+	addr := ":8080"
+	if len(cmd.arguments) > 0 {
+		addr = cmd.arguments[0]
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           newServer(s),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	slog.Info("serving", "addr", addr)
+
+	return srv.ListenAndServe()
+}
+
+func newServer(s *state) http.Handler {
+	srv := &server{s: s}
+	mux := http.NewServeMux()
+
+	srv.registerAPI(mux)
+	srv.registerWeb(mux)
+	srv.registerWebSub(mux)
+	mux.HandleFunc("GET /timeline.atom", srv.requireFeedToken(srv.handleTimeline("atom")))
+	mux.HandleFunc("GET /timeline.rss", srv.requireFeedToken(srv.handleTimeline("rss")))
+	mux.HandleFunc("/fever/", srv.handleFever)
+
+	return mux
+}
+
+// requireToken authenticates the request with an api token passed as
+// "Authorization: Bearer <token>".
+func (srv *server) requireToken(next authedHandler) http.HandlerFunc {
+	return srv.authenticate(next, false)
+}
+
+// requireFeedToken is requireToken for the published feeds, which also take
+// the token as the token query parameter since feed readers can rarely set
+// headers. Anywhere else a token in the url would only end up in logs.
+func (srv *server) requireFeedToken(next authedHandler) http.HandlerFunc {
+	return srv.authenticate(next, true)
+}
+
+func (srv *server) authenticate(next authedHandler, fromQuery bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		if !ok && fromQuery {
+			token = r.URL.Query().Get("token")
+		}
+
+		if token == "" {
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		user, err := srv.s.db.GetUserByApiToken(r.Context(), hashToken(token))
+
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+
+		err = srv.s.db.TouchApiToken(r.Context(),
+			database.TouchApiTokenParams{
+				LastUsedAt: sql.NullTime{
+					Time:  time.Now(),
+					Valid: true,
+				},
+				TokenHash: hashToken(token),
+			})
+
+		if err != nil {
+			writeInternalError(w, r, err)
+			return
+		}
+
+		next(w, r, user)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// writeInternalError logs err and answers with a generic message, so
+// database and other internal errors don't reach clients.
+func writeInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	slog.Error("request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+	writeError(w, http.StatusInternalServerError, "internal server error")
+}
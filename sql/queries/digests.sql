@@ -0,0 +1,33 @@
+-- name: SetDigestPrefs :one
+INSERT INTO digest_prefs (user_id, created_at, updated_at, email, frequency, group_by)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT (user_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    email = EXCLUDED.email,
+    frequency = EXCLUDED.frequency,
+    group_by = EXCLUDED.group_by
+RETURNING *;
+
+-- name: GetDigestPrefs :one
+SELECT * FROM digest_prefs
+WHERE user_id = $1;
+
+-- name: GetAllDigestPrefs :many
+SELECT * FROM digest_prefs
+ORDER BY created_at;
+
+-- name: DeleteDigestPrefs :execrows
+DELETE FROM digest_prefs
+WHERE user_id = $1;
+
+-- name: MarkDigestSent :exec
+UPDATE digest_prefs
+SET last_sent_at = $1
+WHERE user_id = $2;
@@ -0,0 +1,38 @@
+-- name: SetFeedCredential :one
+INSERT INTO feed_credentials (feed_id, created_at, updated_at, kind, name, secret)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT (feed_id, kind, name) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    secret = EXCLUDED.secret
+RETURNING *;
+
+-- name: GetFeedCredentials :many
+SELECT * FROM feed_credentials
+WHERE feed_id = $1
+ORDER BY kind, name;
+
+-- name: DeleteFeedCredential :execrows
+DELETE FROM feed_credentials
+WHERE feed_id = $1 AND kind = $2 AND name = $3;
+
+-- name: DeleteFeedCredentialsByKind :execrows
+DELETE FROM feed_credentials
+WHERE feed_id = $1 AND kind = $2;
+
+-- name: GetFeedIdsWithCredentials :many
+SELECT feed_id FROM feed_credentials
+GROUP BY feed_id;
+
+-- name: CountFeedCredentials :one
+SELECT COUNT(*) FROM feed_credentials
+WHERE feed_id = $1;
+
+-- name: GetAllFeedCredentials :many
+SELECT * FROM feed_credentials;
@@ -0,0 +1,25 @@
+-- name: CreateFeedFetch :exec
+INSERT INTO feed_fetches (id, feed_id, started_at, duration_ms, status_code, bytes, items, new_posts, error)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+);
+
+-- name: GetFeedFetchesSince :many
+SELECT * FROM feed_fetches
+WHERE feed_id = $1 AND started_at > $2
+ORDER BY started_at DESC;
+
+-- name: GetAllFeedFetches :many
+SELECT * FROM feed_fetches;
+
+-- name: DeleteFeedFetchesBefore :execrows
+DELETE FROM feed_fetches
+WHERE feed_id = $1 AND started_at < $2;
@@ -0,0 +1,32 @@
+-- name: AddFeedFollowGroup :exec
+INSERT INTO feed_follow_groups (id, created_at, feed_follow_id, name)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (feed_follow_id, name) DO NOTHING;
+
+-- name: RemoveFeedFollowGroup :execrows
+DELETE FROM feed_follow_groups
+WHERE feed_follow_id = $1 AND name = $2;
+
+-- name: DeleteGroupForUser :execrows
+DELETE FROM feed_follow_groups
+WHERE name = $1 AND feed_follow_id IN (
+    SELECT id FROM feed_follows WHERE user_id = $2
+);
+
+-- name: GetGroupsForUser :many
+SELECT feed_follow_groups.name,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url
+FROM feed_follow_groups
+INNER JOIN feed_follows ON feed_follows.id = feed_follow_groups.feed_follow_id
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY feed_follow_groups.name, feeds.name;
+
+-- name: GetAllFeedFollowGroups :many
+SELECT * FROM feed_follow_groups;
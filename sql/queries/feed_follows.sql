@@ -1,22 +1,15 @@
 -- name: CreateFeedFollow :one
-WITH inserted_feed_follow as (
-    INSERT INTO feed_follows(id, created_at, updated_at, user_id, feed_id)
-    VALUES (
-        $1,
-        $2,
-        $3,
-        $4,
-        $5
-    )
-    RETURNING *
+INSERT INTO feed_follows(id, created_at, updated_at, user_id, feed_id)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
 )
-SELECT
-    inserted_feed_follow.*,
-    feeds.name AS feed_name,
-    users.name AS user_name
-FROM inserted_feed_follow
-INNER JOIN users ON users.id = inserted_feed_follow.user_id
-INNER JOIN feeds ON feeds.id = inserted_feed_follow.feed_id;
+RETURNING *,
+    (SELECT feeds.name FROM feeds WHERE feeds.id = feed_follows.feed_id) AS feed_name,
+    (SELECT users.name FROM users WHERE users.id = feed_follows.user_id) AS user_name;
 
 -- name: GetFeedFollowsForUser :many
 SELECT *,
@@ -27,7 +20,38 @@ INNER JOIN users ON users.id = feed_follows.user_id
 INNER JOIN feeds ON feeds.id = feed_follows.feed_id
 WHERE feed_follows.user_id = $1;
 
--- name: DeleteFeedFollow :exec
+-- name: DeleteFeedFollow :execrows
 DELETE FROM feed_follows
-USING feeds, users
-WHERE users.id = $1 and feeds.url = $2;
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: GetFeedFollow :one
+SELECT * FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: GetAllFeedFollows :many
+SELECT * FROM feed_follows;
+
+-- name: GetUnreadCountsForUser :many
+SELECT feeds.id, feeds.name, feeds.url, COUNT(posts.id) AS unread
+FROM feed_follows
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+LEFT JOIN posts ON posts.feed_id = feeds.id
+    AND NOT EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.post_id = posts.id
+            AND post_states.user_id = feed_follows.user_id
+            AND (post_states.read OR post_states.hidden)
+    )
+WHERE feed_follows.user_id = $1
+GROUP BY feeds.id, feeds.name, feeds.url
+ORDER BY feeds.name;
+
+-- name: CountFeedFollowsForUser :one
+SELECT COUNT(*) FROM feed_follows
+WHERE user_id = $1;
+
+-- name: GetFollowersOfFeed :many
+SELECT users.* FROM users
+INNER JOIN feed_follows ON feed_follows.user_id = users.id
+WHERE feed_follows.feed_id = $1
+ORDER BY users.name;
@@ -11,7 +11,9 @@ VALUES (
 RETURNING *;
 
 -- name: GetFeeds :many
-SELECT feeds.name,
+SELECT feeds.id,
+    feeds.user_id,
+    feeds.name,
     feeds.url,
     users.name AS created_by
 FROM feeds
@@ -27,5 +29,60 @@ WHERE id = $3;
 
 -- name: GetNextFeedToFetch :one
 SELECT * FROM feeds
+WHERE last_fetched_at IS NULL
+OR last_fetched_at < $1
+OR NOT EXISTS (
+    SELECT 1 FROM websub_subscriptions
+    WHERE websub_subscriptions.feed_id = feeds.id
+    AND websub_subscriptions.state = 'active'
+    AND websub_subscriptions.lease_expires_at > $2
+)
 ORDER BY last_fetched_at NULLS FIRST
 LIMIT 1;
+
+-- name: GetNextFeedsToFetch :many
+SELECT * FROM feeds
+WHERE last_fetched_at IS NULL
+OR last_fetched_at < $1
+OR NOT EXISTS (
+    SELECT 1 FROM websub_subscriptions
+    WHERE websub_subscriptions.feed_id = feeds.id
+    AND websub_subscriptions.state = 'active'
+    AND websub_subscriptions.lease_expires_at > $2
+)
+ORDER BY last_fetched_at NULLS FIRST
+LIMIT $3;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1;
+
+-- name: RenameFeed :one
+UPDATE feeds
+SET name = $1, updated_at = $2
+WHERE id = $3
+RETURNING *;
+
+-- name: UpdateFeedUrl :one
+UPDATE feeds
+SET url = $1, updated_at = $2, last_fetched_at = NULL
+WHERE id = $3
+RETURNING *;
+
+-- name: GetAllFeeds :many
+SELECT * FROM feeds;
+
+-- name: ResetFeedsFetched :exec
+UPDATE feeds
+SET last_fetched_at = NULL, updated_at = $1;
+
+-- name: ReassignFeeds :execrows
+UPDATE feeds
+SET user_id = $1, updated_at = $2
+WHERE user_id = $3;
+
+-- name: CountFeedsForUser :one
+SELECT COUNT(*) FROM feeds
+WHERE user_id = $1;
+
+-- name: GetFeedById :one
+SELECT * FROM feeds WHERE id = $1;
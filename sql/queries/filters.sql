@@ -0,0 +1,32 @@
+-- name: CreateFilter :one
+INSERT INTO filters (id, created_at, updated_at, user_id, field, match_type, pattern, action, tag)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+RETURNING *;
+
+-- name: GetFiltersForUser :many
+SELECT * FROM filters
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: GetFiltersForFeed :many
+SELECT filters.* FROM filters
+INNER JOIN feed_follows ON feed_follows.user_id = filters.user_id
+WHERE feed_follows.feed_id = $1
+ORDER BY filters.created_at;
+
+-- name: DeleteFilter :execrows
+DELETE FROM filters
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetAllFilters :many
+SELECT * FROM filters;
@@ -0,0 +1,28 @@
+-- name: SetPasswordReset :exec
+INSERT INTO password_resets (user_id, created_at, code_hash, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id) DO UPDATE
+SET created_at = EXCLUDED.created_at,
+    code_hash = EXCLUDED.code_hash,
+    expires_at = EXCLUDED.expires_at;
+
+-- name: GetPasswordReset :one
+SELECT * FROM password_resets
+WHERE user_id = $1 AND expires_at > $2;
+
+-- name: DeletePasswordReset :execrows
+DELETE FROM password_resets
+WHERE user_id = $1;
+
+-- name: GetAdminsWithoutPassword :many
+SELECT * FROM users
+WHERE role = 'admin' AND password_hash IS NULL
+ORDER BY created_at;
+
+-- name: GetAllPasswordResets :many
+SELECT * FROM password_resets;
@@ -0,0 +1,60 @@
+-- name: SetPostRead :exec
+INSERT INTO post_states (id, created_at, updated_at, user_id, post_id, read)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT (user_id, post_id)
+DO UPDATE SET read = excluded.read, updated_at = excluded.updated_at;
+
+-- name: SetPostStarred :exec
+INSERT INTO post_states (id, created_at, updated_at, user_id, post_id, starred)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT (user_id, post_id)
+DO UPDATE SET starred = excluded.starred, updated_at = excluded.updated_at;
+
+-- name: SetPostHidden :exec
+INSERT INTO post_states (id, created_at, updated_at, user_id, post_id, hidden)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT (user_id, post_id)
+DO UPDATE SET hidden = excluded.hidden, updated_at = excluded.updated_at;
+
+-- name: AddPostTag :exec
+INSERT INTO post_tags (id, created_at, user_id, post_id, tag)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (user_id, post_id, tag) DO NOTHING;
+
+-- name: GetPostTags :many
+SELECT tag FROM post_tags
+WHERE user_id = $1 AND post_id = $2
+ORDER BY tag;
+
+-- name: GetAllPostStates :many
+SELECT * FROM post_states;
+
+-- name: GetAllPostTags :many
+SELECT * FROM post_tags;
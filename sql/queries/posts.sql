@@ -1,5 +1,5 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories)
 VALUES(
     $1,
     $2,
@@ -8,14 +8,136 @@ VALUES(
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10
 )
+ON CONFLICT (url) DO NOTHING
 RETURNING *;
 
--- name: GetPostsForUser :many
-SELECT posts.*, feeds.name as feed_name FROM posts
+-- name: GetAllPosts :many
+SELECT * FROM posts;
+
+-- name: DeletePosts :execrows
+DELETE FROM posts;
+
+-- name: DeletePost :execrows
+DELETE FROM posts
+WHERE id = $1;
+
+-- name: GetPostForUser :one
+SELECT posts.*, feeds.name as feed_name, feeds.url as feed_url,
+    COALESCE(post_states.read, FALSE) AS read,
+    COALESCE(post_states.starred, FALSE) AS starred
+FROM posts
+INNER JOIN feed_follows on posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds on posts.feed_id = feeds.id
+LEFT JOIN post_states on post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND posts.id = $2;
+
+-- name: ListPostsForUser :many
+SELECT posts.*, feeds.name as feed_name, feeds.url as feed_url,
+    COALESCE(post_states.read, FALSE) AS read,
+    COALESCE(post_states.starred, FALSE) AS starred
+FROM posts
+INNER JOIN feed_follows on posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds on posts.feed_id = feeds.id
+LEFT JOIN post_states on post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+    AND NOT COALESCE(post_states.hidden, FALSE)
+    AND (sqlc.narg('feed_id') IS NULL OR posts.feed_id = sqlc.narg('feed_id'))
+    AND (sqlc.narg('group_name') IS NULL OR EXISTS (
+        SELECT 1 FROM feed_follow_groups
+        WHERE feed_follow_groups.feed_follow_id = feed_follows.id
+            AND feed_follow_groups.name = sqlc.narg('group_name')
+    ))
+    AND (sqlc.arg('unread_only') = FALSE OR NOT COALESCE(post_states.read, FALSE))
+    AND (sqlc.arg('starred_only') = FALSE OR COALESCE(post_states.starred, FALSE))
+    AND (sqlc.narg('keyword') IS NULL
+        OR LOWER(posts.title) LIKE sqlc.narg('keyword')
+        OR LOWER(posts.description) LIKE sqlc.narg('keyword'))
+ORDER BY posts.published_at DESC NULLS FIRST, posts.id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetPostsForUserAfterSeq :many
+SELECT posts.*, feeds.name as feed_name, feeds.url as feed_url, feeds.seq as feed_seq,
+    COALESCE(post_states.read, FALSE) AS read,
+    COALESCE(post_states.starred, FALSE) AS starred
+FROM posts
 INNER JOIN feed_follows on posts.feed_id = feed_follows.feed_id
 INNER JOIN feeds on posts.feed_id = feeds.id
+LEFT JOIN post_states on post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND posts.seq > $2 AND NOT COALESCE(post_states.hidden, FALSE)
+ORDER BY posts.seq
+LIMIT $3;
+
+-- name: GetPostsForUserBeforeSeq :many
+SELECT posts.*, feeds.name as feed_name, feeds.url as feed_url, feeds.seq as feed_seq,
+    COALESCE(post_states.read, FALSE) AS read,
+    COALESCE(post_states.starred, FALSE) AS starred
+FROM posts
+INNER JOIN feed_follows on posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds on posts.feed_id = feeds.id
+LEFT JOIN post_states on post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND posts.seq < $2 AND NOT COALESCE(post_states.hidden, FALSE)
+ORDER BY posts.seq DESC
+LIMIT $3;
+
+-- name: GetPostForUserBySeq :one
+SELECT posts.*, feeds.name as feed_name, feeds.url as feed_url, feeds.seq as feed_seq,
+    COALESCE(post_states.read, FALSE) AS read,
+    COALESCE(post_states.starred, FALSE) AS starred
+FROM posts
+INNER JOIN feed_follows on posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds on posts.feed_id = feeds.id
+LEFT JOIN post_states on post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND posts.seq = $2;
+
+-- name: CountPostsForUser :one
+SELECT COUNT(*) FROM posts
+INNER JOIN feed_follows on posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states on post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
 WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2;
+    AND NOT COALESCE(post_states.hidden, FALSE);
+
+-- name: GetUnreadPostSeqsForUser :many
+SELECT posts.seq FROM posts
+INNER JOIN feed_follows on posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states on post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+    AND NOT COALESCE(post_states.read, FALSE)
+    AND NOT COALESCE(post_states.hidden, FALSE)
+ORDER BY posts.seq;
+
+-- name: GetStarredPostSeqsForUser :many
+SELECT posts.seq FROM posts
+INNER JOIN feed_follows on posts.feed_id = feed_follows.feed_id
+INNER JOIN post_states on post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND post_states.starred
+ORDER BY posts.seq;
+
+-- name: GetUnreadPostsSince :many
+SELECT posts.*, feeds.name AS feed_name, feeds.url AS feed_url
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND posts.created_at > $2
+AND NOT COALESCE(post_states.read, FALSE)
+AND NOT COALESCE(post_states.hidden, FALSE)
+ORDER BY feeds.name, posts.published_at DESC NULLS FIRST
+LIMIT $3;
+
+-- name: CountUnreadPostsSince :one
+SELECT COUNT(*) FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND posts.created_at > $2
+AND NOT COALESCE(post_states.read, FALSE)
+AND NOT COALESCE(post_states.hidden, FALSE);
+
+-- name: GetPostTimesForFeed :many
+SELECT published_at, created_at FROM posts
+WHERE feed_id = $1;
@@ -0,0 +1,64 @@
+-- name: SetFeedRetention :one
+INSERT INTO feed_retention (feed_id, created_at, updated_at, max_age_days, max_posts, keep_unread_or_starred)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT (feed_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    max_age_days = EXCLUDED.max_age_days,
+    max_posts = EXCLUDED.max_posts,
+    keep_unread_or_starred = EXCLUDED.keep_unread_or_starred
+RETURNING *;
+
+-- name: GetFeedRetention :one
+SELECT * FROM feed_retention
+WHERE feed_id = $1;
+
+-- name: DeleteFeedRetention :execrows
+DELETE FROM feed_retention
+WHERE feed_id = $1;
+
+-- name: GetPostsForPruning :many
+SELECT posts.id, posts.created_at, posts.title, posts.url, posts.published_at,
+    EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.post_id = posts.id AND post_states.starred
+    ) AS starred,
+    EXISTS (
+        SELECT 1 FROM feed_follows
+        LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+        WHERE feed_follows.feed_id = posts.feed_id
+        AND NOT COALESCE(post_states.read, FALSE)
+        AND NOT COALESCE(post_states.hidden, FALSE)
+    ) AS unread
+FROM posts
+WHERE posts.feed_id = $1
+ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.seq DESC;
+
+-- name: CreatePrunedUrl :exec
+INSERT INTO pruned_urls (feed_id, url, pruned_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (feed_id, url) DO NOTHING;
+
+-- name: GetPrunedUrlsForFeed :many
+SELECT url FROM pruned_urls
+WHERE feed_id = $1;
+
+-- name: DeletePrunedUrl :exec
+DELETE FROM pruned_urls
+WHERE feed_id = $1 AND url = $2;
+
+-- name: GetAllFeedRetention :many
+SELECT * FROM feed_retention;
+
+-- name: GetAllPrunedUrls :many
+SELECT * FROM pruned_urls;
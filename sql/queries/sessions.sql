@@ -0,0 +1,35 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, created_at, user_id, token_hash, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetUserBySession :one
+SELECT users.* FROM users
+INNER JOIN sessions ON sessions.user_id = users.id
+WHERE sessions.token_hash = $1 AND sessions.expires_at > $2;
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_used_at = $1
+WHERE token_hash = $2;
+
+-- name: DeleteSession :execrows
+DELETE FROM sessions
+WHERE token_hash = $1;
+
+-- name: DeleteSessionsForUser :exec
+DELETE FROM sessions
+WHERE user_id = $1;
+
+-- name: DeleteExpiredSessions :exec
+DELETE FROM sessions
+WHERE expires_at <= $1;
+
+-- name: GetAllSessions :many
+SELECT * FROM sessions;
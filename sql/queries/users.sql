@@ -1,10 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, password_hash, role)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5,
+    CASE WHEN EXISTS (SELECT 1 FROM users) THEN 'member' ELSE 'admin' END
 )
 RETURNING *;
 
@@ -16,3 +18,29 @@ DELETE FROM users;
 
 -- name: GetUsers :many
 SELECT * FROM users;
+
+-- name: DeleteUser :execrows
+DELETE FROM users WHERE id = $1;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $1, updated_at = $2
+WHERE id = $3;
+
+-- name: SetUserRole :exec
+UPDATE users
+SET role = $1, updated_at = $2
+WHERE id = $3;
+
+-- name: CountAdmins :one
+SELECT COUNT(*) FROM users
+WHERE role = 'admin';
+
+-- name: RenameUser :one
+UPDATE users
+SET name = $1, updated_at = $2
+WHERE id = $3
+RETURNING *;
+
+-- name: GetUserById :one
+SELECT * FROM users WHERE id = $1;
@@ -0,0 +1,67 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (id, created_at, updated_at, user_id, url, secret, feed_id, group_name, tag, template, batch)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11
+)
+RETURNING *;
+
+-- name: GetWebhooksForUser :many
+SELECT * FROM webhooks
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: GetWebhookForUser :one
+SELECT * FROM webhooks
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetWebhooksForFeed :many
+SELECT webhooks.* FROM webhooks
+INNER JOIN feed_follows ON feed_follows.user_id = webhooks.user_id
+WHERE feed_follows.feed_id = $1
+AND (webhooks.feed_id IS NULL OR webhooks.feed_id = feed_follows.feed_id)
+AND (webhooks.group_name IS NULL OR EXISTS (
+    SELECT 1 FROM feed_follow_groups
+    WHERE feed_follow_groups.feed_follow_id = feed_follows.id
+    AND feed_follow_groups.name = webhooks.group_name
+));
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = $1 AND user_id = $2;
+
+-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (id, created_at, webhook_id, event, posts, attempts, status_code, error, succeeded)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+);
+
+-- name: GetWebhookDeliveriesForUser :many
+SELECT webhook_deliveries.*, webhooks.url FROM webhook_deliveries
+INNER JOIN webhooks ON webhook_deliveries.webhook_id = webhooks.id
+WHERE webhooks.user_id = $1
+ORDER BY webhook_deliveries.created_at DESC
+LIMIT $2;
+
+-- name: GetAllWebhooks :many
+SELECT * FROM webhooks;
+
+-- name: GetAllWebhookDeliveries :many
+SELECT * FROM webhook_deliveries;
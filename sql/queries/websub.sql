@@ -0,0 +1,30 @@
+-- name: CreateWebsubSubscription :one
+INSERT INTO websub_subscriptions (feed_id, created_at, updated_at, hub, topic, secret, state)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+ON CONFLICT (feed_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    hub = EXCLUDED.hub,
+    topic = EXCLUDED.topic,
+    secret = EXCLUDED.secret,
+    state = EXCLUDED.state
+RETURNING *;
+
+-- name: GetWebsubSubscription :one
+SELECT * FROM websub_subscriptions
+WHERE feed_id = $1;
+
+-- name: SetWebsubState :exec
+UPDATE websub_subscriptions
+SET state = $1, lease_expires_at = $2, updated_at = $3
+WHERE feed_id = $4;
+
+-- name: GetAllWebsubSubscriptions :many
+SELECT * FROM websub_subscriptions;
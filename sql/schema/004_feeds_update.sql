@@ -4,4 +4,4 @@ ADD last_fetched_at TIMESTAMP;
 
 -- +goose Down
 ALTER TABLE feeds
-DROP COLUMN last_fetch_at;
+DROP COLUMN last_fetched_at;
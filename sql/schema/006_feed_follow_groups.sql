@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE feed_follow_groups (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    feed_follow_id UUID
+        NOT NULL
+        REFERENCES feed_follows(id)
+        ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    UNIQUE (feed_follow_id, name)
+);
+
+-- +goose Down
+DROP TABLE feed_follow_groups;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE filters (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID
+        NOT NULL
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    field TEXT NOT NULL,
+    match_type TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    action TEXT NOT NULL,
+    tag TEXT
+);
+
+-- +goose Down
+DROP TABLE filters;
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD seq BIGSERIAL;
+
+ALTER TABLE posts
+ADD seq BIGSERIAL;
+
+CREATE UNIQUE INDEX posts_seq_idx ON posts (seq);
+
+-- +goose Down
+DROP INDEX posts_seq_idx;
+
+ALTER TABLE posts
+DROP COLUMN seq;
+
+ALTER TABLE feeds
+DROP COLUMN seq;
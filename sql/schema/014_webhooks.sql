@@ -0,0 +1,38 @@
+-- +goose Up
+CREATE TABLE webhooks (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID
+        NOT NULL
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    feed_id UUID
+        REFERENCES feeds(id)
+        ON DELETE CASCADE,
+    group_name TEXT,
+    tag TEXT,
+    template TEXT,
+    batch BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    webhook_id UUID
+        NOT NULL
+        REFERENCES webhooks(id)
+        ON DELETE CASCADE,
+    event TEXT NOT NULL,
+    posts INTEGER NOT NULL,
+    attempts INTEGER NOT NULL,
+    status_code INTEGER,
+    error TEXT,
+    succeeded BOOLEAN NOT NULL
+);
+
+-- +goose Down
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
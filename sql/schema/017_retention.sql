@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE feed_retention (
+    feed_id UUID PRIMARY KEY
+        REFERENCES feeds(id)
+        ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    max_age_days INTEGER,
+    max_posts INTEGER,
+    keep_unread_or_starred BOOLEAN
+);
+
+-- +goose Down
+DROP TABLE feed_retention;
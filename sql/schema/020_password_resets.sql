@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE password_resets (
+    user_id UUID PRIMARY KEY
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    code_hash TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE password_resets;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE pruned_urls (
+    feed_id UUID
+        NOT NULL
+        REFERENCES feeds(id)
+        ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    pruned_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (feed_id, url)
+);
+
+-- +goose Down
+DROP TABLE pruned_urls;
@@ -0,0 +1,26 @@
+// Package schema embeds the goose migrations in this directory so the
+// binary can apply them itself. The sqlite directory holds the same
+// migrations, with the same versions, written for SQLite.
+package schema
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFS embed.FS
+
+// SQLite returns the migrations for SQLite databases.
+func SQLite() fs.FS {
+	sub, err := fs.Sub(sqliteFS, "sqlite")
+
+	if err != nil {
+		panic(err)
+	}
+
+	return sub
+}
@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE post_states (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT
+        NOT NULL
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    post_id TEXT
+        NOT NULL
+        REFERENCES posts(id)
+        ON DELETE CASCADE,
+    read BOOLEAN NOT NULL DEFAULT FALSE,
+    starred BOOLEAN NOT NULL DEFAULT FALSE,
+    hidden BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (user_id, post_id)
+);
+
+CREATE TABLE post_tags (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id TEXT
+        NOT NULL
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    post_id TEXT
+        NOT NULL
+        REFERENCES posts(id)
+        ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    UNIQUE (user_id, post_id, tag)
+);
+
+-- +goose Down
+DROP TABLE post_tags;
+DROP TABLE post_states;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE api_tokens (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id TEXT
+        NOT NULL
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    last_used_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE api_tokens;
@@ -0,0 +1,120 @@
+-- SQLite can only add an INTEGER PRIMARY KEY, which seq has to be to number
+-- rows the way BIGSERIAL does, by creating the table again. Dropping the old
+-- table would cascade to the rows referencing it unless foreign keys are
+-- off, and they can't be turned off inside a transaction, so this migration
+-- runs its own.
+-- +goose NO TRANSACTION
+
+-- +goose Up
+PRAGMA foreign_keys = OFF;
+
+BEGIN;
+
+CREATE TABLE feeds_new (
+    id TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    user_id TEXT
+        NOT NULL
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    last_fetched_at TIMESTAMP,
+    seq INTEGER PRIMARY KEY AUTOINCREMENT
+);
+
+INSERT INTO feeds_new (id, created_at, updated_at, name, url, user_id, last_fetched_at)
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
+FROM feeds
+ORDER BY created_at;
+
+DROP TABLE feeds;
+
+ALTER TABLE feeds_new RENAME TO feeds;
+
+CREATE TABLE posts_new (
+    id TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL,
+    description TEXT,
+    published_at TIMESTAMP,
+    feed_id TEXT
+        NOT NULL
+        REFERENCES feeds(id)
+        ON DELETE CASCADE,
+    author TEXT,
+    categories TEXT,
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    UNIQUE(url)
+);
+
+INSERT INTO posts_new (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories)
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories
+FROM posts
+ORDER BY created_at;
+
+DROP TABLE posts;
+
+ALTER TABLE posts_new RENAME TO posts;
+
+COMMIT;
+
+PRAGMA foreign_keys = ON;
+
+-- +goose Down
+PRAGMA foreign_keys = OFF;
+
+BEGIN;
+
+CREATE TABLE posts_old (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL,
+    description TEXT,
+    published_at TIMESTAMP,
+    feed_id TEXT
+        NOT NULL
+        REFERENCES feeds(id)
+        ON DELETE CASCADE,
+    author TEXT,
+    categories TEXT,
+    UNIQUE(url)
+);
+
+INSERT INTO posts_old (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories)
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories
+FROM posts;
+
+DROP TABLE posts;
+
+ALTER TABLE posts_old RENAME TO posts;
+
+CREATE TABLE feeds_old (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    user_id TEXT
+        NOT NULL
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    last_fetched_at TIMESTAMP
+);
+
+INSERT INTO feeds_old (id, created_at, updated_at, name, url, user_id, last_fetched_at)
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
+FROM feeds;
+
+DROP TABLE feeds;
+
+ALTER TABLE feeds_old RENAME TO feeds;
+
+COMMIT;
+
+PRAGMA foreign_keys = ON;
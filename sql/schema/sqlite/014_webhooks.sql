@@ -0,0 +1,38 @@
+-- +goose Up
+CREATE TABLE webhooks (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT
+        NOT NULL
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    feed_id TEXT
+        REFERENCES feeds(id)
+        ON DELETE CASCADE,
+    group_name TEXT,
+    tag TEXT,
+    template TEXT,
+    batch BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE webhook_deliveries (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    webhook_id TEXT
+        NOT NULL
+        REFERENCES webhooks(id)
+        ON DELETE CASCADE,
+    event TEXT NOT NULL,
+    posts INTEGER NOT NULL,
+    attempts INTEGER NOT NULL,
+    status_code INTEGER,
+    error TEXT,
+    succeeded BOOLEAN NOT NULL
+);
+
+-- +goose Down
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
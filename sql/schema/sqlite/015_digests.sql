@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE digest_prefs (
+    user_id TEXT PRIMARY KEY
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    email TEXT NOT NULL,
+    frequency TEXT NOT NULL,
+    group_by TEXT NOT NULL,
+    last_sent_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE digest_prefs;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE websub_subscriptions (
+    feed_id TEXT PRIMARY KEY
+        REFERENCES feeds(id)
+        ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    hub TEXT NOT NULL,
+    topic TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    state TEXT NOT NULL,
+    lease_expires_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE websub_subscriptions;
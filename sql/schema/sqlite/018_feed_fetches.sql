@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE feed_fetches (
+    id TEXT PRIMARY KEY,
+    feed_id TEXT
+        NOT NULL
+        REFERENCES feeds(id)
+        ON DELETE CASCADE,
+    started_at TIMESTAMP NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    status_code INTEGER,
+    bytes INTEGER NOT NULL,
+    items INTEGER NOT NULL,
+    new_posts INTEGER NOT NULL,
+    error TEXT
+);
+
+CREATE INDEX feed_fetches_feed_id_started_at_idx ON feed_fetches (feed_id, started_at);
+
+-- +goose Down
+DROP TABLE feed_fetches;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE feed_credentials (
+    feed_id TEXT
+        NOT NULL
+        REFERENCES feeds(id)
+        ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    kind TEXT NOT NULL,
+    name TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    PRIMARY KEY (feed_id, kind, name)
+);
+
+-- +goose Down
+DROP TABLE feed_credentials;
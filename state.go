@@ -1,11 +1,21 @@
 package main
 
 import (
+	"net/http"
+
 	"github.com/w0/aggregator/internal/config"
 	"github.com/w0/aggregator/internal/database"
+	"github.com/w0/aggregator/internal/storage"
 )
 
 type state struct {
-	db  *database.Queries
-	cfg *config.Config
+	db   database.Store
+	conn *storage.DB
+	cfg  *config.Config
+	// hosts limits the feed fetches made to each host.
+	hosts *hostLimiter
+	// client fetches feeds through the configured proxy and TLS settings.
+	client *http.Client
+	// webhooks delivers new posts to webhooks in the background.
+	webhooks *webhookQueue
 }
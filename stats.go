@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/w0/aggregator/internal/database"
+)
+
+const feedStatsUsage = `usage: aggregator feed stats <url> [--days <n>]`
+
+const healthUsage = `usage: aggregator health [--days <n>] [--stale-after <days>]`
+
+// fetchHistoryDays is how long agg keeps the fetch history the stats are
+// made from.
+const fetchHistoryDays = 90
+
+// feedHealth sums up the fetches and posts of a feed over the last days.
+type feedHealth struct {
+	days    int
+	fetches []database.FeedFetch // newest first
+	failed  int
+	latency time.Duration // average over every fetch
+	// lastPost is when the newest post of the feed was saved.
+	lastPost    time.Time
+	recentPosts int // posts published in the last days
+}
+
+func getFeedHealth(s *state, feed database.Feed, days int, now time.Time) (feedHealth, error) {
+	h := feedHealth{days: days}
+	ctx := context.Background()
+	since := now.AddDate(0, 0, -days)
+
+	fetches, err := s.db.GetFeedFetchesSince(ctx,
+		database.GetFeedFetchesSinceParams{
+			FeedID:    feed.ID,
+			StartedAt: since,
+		})
+
+	if err != nil {
+		return h, fmt.Errorf("failed getting fetches of %s: %w", feed.Url, err)
+	}
+
+	h.fetches = fetches
+
+	var total time.Duration
+	for _, v := range fetches {
+		if v.Error.Valid {
+			h.failed++
+		}
+		total += time.Duration(v.DurationMs) * time.Millisecond
+	}
+
+	if len(fetches) > 0 {
+		h.latency = total / time.Duration(len(fetches))
+	}
+
+	posts, err := s.db.GetPostTimesForFeed(ctx, feed.ID)
+
+	if err != nil {
+		return h, fmt.Errorf("failed getting posts of %s: %w", feed.Url, err)
+	}
+
+	for _, v := range posts {
+		if v.CreatedAt.After(h.lastPost) {
+			h.lastPost = v.CreatedAt
+		}
+
+		posted := v.CreatedAt
+		if v.PublishedAt.Valid {
+			posted = v.PublishedAt.Time
+		}
+
+		if posted.After(since) {
+			h.recentPosts++
+		}
+	}
+
+	return h, nil
+}
+
+func (h feedHealth) successRate() float64 {
+	if len(h.fetches) == 0 {
+		return 0
+	}
+	return 100 * float64(len(h.fetches)-h.failed) / float64(len(h.fetches))
+}
+
+func (h feedHealth) postsPerWeek() float64 {
+	return float64(h.recentPosts) * 7 / float64(h.days)
+}
+
+// lastError returns the newest failed fetch.
+func (h feedHealth) lastError() (database.FeedFetch, bool) {
+	for _, v := range h.fetches {
+		if v.Error.Valid {
+			return v, true
+		}
+	}
+	return database.FeedFetch{}, false
+}
+
+// status is failing when the last fetch failed, stale when no new post was
+// saved within staleAfter, and ok otherwise. Feeds agg hasn't got to yet are
+// never fetched.
+func (h feedHealth) status(feed database.Feed, staleAfter time.Duration, now time.Time) string {
+	switch {
+	case !feed.LastFetchedAt.Valid:
+		return "never fetched"
+	case len(h.fetches) > 0 && h.fetches[0].Error.Valid:
+		return "failing"
+	case h.lastPost.Before(now.Add(-staleAfter)):
+		return "stale"
+	default:
+		return "ok"
+	}
+}
+
+func formatLastPost(h feedHealth) string {
+	if h.lastPost.IsZero() {
+		return "never"
+	}
+	return h.lastPost.Format(time.DateTime)
+}
+
+func handlerFeedStats(s *state, feed database.Feed, arguments []string) error {
+	fs := flag.NewFlagSet("feed stats", flag.ContinueOnError)
+	days := fs.Int("days", 30, "look at the last n days")
+
+	if err := fs.Parse(arguments); err != nil || fs.NArg() > 0 || *days <= 0 {
+		return fmt.Errorf(feedStatsUsage)
+	}
+
+	if *days > fetchHistoryDays {
+		return fmt.Errorf("days can be at most %d, older fetches aren't kept", fetchHistoryDays)
+	}
+
+	now := time.Now()
+
+	h, err := getFeedHealth(s, feed, *days, now)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Stats for %s (%s) over the last %d days\n", feed.Name, feed.Url, *days)
+	fmt.Printf("Fetches:        %d, %d failed\n", len(h.fetches), h.failed)
+
+	if len(h.fetches) > 0 {
+		last := h.fetches[0]
+
+		result := "ok"
+		if last.Error.Valid {
+			result = "failed"
+		}
+
+		status := "no response"
+		if last.StatusCode.Valid {
+			status = fmt.Sprintf("status %d", last.StatusCode.Int32)
+		}
+
+		fmt.Printf("Success rate:   %.1f%%\n", h.successRate())
+		fmt.Printf("Avg latency:    %s\n", h.latency)
+		fmt.Printf("Last fetch:     %s, %s, %s, %d bytes, %d items, %d new\n",
+			last.StartedAt.Format(time.DateTime), result, status, last.Bytes, last.Items, last.NewPosts)
+	}
+
+	if v, ok := h.lastError(); ok {
+		fmt.Printf("Last error:     %s, %s\n", v.StartedAt.Format(time.DateTime), v.Error.String)
+	}
+
+	fmt.Printf("Last new post:  %s\n", formatLastPost(h))
+	fmt.Printf("Posts per week: %.1f\n", h.postsPerWeek())
+
+	return nil
+}
+
+func handlerHealth(s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	days := fs.Int("days", 30, "look at the last n days")
+	staleDays := fs.Int("stale-after", 30, "call feeds without new posts for this many days stale")
+
+	if err := fs.Parse(cmd.arguments); err != nil || fs.NArg() > 0 || *days <= 0 || *staleDays <= 0 {
+		return fmt.Errorf(healthUsage)
+	}
+
+	if *days > fetchHistoryDays {
+		return fmt.Errorf("days can be at most %d, older fetches aren't kept", fetchHistoryDays)
+	}
+
+	feeds, err := s.db.GetAllFeeds(context.Background())
+
+	if err != nil {
+		return fmt.Errorf("failed getting feeds: %w", err)
+	}
+
+	now := time.Now()
+	staleAfter := time.Duration(*staleDays) * 24 * time.Hour
+	counts := map[string]int{}
+
+	fmt.Printf("Feed health over the last %d days\n\n", *days)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FEED\tFETCHES\tSUCCESS\tLATENCY\tPOSTS/WEEK\tLAST NEW POST\tSTATUS")
+
+	for _, feed := range feeds {
+		h, err := getFeedHealth(s, feed, *days, now)
+
+		if err != nil {
+			return err
+		}
+
+		status := h.status(feed, staleAfter, now)
+		counts[status]++
+
+		success, latency := "-", "-"
+		if len(h.fetches) > 0 {
+			success = fmt.Sprintf("%.0f%%", h.successRate())
+			latency = h.latency.String()
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%.1f\t%s\t%s\n",
+			feed.Name, len(h.fetches), success, latency, h.postsPerWeek(), formatLastPost(h), status)
+	}
+
+	w.Flush()
+
+	fmt.Printf("\n%d feeds: %d ok, %d failing, %d stale, %d never fetched\n",
+		len(feeds), counts["ok"], counts["failing"], counts["stale"], counts["never fetched"])
+
+	return nil
+}
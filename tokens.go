@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+const tokenUsage = `usage: aggregator token create [--fever] [name]
+       aggregator token list
+       aggregator token revoke <id>`
+
+func handlerToken(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf(tokenUsage)
+	}
+
+	switch cmd.arguments[0] {
+	case "create":
+		fs := flag.NewFlagSet("token create", flag.ContinueOnError)
+		fever := fs.Bool("fever", false, "create a password for Fever api clients")
+
+		if err := fs.Parse(cmd.arguments[1:]); err != nil {
+			return fmt.Errorf(tokenUsage)
+		}
+
+		name := "default"
+		if fs.NArg() > 0 {
+			name = fs.Arg(0)
+		}
+
+		token, err := newToken()
+
+		if err != nil {
+			return err
+		}
+
+		// Fever clients never send the token itself, only md5 of
+		// "username:token", so that is what gets hashed and stored.
+		hash := hashToken(token)
+		if *fever {
+			hash = hashToken(feverKey(user.Name, token))
+		}
+
+		created, err := s.db.CreateApiToken(context.Background(),
+			database.CreateApiTokenParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UserID:    user.ID,
+				Name:      name,
+				TokenHash: hash,
+			})
+
+		if err != nil {
+			return fmt.Errorf("failed to create token: %w", err)
+		}
+
+		fmt.Printf("Token %s (%s) created for %s.\n", created.Name, created.ID, user.Name)
+		if *fever {
+			fmt.Printf("Log in to your Fever client as %s with this password.\n", user.Name)
+		}
+		fmt.Println("It will not be shown again:")
+		fmt.Println(token)
+	case "list":
+		tokens, err := s.db.GetApiTokensForUser(context.Background(), user.ID)
+
+		if err != nil {
+			return fmt.Errorf("failed to list tokens: %w", err)
+		}
+
+		fmt.Printf("%s's api tokens:\n", user.Name)
+		for _, v := range tokens {
+			lastUsed := "never"
+			if v.LastUsedAt.Valid {
+				lastUsed = v.LastUsedAt.Time.Format(time.DateTime)
+			}
+			fmt.Printf("\t * %s %s, created %s, last used %s\n", v.ID, v.Name, v.CreatedAt.Format(time.DateTime), lastUsed)
+		}
+	case "revoke":
+		if len(cmd.arguments) < 2 {
+			return fmt.Errorf(tokenUsage)
+		}
+
+		id, err := uuid.Parse(cmd.arguments[1])
+
+		if err != nil {
+			return fmt.Errorf("invalid token id: %w", err)
+		}
+
+		n, err := s.db.DeleteApiToken(context.Background(),
+			database.DeleteApiTokenParams{
+				ID:     id,
+				UserID: user.ID,
+			})
+
+		if err != nil {
+			return fmt.Errorf("failed to revoke token: %w", err)
+		}
+
+		if n == 0 {
+			return fmt.Errorf("token %s not found", id)
+		}
+
+		fmt.Printf("Revoked token %s\n", id)
+	default:
+		return fmt.Errorf(tokenUsage)
+	}
+
+	return nil
+}
+
+// newToken returns a random token. Only its hash is stored in the database.
+func newToken() (string, error) {
+	b := make([]byte, 32)
+
+	_, err := rand.Read(b)
+
+	if err != nil {
+		return "", fmt.Errorf("failed generating token: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/w0/aggregator/internal/config"
+	"github.com/w0/aggregator/internal/database"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// The kinds of credentials a feed can be fetched with. Basic and bearer both
+// set the Authorization header, so a feed has at most one of them; headers
+// and cookies are keyed by their name.
+const (
+	credBasic  = "basic"
+	credBearer = "bearer"
+	credHeader = "header"
+	credCookie = "cookie"
+)
+
+const maxRedirects = 10
+
+// newFeedClient returns the client feeds are fetched with. It goes through
+// the proxy the environment names unless the config sets its own, trusts the
+// config's CA bundle on top of the system's roots, and presents the config's
+// client certificate to hosts that ask for one.
+func newFeedClient(cfg *config.Fetch) (*http.Client, error) {
+	proxy := httpproxy.FromEnvironment()
+	tlsConfig := &tls.Config{}
+
+	if cfg != nil {
+		if cfg.Proxy != "" {
+			u, err := url.Parse(cfg.Proxy)
+
+			if err != nil || u.Host == "" {
+				return nil, fmt.Errorf("proxy must be a URL such as http://proxy:3128")
+			}
+
+			proxy.HTTPProxy = cfg.Proxy
+			proxy.HTTPSProxy = cfg.Proxy
+		}
+
+		if cfg.NoProxy != "" {
+			proxy.NoProxy = cfg.NoProxy
+		}
+
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+
+			if err != nil {
+				return nil, fmt.Errorf("failed reading ca_file: %w", err)
+			}
+
+			pool, err := x509.SystemCertPool()
+
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+			}
+
+			tlsConfig.RootCAs = pool
+		}
+
+		if cfg.ClientCert != "" || cfg.ClientKey != "" {
+			cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+
+			if err != nil {
+				return nil, fmt.Errorf("failed loading client_cert and client_key: %w", err)
+			}
+
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+
+	proxyFunc := proxy.ProxyFunc()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		// the timeout keeps a host that never answers from holding on to
+		// one of its request slots
+		Timeout:       30 * time.Second,
+		Transport:     transport,
+		CheckRedirect: checkFeedRedirect,
+	}, nil
+}
+
+// checkFeedRedirect keeps a feed's credentials from following a redirect to
+// another host. The client already drops Authorization and Cookie there, but
+// not the custom headers a feed may be fetched with.
+func checkFeedRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return errors.New("stopped after 10 redirects")
+	}
+
+	if strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+		return nil
+	}
+
+	for name := range req.Header {
+		if name != "User-Agent" && name != "Referer" {
+			req.Header.Del(name)
+		}
+	}
+
+	return nil
+}
+
+// setCredentials adds a feed's credentials to the request fetching it.
+func setCredentials(req *http.Request, creds []database.FeedCredential) {
+	for _, c := range creds {
+		switch c.Kind {
+		case credBasic:
+			req.SetBasicAuth(c.Name, c.Secret)
+		case credBearer:
+			req.Header.Set("Authorization", "Bearer "+c.Secret)
+		case credHeader:
+			req.Header.Set(c.Name, c.Secret)
+		case credCookie:
+			req.AddCookie(&http.Cookie{Name: c.Name, Value: c.Secret})
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/w0/aggregator/internal/config"
+)
+
+// clearProxyEnv keeps the environment's proxy settings out of a test.
+func clearProxyEnv(t *testing.T) {
+	for _, v := range []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy"} {
+		t.Setenv(v, "")
+	}
+}
+
+func TestFeedClientProxy(t *testing.T) {
+	clearProxyEnv(t)
+
+	var mu sync.Mutex
+	var proxied []string
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r.RequestURI)
+		mu.Unlock()
+
+		io.WriteString(w, "from the proxy")
+	}))
+	t.Cleanup(proxy.Close)
+
+	client, err := newFeedClient(&config.Fetch{Proxy: proxy.URL})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the feed's host doesn't resolve, only the proxy can answer
+	res, err := client.Get("http://feed.invalid/rss")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if string(body) != "from the proxy" || len(proxied) != 1 || proxied[0] != "http://feed.invalid/rss" {
+		t.Errorf("proxy got %v and answered %q", proxied, body)
+	}
+
+	if _, err := newFeedClient(&config.Fetch{Proxy: "proxy:3128"}); err == nil {
+		t.Error("a proxy without a scheme was accepted")
+	}
+}
+
+func TestFeedClientNoProxy(t *testing.T) {
+	clearProxyEnv(t)
+
+	client, err := newFeedClient(&config.Fetch{Proxy: "http://proxy.invalid:3128", NoProxy: "feed.invalid"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", "http://feed.invalid/rss", nil)
+	u, err := client.Transport.(*http.Transport).Proxy(req)
+
+	if err != nil || u != nil {
+		t.Errorf("no_proxy host goes through %v, %v", u, err)
+	}
+
+	req, _ = http.NewRequest("GET", "https://other.invalid/rss", nil)
+	u, err = client.Transport.(*http.Transport).Proxy(req)
+
+	if err != nil || u == nil || u.Host != "proxy.invalid:3128" {
+		t.Errorf("https request goes through %v, %v", u, err)
+	}
+}
+
+func TestFeedClientCA(t *testing.T) {
+	clearProxyEnv(t)
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(srv.Close)
+
+	// without the CA the test server's certificate isn't trusted
+	client, err := newFeedClient(&config.Fetch{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Get(srv.URL); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("fetching without the CA: %v", err)
+	}
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	if err := os.WriteFile(caFile, ca, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err = newFeedClient(&config.Fetch{CAFile: caFile})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Get(srv.URL)
+
+	if err != nil {
+		t.Fatalf("fetching with the CA: %v", err)
+	}
+
+	res.Body.Close()
+
+	empty := filepath.Join(dir, "empty.pem")
+
+	if err := os.WriteFile(empty, []byte("no certificates here"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newFeedClient(&config.Fetch{CAFile: empty}); err == nil {
+		t.Error("a CA file without certificates was accepted")
+	}
+
+	if _, err := newFeedClient(&config.Fetch{CAFile: filepath.Join(dir, "missing.pem")}); err == nil {
+		t.Error("a missing CA file was accepted")
+	}
+}
@@ -0,0 +1,561 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+const webhookUsage = `usage: aggregator webhook add [--feed <url>] [--group <name>] [--tag <tag>] [--template <file>] [--batch] <url>
+       aggregator webhook list
+       aggregator webhook rm <id>
+       aggregator webhook test <id>
+       aggregator webhook log [--limit <n>]`
+
+// webhookBackoff is how long to wait before each retry of a failed delivery.
+var webhookBackoff = []time.Duration{time.Second, 5 * time.Second, 30 * time.Second}
+
+const (
+	// webhookTimeout bounds each delivery attempt.
+	webhookTimeout = 10 * time.Second
+
+	// webhookWorkers deliver queued webhooks, at most webhookQueueSize
+	// of them waiting at a time.
+	webhookWorkers   = 4
+	webhookQueueSize = 256
+)
+
+type webhookFeed struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	URL  string    `json:"url"`
+}
+
+type webhookPost struct {
+	ID          uuid.UUID  `json:"id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Description string     `json:"description,omitempty"`
+	Author      string     `json:"author,omitempty"`
+	Categories  []string   `json:"categories,omitempty"`
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+type webhookPayload struct {
+	Event string        `json:"event"`
+	Feed  *webhookFeed  `json:"feed,omitempty"`
+	Posts []webhookPost `json:"posts"`
+}
+
+// webhookJob is one fetch's posts for one webhook, delivered in order.
+type webhookJob struct {
+	hook     database.Webhook
+	feed     database.Feed
+	payloads []webhookPayload
+}
+
+// webhookQueue delivers webhooks in the background on a fixed number of
+// workers, so slow receivers neither hold up fetching nor pile up
+// goroutines.
+type webhookQueue struct {
+	jobs  chan webhookJob
+	start sync.Once
+}
+
+func newWebhookQueue() *webhookQueue {
+	return &webhookQueue{jobs: make(chan webhookJob, webhookQueueSize)}
+}
+
+// push queues job, starting the workers the first time. A job that doesn't
+// fit is dropped rather than waited for, and logged as failed deliveries.
+func (q *webhookQueue) push(s *state, job webhookJob) {
+	q.start.Do(func() {
+		for range webhookWorkers {
+			go q.work(s)
+		}
+	})
+
+	select {
+	case q.jobs <- job:
+		return
+	default:
+	}
+
+	slog.Warn("webhook queue full, dropping delivery", "webhook_id", job.hook.ID, "feed_id", job.feed.ID, "posts", len(job.payloads))
+
+	for _, payload := range job.payloads {
+		err := s.db.CreateWebhookDelivery(context.Background(),
+			database.CreateWebhookDeliveryParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				WebhookID: job.hook.ID,
+				Event:     payload.Event,
+				Posts:     int32(len(payload.Posts)),
+				Error:     sql.NullString{String: "dropped, too many deliveries queued", Valid: true},
+			})
+
+		if err != nil {
+			slog.Error("failed recording dropped delivery", "webhook_id", job.hook.ID, "error", err)
+		}
+	}
+}
+
+func (q *webhookQueue) work(s *state) {
+	for job := range q.jobs {
+		for _, payload := range job.payloads {
+			if err := deliverWebhook(s, job.hook, payload); err != nil {
+				slog.Warn("webhook delivery failed", "webhook_id", job.hook.ID, "feed_id", job.feed.ID, "user", job.hook.UserID, "error", err)
+			}
+		}
+	}
+}
+
+// newPost is a post inserted by saveFeeds together with the fields filters
+// match against.
+type newPost struct {
+	post   database.Post
+	fields postFields
+}
+
+func handlerWebhook(s *state, cmd command, user database.User) error {
+	if len(cmd.arguments) == 0 {
+		return fmt.Errorf(webhookUsage)
+	}
+
+	ctx := context.Background()
+	args := cmd.arguments[1:]
+
+	switch cmd.arguments[0] {
+	case "add":
+		fs := flag.NewFlagSet("webhook add", flag.ContinueOnError)
+		feedURL := fs.String("feed", "", "only send posts from this feed")
+		group := fs.String("group", "", "only send posts from feeds in this group")
+		tag := fs.String("tag", "", "only send posts a filter tagged with this tag")
+		templateFile := fs.String("template", "", "text/template file rendering the request body")
+		batch := fs.Bool("batch", false, "send one request per fetch instead of one per post")
+
+		if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+			return fmt.Errorf(webhookUsage)
+		}
+
+		params := database.CreateWebhookParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    user.ID,
+			Url:       fs.Arg(0),
+			GroupName: sql.NullString{String: *group, Valid: *group != ""},
+			Tag:       sql.NullString{String: *tag, Valid: *tag != ""},
+			Batch:     *batch,
+		}
+
+		if *feedURL != "" {
+			feed, err := s.db.GetFeedByUrl(ctx, *feedURL)
+
+			if err != nil {
+				return fmt.Errorf("feed %s not found: %w", *feedURL, err)
+			}
+
+			params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+		}
+
+		if *templateFile != "" {
+			b, err := os.ReadFile(*templateFile)
+
+			if err != nil {
+				return fmt.Errorf("failed reading template: %w", err)
+			}
+
+			_, err = parseWebhookTemplate(string(b))
+
+			if err != nil {
+				return err
+			}
+
+			params.Template = sql.NullString{String: string(b), Valid: true}
+		}
+
+		secret, err := newToken()
+
+		if err != nil {
+			return err
+		}
+
+		params.Secret = secret
+
+		hook, err := s.db.CreateWebhook(ctx, params)
+
+		if err != nil {
+			return fmt.Errorf("failed to create webhook: %w", err)
+		}
+
+		fmt.Printf("Webhook %s created for %s.\n", hook.ID, hook.Url)
+		fmt.Println("Requests are signed in X-Gator-Signature with this secret:")
+		fmt.Println(hook.Secret)
+	case "list":
+		hooks, err := s.db.GetWebhooksForUser(ctx, user.ID)
+
+		if err != nil {
+			return fmt.Errorf("failed to list webhooks: %w", err)
+		}
+
+		fmt.Printf("%s's webhooks:\n", user.Name)
+		for _, v := range hooks {
+			fmt.Printf("\t * %s %s%s\n", v.ID, v.Url, webhookScope(v))
+		}
+	case "rm":
+		if len(args) == 0 {
+			return fmt.Errorf(webhookUsage)
+		}
+
+		id, err := uuid.Parse(args[0])
+
+		if err != nil {
+			return fmt.Errorf("invalid webhook id: %w", err)
+		}
+
+		n, err := s.db.DeleteWebhook(ctx,
+			database.DeleteWebhookParams{
+				ID:     id,
+				UserID: user.ID,
+			})
+
+		if err != nil {
+			return fmt.Errorf("failed to remove webhook: %w", err)
+		}
+
+		if n == 0 {
+			return fmt.Errorf("webhook %s not found", id)
+		}
+
+		fmt.Printf("Removed webhook %s\n", id)
+	case "test":
+		if len(args) == 0 {
+			return fmt.Errorf(webhookUsage)
+		}
+
+		id, err := uuid.Parse(args[0])
+
+		if err != nil {
+			return fmt.Errorf("invalid webhook id: %w", err)
+		}
+
+		hook, err := s.db.GetWebhookForUser(ctx,
+			database.GetWebhookForUserParams{
+				ID:     id,
+				UserID: user.ID,
+			})
+
+		if err != nil {
+			return fmt.Errorf("webhook %s not found: %w", id, err)
+		}
+
+		err = deliverWebhook(s, hook, webhookPayload{Event: "ping", Posts: []webhookPost{}})
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Delivered a ping to %s\n", hook.Url)
+	case "log":
+		fs := flag.NewFlagSet("webhook log", flag.ContinueOnError)
+		limit := fs.Int("limit", 20, "number of deliveries to show")
+
+		if err := fs.Parse(args); err != nil {
+			return fmt.Errorf(webhookUsage)
+		}
+
+		deliveries, err := s.db.GetWebhookDeliveriesForUser(ctx,
+			database.GetWebhookDeliveriesForUserParams{
+				UserID: user.ID,
+				Limit:  int32(*limit),
+			})
+
+		if err != nil {
+			return fmt.Errorf("failed to read delivery log: %w", err)
+		}
+
+		for _, v := range deliveries {
+			result := "ok"
+			if !v.Succeeded {
+				result = "failed"
+			}
+
+			status := "-"
+			if v.StatusCode.Valid {
+				status = strconv.Itoa(int(v.StatusCode.Int32))
+			}
+
+			fmt.Printf("%s %s %s %d posts, %d attempts, status %s: %s",
+				v.CreatedAt.Format(time.DateTime), v.Url, v.Event, v.Posts, v.Attempts, status, result)
+			if v.Error.Valid {
+				fmt.Printf(" (%s)", v.Error.String)
+			}
+			fmt.Println()
+		}
+	default:
+		return fmt.Errorf(webhookUsage)
+	}
+
+	return nil
+}
+
+func webhookScope(hook database.Webhook) string {
+	var scope string
+
+	if hook.FeedID.Valid {
+		scope += fmt.Sprintf(" feed=%s", hook.FeedID.UUID)
+	}
+	if hook.GroupName.Valid {
+		scope += fmt.Sprintf(" group=%s", hook.GroupName.String)
+	}
+	if hook.Tag.Valid {
+		scope += fmt.Sprintf(" tag=%s", hook.Tag.String)
+	}
+	if hook.Template.Valid {
+		scope += " (template)"
+	}
+	if hook.Batch {
+		scope += " (batch)"
+	}
+
+	return scope
+}
+
+// notifyWebhooks sends the posts saveFeeds just inserted to the webhooks of
+// every user following feed. Posts those users' filters hide are left out.
+// Deliveries are queued for the webhook workers.
+func notifyWebhooks(s *state, feed database.Feed, filters []database.Filter, posts []newPost) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	hooks, err := s.db.GetWebhooksForFeed(context.Background(), feed.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed getting webhooks. %w", err)
+	}
+
+	for _, hook := range hooks {
+		var selected []webhookPost
+
+		for _, p := range posts {
+			hidden, tags := filterOutcome(filters, hook.UserID, p.fields)
+
+			if hidden || (hook.Tag.Valid && !slices.Contains(tags, hook.Tag.String)) {
+				continue
+			}
+
+			selected = append(selected, webhookPostFrom(p))
+		}
+
+		if len(selected) == 0 {
+			continue
+		}
+
+		payloads := []webhookPayload{}
+		if hook.Batch {
+			payloads = append(payloads, webhookPayload{
+				Event: "posts.created",
+				Feed:  webhookFeedFrom(feed),
+				Posts: selected,
+			})
+		} else {
+			for _, p := range selected {
+				payloads = append(payloads, webhookPayload{
+					Event: "posts.created",
+					Feed:  webhookFeedFrom(feed),
+					Posts: []webhookPost{p},
+				})
+			}
+		}
+
+		s.webhooks.push(s, webhookJob{hook: hook, feed: feed, payloads: payloads})
+	}
+
+	return nil
+}
+
+// deliverWebhook posts payload to the webhook, retrying with backoff on
+// network errors, 429 and 5xx responses, and records the outcome in the
+// delivery log.
+func deliverWebhook(s *state, hook database.Webhook, payload webhookPayload) error {
+	body, err := webhookBody(hook, payload)
+
+	if err != nil {
+		return err
+	}
+
+	delivery := database.CreateWebhookDeliveryParams{
+		ID:        uuid.New(),
+		WebhookID: hook.ID,
+		Event:     payload.Event,
+		Posts:     int32(len(payload.Posts)),
+	}
+
+	for {
+		delivery.Attempts++
+
+		status, err := postWebhook(s, hook, delivery.ID, payload.Event, body)
+
+		delivery.StatusCode = sql.NullInt32{Int32: int32(status), Valid: status != 0}
+		delivery.Error = sql.NullString{}
+		if err != nil {
+			delivery.Error = sql.NullString{String: err.Error(), Valid: true}
+		}
+
+		delivery.Succeeded = err == nil
+		retry := err != nil && (status == 0 || status == http.StatusTooManyRequests || status >= 500)
+
+		if !retry || int(delivery.Attempts) > len(webhookBackoff) {
+			break
+		}
+
+		time.Sleep(webhookBackoff[delivery.Attempts-1])
+	}
+
+	delivery.CreatedAt = time.Now()
+
+	err = s.db.CreateWebhookDelivery(context.Background(), delivery)
+
+	if err != nil {
+		return fmt.Errorf("failed recording delivery: %w", err)
+	}
+
+	if !delivery.Succeeded {
+		return fmt.Errorf("delivery to %s failed after %d attempts: %s", hook.Url, delivery.Attempts, delivery.Error.String)
+	}
+
+	return nil
+}
+
+// postWebhook sends one delivery attempt and returns the response status.
+// The body is signed with HMAC-SHA256 using the webhook's secret. It goes
+// through the same proxy and TLS settings as feed fetches, and waits its
+// turn with the webhook's host like they do.
+func postWebhook(s *state, hook database.Webhook, deliveryID uuid.UUID, event string, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", hook.Url, bytes.NewReader(body))
+
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent(s.cfg))
+	req.Header.Set("X-Gator-Event", event)
+	req.Header.Set("X-Gator-Delivery", deliveryID.String())
+	req.Header.Set("X-Gator-Signature", "sha256="+signPayload(hook.Secret, body))
+
+	release, err := s.hosts.acquire(ctx, req.URL.Host)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer release()
+
+	res, err := s.client.Do(req)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+		s.hosts.backOff(req.URL.Host, res)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	return res.StatusCode, nil
+}
+
+func signPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBody renders payload with the webhook's template, or as JSON when
+// it has none.
+func webhookBody(hook database.Webhook, payload webhookPayload) ([]byte, error) {
+	if !hook.Template.Valid {
+		return json.Marshal(payload)
+	}
+
+	tmpl, err := parseWebhookTemplate(hook.Template.String)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	err = tmpl.Execute(&buf, payload)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed rendering webhook template: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// parseWebhookTemplate parses a payload template. Templates get a json
+// function to safely embed values in JSON bodies.
+func parseWebhookTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+func webhookFeedFrom(f database.Feed) *webhookFeed {
+	return &webhookFeed{
+		ID:   f.ID,
+		Name: f.Name,
+		URL:  f.Url,
+	}
+}
+
+func webhookPostFrom(p newPost) webhookPost {
+	return webhookPost{
+		ID:          p.post.ID,
+		Title:       p.post.Title,
+		URL:         p.post.Url,
+		Description: p.post.Description.String,
+		Author:      p.post.Author.String,
+		Categories:  p.fields.categories,
+		PublishedAt: nullTime(p.post.PublishedAt),
+	}
+}
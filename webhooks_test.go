@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+// hookReceiver records the webhook requests it gets and answers them with
+// the statuses it is given, then 200.
+type hookReceiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []hookRequest
+}
+
+type hookRequest struct {
+	at     time.Time
+	header http.Header
+	body   []byte
+}
+
+func newHookReceiver(t *testing.T, statuses ...int) *hookReceiver {
+	h := &hookReceiver{statuses: statuses}
+	h.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		h.requests = append(h.requests, hookRequest{at: time.Now(), header: r.Header, body: body})
+
+		if len(h.statuses) > 0 {
+			w.WriteHeader(h.statuses[0])
+			h.statuses = h.statuses[1:]
+		}
+	}))
+	t.Cleanup(h.Close)
+
+	return h
+}
+
+func (h *hookReceiver) received() []hookRequest {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]hookRequest(nil), h.requests...)
+}
+
+// addWebhook runs webhook add and returns the webhook's id and secret.
+func (e *testEnv) addWebhook(args ...string) (string, string) {
+	e.t.Helper()
+
+	out := e.must("", append([]string{"webhook", "add"}, args...)...)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	id := strings.Fields(lines[0])[1]
+
+	return id, lines[len(lines)-1]
+}
+
+// deliveries waits for the user's delivery log to hold n deliveries and
+// returns them, newest first.
+func (e *testEnv) deliveries(user string, n int) []database.GetWebhookDeliveriesForUserRow {
+	e.t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		rows, err := e.s.db.GetWebhookDeliveriesForUser(context.Background(),
+			database.GetWebhookDeliveriesForUserParams{
+				UserID: e.user(user).ID,
+				Limit:  100,
+			})
+
+		if err != nil {
+			e.t.Fatal(err)
+		}
+
+		if len(rows) >= n || time.Now().After(deadline) {
+			if len(rows) != n {
+				e.t.Fatalf("delivery log has %d deliveries, want %d", len(rows), n)
+			}
+
+			return rows
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// fastBackoff shortens the waits between retries for the length of a test.
+func fastBackoff(t *testing.T) {
+	saved := webhookBackoff
+	webhookBackoff = []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond}
+	t.Cleanup(func() { webhookBackoff = saved })
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed := newTestFeed(t, testItem(1), testItem(2))
+		single := newHookReceiver(t)
+		batch := newHookReceiver(t)
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		_, secret := e.addWebhook(single.URL)
+		e.addWebhook("--batch", batch.URL)
+		e.fetchAll()
+
+		e.deliveries("alice", 3)
+
+		reqs := single.received()
+
+		if len(reqs) != 2 {
+			t.Fatalf("webhook got %d requests, want one per post", len(reqs))
+		}
+
+		ids := map[string]bool{}
+
+		for _, r := range reqs {
+			if got, want := r.header.Get("X-Gator-Signature"), "sha256="+signPayload(secret, r.body); got != want {
+				t.Errorf("X-Gator-Signature is %q, want %q", got, want)
+			}
+
+			if got := r.header.Get("X-Gator-Event"); got != "posts.created" {
+				t.Errorf("X-Gator-Event is %q", got)
+			}
+
+			if got := r.header.Get("User-Agent"); got != userAgent(e.s.cfg) {
+				t.Errorf("User-Agent is %q", got)
+			}
+
+			id, err := uuid.Parse(r.header.Get("X-Gator-Delivery"))
+
+			if err != nil || ids[id.String()] {
+				t.Errorf("X-Gator-Delivery %q isn't a new id", r.header.Get("X-Gator-Delivery"))
+			}
+			ids[id.String()] = true
+
+			var payload webhookPayload
+
+			if err := json.Unmarshal(r.body, &payload); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(payload.Posts) != 1 || payload.Feed == nil || payload.Feed.URL != feed.URL {
+				t.Errorf("payload is %+v", payload)
+			}
+		}
+
+		// a signature made with another secret doesn't match
+		if r := reqs[0]; r.header.Get("X-Gator-Signature") == "sha256="+signPayload("other", r.body) {
+			t.Error("signature doesn't depend on the secret")
+		}
+
+		reqs = batch.received()
+
+		if len(reqs) != 1 {
+			t.Fatalf("batch webhook got %d requests, want 1", len(reqs))
+		}
+
+		var payload webhookPayload
+
+		if err := json.Unmarshal(reqs[0].body, &payload); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(payload.Posts) != 2 {
+			t.Errorf("batch payload has %d posts, want 2", len(payload.Posts))
+		}
+
+		// the posts are only sent once
+		e.fetchAll()
+		time.Sleep(50 * time.Millisecond)
+
+		if n := len(single.received()) + len(batch.received()); n != 3 {
+			t.Errorf("webhooks got %d requests after fetching again, want 3", n)
+		}
+	})
+}
+
+func TestWebhookRetry(t *testing.T) {
+	fastBackoff(t)
+
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		e.register("alice")
+
+		for _, tc := range []struct {
+			name     string
+			statuses []int
+			attempts int32
+			ok       bool
+		}{
+			{"retried until it succeeds", []int{503, 429}, 3, true},
+			{"gives up after every backoff", []int{500, 500, 500, 500}, 4, false},
+			{"client errors aren't retried", []int{400}, 1, false},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				hook := newHookReceiver(t, tc.statuses...)
+				id, _ := e.addWebhook(hook.URL)
+
+				_, err := e.run("", "webhook", "test", id)
+
+				if (err == nil) != tc.ok {
+					t.Errorf("webhook test returned %v", err)
+				}
+
+				reqs := hook.received()
+
+				if len(reqs) != int(tc.attempts) {
+					t.Fatalf("got %d attempts, want %d", len(reqs), tc.attempts)
+				}
+
+				for i := 1; i < len(reqs); i++ {
+					if gap := reqs[i].at.Sub(reqs[i-1].at); gap < webhookBackoff[i-1] {
+						t.Errorf("attempt %d came %v after the one before, want at least %v", i+1, gap, webhookBackoff[i-1])
+					}
+
+					// a retry is the same delivery
+					if reqs[i].header.Get("X-Gator-Delivery") != reqs[0].header.Get("X-Gator-Delivery") {
+						t.Error("retry has a new delivery id")
+					}
+				}
+
+				e.must("", "webhook", "rm", id)
+			})
+		}
+	})
+}
+
+func TestWebhookLog(t *testing.T) {
+	fastBackoff(t)
+
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		ok := newHookReceiver(t)
+		failing := newHookReceiver(t, 404)
+
+		e.register("alice")
+		okID, _ := e.addWebhook(ok.URL)
+		failingID, _ := e.addWebhook(failing.URL)
+
+		e.must("", "webhook", "test", okID)
+		e.fails("", "webhook", "test", failingID)
+
+		rows := e.deliveries("alice", 2)
+
+		if v := rows[0]; v.Url != failing.URL || v.Succeeded || v.Attempts != 1 || v.StatusCode.Int32 != 404 || !v.Error.Valid {
+			t.Errorf("failed delivery is logged as %+v", v)
+		}
+
+		if v := rows[1]; v.Url != ok.URL || !v.Succeeded || v.Event != "ping" || v.StatusCode.Int32 != 200 || v.Error.Valid {
+			t.Errorf("delivery is logged as %+v", v)
+		}
+
+		out := e.must("", "webhook", "log")
+
+		if !strings.Contains(out, ok.URL+" ping 0 posts, 1 attempts, status 200: ok") ||
+			!strings.Contains(out, failing.URL+" ping 0 posts, 1 attempts, status 404: failed (unexpected status 404 Not Found)") {
+			t.Errorf("webhook log printed\n%s", out)
+		}
+
+		// other users don't see the log
+		e.register("bob")
+
+		if out := e.must("", "webhook", "log"); out != "" {
+			t.Errorf("bob's webhook log shows\n%s", out)
+		}
+	})
+}
+
+// A full queue drops deliveries instead of blocking the fetch, and logs
+// them as failed.
+func TestWebhookQueueFull(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		hook := newHookReceiver(t)
+
+		e.register("alice")
+		id, _ := e.addWebhook(hook.URL)
+
+		webhook, err := e.s.db.GetWebhookForUser(context.Background(),
+			database.GetWebhookForUserParams{ID: uuid.MustParse(id), UserID: e.user("alice").ID})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// no room and no workers
+		q := &webhookQueue{jobs: make(chan webhookJob)}
+		q.start.Do(func() {})
+
+		q.push(e.s, webhookJob{hook: webhook, payloads: []webhookPayload{{Event: "posts.created"}, {Event: "posts.created"}}})
+
+		rows := e.deliveries("alice", 2)
+
+		for _, v := range rows {
+			if v.Succeeded || v.Attempts != 0 || !strings.Contains(v.Error.String, "dropped") {
+				t.Errorf("dropped delivery is logged as %+v", v)
+			}
+		}
+
+		if n := len(hook.received()); n != 0 {
+			t.Errorf("webhook got %d requests", n)
+		}
+	})
+}
+
+// Deliveries wait their turn with the host limiter, so a webhook's
+// Retry-After holds off the retries.
+func TestWebhookRetryAfter(t *testing.T) {
+	fastBackoff(t)
+
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		var requests atomic.Int32
+
+		hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		t.Cleanup(hook.Close)
+
+		e.register("alice")
+		id, _ := e.addWebhook(hook.URL)
+
+		if err := e.fails("", "webhook", "test", id); !strings.Contains(err.Error(), "host asked us to wait") {
+			t.Errorf("webhook test returned %v", err)
+		}
+
+		if n := requests.Load(); n != 1 {
+			t.Errorf("webhook got %d requests, want 1", n)
+		}
+	})
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+const (
+	// pushedPollInterval is how often feeds with an active WebSub
+	// subscription are still polled, in case the hub misses an update.
+	pushedPollInterval = 24 * time.Hour
+	websubLease        = 10 * 24 * time.Hour
+	websubRenewBefore  = 2 * 24 * time.Hour
+	maxPushSize        = 10 << 20
+	// websubTimeout bounds each subscription request to a hub.
+	websubTimeout = 10 * time.Second
+)
+
+var websubHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// websubLinks returns the hub and self urls the feed advertises, if any.
+func (f *RSSFeed) websubLinks() (hub, self string) {
+	for _, v := range f.Channel.Links {
+		switch v.Rel {
+		case "hub":
+			if hub == "" {
+				hub = v.Href
+			}
+		case "self":
+			self = v.Href
+		}
+	}
+
+	return hub, self
+}
+
+// subscribeWebSub asks the feed's hub to push updates to us, or renews the
+// lease when it is about to run out. Nothing happens unless a callback url
+// is configured and the feed advertises a hub.
+func subscribeWebSub(s *state, feed database.Feed, rss *RSSFeed) error {
+	if s.cfg.WebSubCallback == "" {
+		return nil
+	}
+
+	hub, topic := rss.websubLinks()
+	if hub == "" {
+		return nil
+	}
+	if topic == "" {
+		topic = feed.Url
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+
+	sub, err := s.db.GetWebsubSubscription(ctx, feed.ID)
+
+	if err == nil && sub.Hub == hub && sub.Topic == topic {
+		switch {
+		case sub.State == "active" && sub.LeaseExpiresAt.Time.After(now.Add(websubRenewBefore)):
+			return nil
+		case sub.State != "active" && now.Sub(sub.UpdatedAt) < pushedPollInterval:
+			// waiting for verification, or the hub refused us recently
+			return nil
+		}
+	}
+
+	secret, err := newToken()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.CreateWebsubSubscription(ctx,
+		database.CreateWebsubSubscriptionParams{
+			FeedID:    feed.ID,
+			CreatedAt: now,
+			UpdatedAt: now,
+			Hub:       hub,
+			Topic:     topic,
+			Secret:    secret,
+			State:     "pending",
+		})
+
+	if err != nil {
+		return fmt.Errorf("failed saving websub subscription: %w", err)
+	}
+
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {topic},
+		"hub.callback":      {websubCallback(s, feed.ID)},
+		"hub.secret":        {secret},
+		"hub.lease_seconds": {strconv.Itoa(int(websubLease.Seconds()))},
+	}
+
+	err = postToHub(s, hub, form)
+
+	if err != nil {
+		stateErr := s.db.SetWebsubState(ctx,
+			database.SetWebsubStateParams{
+				State:     "failed",
+				UpdatedAt: time.Now(),
+				FeedID:    feed.ID,
+			})
+
+		if stateErr != nil {
+			return fmt.Errorf("failed updating websub subscription: %w", stateErr)
+		}
+
+		return fmt.Errorf("failed subscribing to %s at %s: %w", topic, hub, err)
+	}
+
+	return nil
+}
+
+// postToHub sends a subscription request to hub the way feeds are fetched:
+// through the same proxy and TLS settings, with the same User-Agent and
+// waiting its turn with the hub's host.
+func postToHub(s *state, hub string, form url.Values) error {
+	ctx, cancel := context.WithTimeout(context.Background(), websubTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", hub, strings.NewReader(form.Encode()))
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", userAgent(s.cfg))
+
+	release, err := s.hosts.acquire(ctx, req.URL.Host)
+
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
+	res, err := s.client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+		s.hosts.backOff(req.URL.Host, res)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("hub responded %s", res.Status)
+	}
+
+	return nil
+}
+
+func websubCallback(s *state, feedID uuid.UUID) string {
+	return strings.TrimSuffix(s.cfg.WebSubCallback, "/") + "/websub/" + feedID.String()
+}
+
+// registerWebSub adds the callback hubs verify subscriptions with and push
+// new content to.
+func (srv *server) registerWebSub(mux *http.ServeMux) {
+	mux.HandleFunc("GET /websub/{feed_id}", srv.handleWebSubVerify)
+	mux.HandleFunc("POST /websub/{feed_id}", srv.handleWebSubPush)
+}
+
+func (srv *server) handleWebSubVerify(w http.ResponseWriter, r *http.Request) {
+	sub, ok := srv.websubFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+
+	if query.Get("hub.topic") != sub.Topic {
+		http.NotFound(w, r)
+		return
+	}
+
+	params := database.SetWebsubStateParams{
+		UpdatedAt: time.Now(),
+		FeedID:    sub.FeedID,
+	}
+
+	switch query.Get("hub.mode") {
+	case "subscribe":
+		if sub.State != "pending" && sub.State != "active" {
+			http.NotFound(w, r)
+			return
+		}
+
+		lease, err := strconv.Atoi(query.Get("hub.lease_seconds"))
+		if err != nil || lease <= 0 {
+			lease = int(websubLease.Seconds())
+		}
+
+		params.State = "active"
+		params.LeaseExpiresAt = sql.NullTime{
+			Time:  time.Now().Add(time.Duration(lease) * time.Second),
+			Valid: true,
+		}
+	case "denied":
+		params.State = "denied"
+	default:
+		// we never unsubscribe, feeds are dropped by deleting them
+		http.NotFound(w, r)
+		return
+	}
+
+	err := srv.s.db.SetWebsubState(r.Context(), params)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	io.WriteString(w, query.Get("hub.challenge"))
+}
+
+func (srv *server) handleWebSubPush(w http.ResponseWriter, r *http.Request) {
+	sub, ok := srv.websubFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPushSize))
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("content over %d bytes", maxPushSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Content with a bad signature must still be acknowledged, but is
+	// otherwise ignored.
+	if !validHubSignature(sub.Secret, r.Header.Get("X-Hub-Signature"), body) {
+		slog.Warn("ignoring websub push with an invalid signature", "feed_id", sub.FeedID, "feed_url", sub.Topic)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	feed, err := srv.s.db.GetFeedById(r.Context(), sub.FeedID)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var rss RSSFeed
+
+	err = xml.Unmarshal(body, &rss)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	unescapeHTML(&rss)
+
+	_, _, err = saveFeeds(srv.s, &rss, feed, time.Time{})
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// websubFromPath loads the subscription named by the feed id in the callback
+// url. Hubs get 410 Gone for feeds we no longer have, so they stop pushing.
+func (srv *server) websubFromPath(w http.ResponseWriter, r *http.Request) (database.WebsubSubscription, bool) {
+	id, err := uuid.Parse(r.PathValue("feed_id"))
+
+	if err != nil {
+		http.NotFound(w, r)
+		return database.WebsubSubscription{}, false
+	}
+
+	sub, err := srv.s.db.GetWebsubSubscription(r.Context(), id)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "unknown subscription", http.StatusGone)
+		return database.WebsubSubscription{}, false
+	}
+
+	// anything else may go away, the hub should try again
+	if err != nil {
+		slog.Error("failed loading websub subscription", "feed_id", id, "error", err)
+		http.Error(w, "failed loading subscription", http.StatusInternalServerError)
+		return database.WebsubSubscription{}, false
+	}
+
+	return sub, true
+}
+
+// validHubSignature checks an X-Hub-Signature header of the form
+// method=hexdigest against the HMAC of body keyed with secret.
+func validHubSignature(secret, header string, body []byte) bool {
+	method, digest, ok := strings.Cut(header, "=")
+	if !ok {
+		return false
+	}
+
+	newHash, ok := websubHashes[method]
+	if !ok {
+		return false
+	}
+
+	want, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), want)
+}
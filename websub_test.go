@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+// Subscription requests go out like feed fetches, with our User-Agent.
+func TestWebSubSubscribe(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		hub := newHookReceiver(t, http.StatusServiceUnavailable)
+		e.s.cfg.WebSubCallback = "https://gator.example.com/"
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", "https://example.com/feed")
+		feed := e.feed("https://example.com/feed")
+
+		var rss RSSFeed
+		rss.Channel.Links = []atomLink{{Rel: "hub", Href: hub.URL}}
+
+		if err := subscribeWebSub(e.s, feed, &rss); err == nil {
+			t.Fatal("a hub answering 503 doesn't fail the subscription")
+		}
+
+		sub, err := e.s.db.GetWebsubSubscription(context.Background(), feed.ID)
+
+		if err != nil || sub.State != "failed" {
+			t.Errorf("subscription is %+v, %v", sub, err)
+		}
+
+		// the hub had no Retry-After, so it can be asked again right away
+		if err := postToHub(e.s, hub.URL, url.Values{"hub.mode": {"subscribe"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		reqs := hub.received()
+
+		if len(reqs) != 2 {
+			t.Fatalf("hub got %d requests, want 2", len(reqs))
+		}
+
+		if got := reqs[0].header.Get("User-Agent"); got != userAgent(e.s.cfg) {
+			t.Errorf("User-Agent is %q", got)
+		}
+
+		form, err := url.ParseQuery(string(reqs[0].body))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if form.Get("hub.callback") != "https://gator.example.com/websub/"+feed.ID.String() || form.Get("hub.topic") != feed.Url {
+			t.Errorf("subscription request is %v", form)
+		}
+	})
+}
+
+// A hub's Retry-After holds off further requests to its host.
+func TestWebSubRetryAfter(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		t.Cleanup(hub.Close)
+
+		form := url.Values{"hub.mode": {"subscribe"}}
+
+		if err := postToHub(e.s, hub.URL, form); err == nil || errors.Is(err, errBackOff) {
+			t.Fatalf("first request returned %v", err)
+		}
+
+		if err := postToHub(e.s, hub.URL, form); !errors.Is(err, errBackOff) {
+			t.Errorf("request during the hub's Retry-After returned %v", err)
+		}
+	})
+}
+
+// brokenWebSubStore fails loading subscriptions.
+type brokenWebSubStore struct {
+	database.Store
+}
+
+func (brokenWebSubStore) GetWebsubSubscription(ctx context.Context, feedID uuid.UUID) (database.WebsubSubscription, error) {
+	return database.WebsubSubscription{}, errors.New("database is down")
+}
+
+func TestWebSubPush(t *testing.T) {
+	eachStore(t, func(t *testing.T, e *testEnv) {
+		feed := newTestFeed(t, testItem(1))
+
+		e.register("alice")
+		e.must("", "addfeed", "Test feed", feed.URL)
+		id := e.feed(feed.URL).ID
+		now := time.Now()
+
+		_, err := e.s.db.CreateWebsubSubscription(context.Background(),
+			database.CreateWebsubSubscriptionParams{
+				FeedID:    id,
+				CreatedAt: now,
+				UpdatedAt: now,
+				Hub:       "https://hub.example.com/",
+				Topic:     feed.URL,
+				Secret:    "secret",
+				State:     "active",
+			})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := http.Get(feed.URL)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(content)
+		signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+		push := func(path, signature string, body []byte) int {
+			t.Helper()
+
+			req := httptest.NewRequest("POST", path, bytes.NewReader(body))
+			req.Header.Set("X-Hub-Signature", signature)
+
+			w := httptest.NewRecorder()
+			newServer(e.s).ServeHTTP(w, req)
+
+			return w.Code
+		}
+
+		path := "/websub/" + id.String()
+
+		// content with a bad signature is acknowledged and dropped
+		if code := push(path, "sha256=00", content); code != http.StatusAccepted {
+			t.Errorf("push with a bad signature got %d, want 202", code)
+		}
+
+		if got := e.postUrls(feed.URL); len(got) != 0 {
+			t.Errorf("push with a bad signature saved %v", got)
+		}
+
+		if code := push(path, signature, content); code != http.StatusNoContent {
+			t.Errorf("push got %d, want 204", code)
+		}
+
+		if got := e.postUrls(feed.URL); !slices.Equal(got, []string{postUrl(1)}) {
+			t.Errorf("push saved %v", got)
+		}
+
+		if code := push(path, signature, make([]byte, maxPushSize+1)); code != http.StatusRequestEntityTooLarge {
+			t.Errorf("push over %d bytes got %d, want 413", maxPushSize, code)
+		}
+
+		if code := push("/websub/not-a-uuid", signature, content); code != http.StatusNotFound {
+			t.Errorf("push to a bad path got %d, want 404", code)
+		}
+
+		// hubs are told to stop pushing feeds we don't have
+		if code := push("/websub/"+uuid.NewString(), signature, content); code != http.StatusGone {
+			t.Errorf("push for an unknown feed got %d, want 410", code)
+		}
+
+		// but not when the subscription couldn't be looked up
+		e.s.db = brokenWebSubStore{e.s.db}
+
+		if code := push(path, signature, content); code != http.StatusInternalServerError {
+			t.Errorf("push while the database is down got %d, want 500", code)
+		}
+	})
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/w0/aggregator/internal/database"
+)
+
+const (
+	sessionCookie = "gator_session"
+	webPageSize   = 30
+)
+
+//go:embed templates/*.html templates/*.txt
+var templateFS embed.FS
+
+var pages = map[string]*template.Template{
+	"login":    parsePage("login.html"),
+	"timeline": parsePage("timeline.html"),
+	"post":     parsePage("post.html"),
+}
+
+func parsePage(name string) *template.Template {
+	return template.Must(template.ParseFS(templateFS,
+		"templates/layout.html",
+		"templates/actions.html",
+		"templates/"+name))
+}
+
+type webPost struct {
+	ID          uuid.UUID
+	Title       string
+	URL         string
+	FeedName    string
+	Author      string
+	PublishedAt *time.Time
+	Read        bool
+	Starred     bool
+	Content     template.HTML
+}
+
+type loginPage struct {
+	User  *database.User
+	Error string
+}
+
+type timelinePage struct {
+	User   *database.User
+	Title  string
+	Error  string
+	Feeds  []database.GetUnreadCountsForUserRow
+	Groups []string
+	Posts  []webPost
+	Prev   string
+	Next   string
+}
+
+type postPage struct {
+	User *database.User
+	Post webPost
+}
+
+func (srv *server) registerWeb(mux *http.ServeMux) {
+	mux.HandleFunc("GET /login", srv.handleLoginPage)
+	mux.HandleFunc("POST /login", srv.handleLogin)
+	mux.HandleFunc("POST /logout", srv.handleLogout)
+	mux.HandleFunc("GET /{$}", srv.requireSession(srv.handleTimelinePage))
+	mux.HandleFunc("GET /posts/{id}", srv.requireSession(srv.handlePostPage))
+	mux.HandleFunc("POST /posts/{id}/{action}", srv.requireSession(srv.handlePostAction))
+	mux.HandleFunc("POST /feeds", srv.requireSession(srv.handleAddFeedForm))
+	mux.HandleFunc("POST /follow", srv.requireSession(srv.handleFollowForm))
+	mux.HandleFunc("POST /unfollow", srv.requireSession(srv.handleUnfollowForm))
+}
+
+// requireSession authenticates browser requests with the session token
+// stored in a cookie, sending anyone without one to the login page.
+func (srv *server) requireSession(next authedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(sessionCookie)
+
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		user, err := userFromSession(r.Context(), srv.s, cookie.Value)
+
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		next(w, r, user)
+	}
+}
+
+func (srv *server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	render(w, "login", loginPage{})
+}
+
+func (srv *server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	user, err := srv.s.db.GetUser(r.Context(), r.FormValue("username"))
+
+	if err == nil {
+		err = checkPassword(user, r.FormValue("password"))
+	}
+
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		render(w, "login", loginPage{Error: "Invalid username or password."})
+		return
+	}
+
+	token, err := createSession(r.Context(), srv.s, user)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  time.Now().Add(sessionTTL),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (srv *server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		_, err = srv.s.db.DeleteSession(r.Context(), hashToken(cookie.Value))
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (srv *server) handleTimelinePage(w http.ResponseWriter, r *http.Request, user database.User) {
+	query := r.URL.Query()
+
+	offset, err := queryInt(query.Get("offset"), 0)
+
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	page := timelinePage{
+		User:  &user,
+		Title: "All posts",
+		Error: query.Get("error"),
+	}
+
+	page.Feeds, err = srv.s.db.GetUnreadCountsForUser(r.Context(), user.ID)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	groups, err := srv.s.db.GetGroupsForUser(r.Context(), user.ID)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for _, v := range groups {
+		if len(page.Groups) == 0 || page.Groups[len(page.Groups)-1] != v.Name {
+			page.Groups = append(page.Groups, v.Name)
+		}
+	}
+
+	params := database.ListPostsForUserParams{
+		UserID:      user.ID,
+		UnreadOnly:  query.Get("unread") == "true",
+		StarredOnly: query.Get("starred") == "true",
+		Limit:       webPageSize,
+		Offset:      int32(offset),
+	}
+
+	switch {
+	case params.UnreadOnly:
+		page.Title = "Unread"
+	case params.StarredOnly:
+		page.Title = "Starred"
+	}
+
+	if group := query.Get("group"); group != "" {
+		params.GroupName = sql.NullString{String: group, Valid: true}
+		page.Title = group
+	}
+
+	if feed, err := uuid.Parse(query.Get("feed")); err == nil {
+		params.FeedID = uuid.NullUUID{UUID: feed, Valid: true}
+
+		for _, v := range page.Feeds {
+			if v.ID == feed {
+				page.Title = v.Name
+			}
+		}
+	}
+
+	posts, err := srv.s.db.ListPostsForUser(r.Context(), params)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for _, v := range posts {
+		page.Posts = append(page.Posts, webPostFrom(v))
+	}
+
+	if offset > 0 {
+		page.Prev = pageURL(query, max(offset-webPageSize, 0))
+	}
+
+	if len(posts) == webPageSize {
+		page.Next = pageURL(query, offset+webPageSize)
+	}
+
+	render(w, "timeline", page)
+}
+
+func (srv *server) handlePostPage(w http.ResponseWriter, r *http.Request, user database.User) {
+	post, ok := srv.postFromPath(w, r, user)
+	if !ok {
+		return
+	}
+
+	if !post.Read {
+		err := setPostRead(srv.s, user, post.ID, true)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		post.Read = true
+	}
+
+	view := webPostFrom(post)
+	view.Content = sanitizeHTML(post.Description.String)
+
+	render(w, "post", postPage{User: &user, Post: view})
+}
+
+func (srv *server) handlePostAction(w http.ResponseWriter, r *http.Request, user database.User) {
+	post, ok := srv.postFromPath(w, r, user)
+	if !ok {
+		return
+	}
+
+	var err error
+
+	switch r.PathValue("action") {
+	case "read", "unread":
+		err = setPostRead(srv.s, user, post.ID, r.PathValue("action") == "read")
+	case "star", "unstar":
+		err = setPostStarred(srv.s, user, post.ID, r.PathValue("action") == "star")
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	redirectBack(w, r, "")
+}
+
+func (srv *server) handleAddFeedForm(w http.ResponseWriter, r *http.Request, user database.User) {
+	name, feedURL := r.FormValue("name"), r.FormValue("url")
+
+	if name == "" || feedURL == "" {
+		redirectBack(w, r, "name and url are required")
+		return
+	}
+
+	_, err := srv.s.db.GetFeedByUrl(r.Context(), feedURL)
+
+	if err == nil {
+		redirectBack(w, r, "that feed already exists, follow it instead")
+		return
+	}
+
+	_, err = ensureFollow(srv.s, user, name, feedURL)
+
+	if err != nil {
+		redirectBack(w, r, err.Error())
+		return
+	}
+
+	redirectBack(w, r, "")
+}
+
+func (srv *server) handleFollowForm(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, err := srv.s.db.GetFeedByUrl(r.Context(), r.FormValue("url"))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		redirectBack(w, r, "unknown feed, add it first")
+		return
+	}
+
+	if err == nil {
+		_, err = ensureFollow(srv.s, user, feed.Name, feed.Url)
+	}
+
+	if err == nil && r.FormValue("group") != "" {
+		err = addFeedToGroup(srv.s, user, feed, r.FormValue("group"))
+	}
+
+	if err != nil {
+		redirectBack(w, r, err.Error())
+		return
+	}
+
+	redirectBack(w, r, "")
+}
+
+func (srv *server) handleUnfollowForm(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedID, err := uuid.Parse(r.FormValue("feed_id"))
+
+	if err != nil {
+		redirectBack(w, r, "invalid feed")
+		return
+	}
+
+	_, err = srv.s.db.DeleteFeedFollow(r.Context(),
+		database.DeleteFeedFollowParams{
+			UserID: user.ID,
+			FeedID: feedID,
+		})
+
+	if err != nil {
+		redirectBack(w, r, err.Error())
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func render(w http.ResponseWriter, name string, data any) {
+	var buf bytes.Buffer
+
+	err := pages[name].ExecuteTemplate(&buf, "layout", data)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+// redirectBack sends the browser back to the page the form was posted from,
+// showing msg as an error when it is not empty.
+func redirectBack(w http.ResponseWriter, r *http.Request, msg string) {
+	target := "/"
+
+	if ref, err := url.Parse(r.Referer()); err == nil && ref.Host == r.Host {
+		target = ref.RequestURI()
+	}
+
+	if msg != "" {
+		u, _ := url.Parse(target)
+		q := u.Query()
+		q.Set("error", msg)
+		u.RawQuery = q.Encode()
+		target = u.String()
+	}
+
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+func pageURL(query url.Values, offset int) string {
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	q.Del("error")
+	q.Set("offset", strconv.Itoa(offset))
+
+	return "/?" + q.Encode()
+}
+
+func webPostFrom(p database.ListPostsForUserRow) webPost {
+	return webPost{
+		ID:          p.ID,
+		Title:       p.Title,
+		URL:         p.Url,
+		FeedName:    p.FeedName,
+		Author:      p.Author.String,
+		PublishedAt: nullTime(p.PublishedAt),
+		Read:        p.Read,
+		Starred:     p.Starred,
+	}
+}